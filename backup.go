@@ -0,0 +1,365 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+var (
+	// backupDir is where scheduled backups are written, relative to pushups.db
+	backupDir = "backups"
+	// backupInterval is how often a backup is taken, 0 turns scheduled backups off
+	backupInterval time.Duration
+	// backupKeep is how many scheduled backups of each file are kept
+	backupKeep = 7
+)
+
+// backupTimeLayout is the timestamp in the names of backup files
+const backupTimeLayout = "20060102-150405"
+
+// backupFromEnv reads BACKUP_DIR, BACKUP_INTERVAL and BACKUP_KEEP
+func backupFromEnv() error {
+	if value := os.Getenv("BACKUP_DIR"); value != "" {
+		backupDir = value
+	}
+	if value := os.Getenv("BACKUP_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || (interval != 0 && interval < time.Minute) {
+			return fmt.Errorf("BACKUP_INTERVAL must be 0 or a duration of at least 1m, e.g. 24h")
+		}
+		backupInterval = interval
+	}
+	if value := os.Getenv("BACKUP_KEEP"); value != "" {
+		keep, err := strconv.Atoi(value)
+		if err != nil || keep < 1 {
+			return fmt.Errorf("BACKUP_KEEP must be a number of at least 1")
+		}
+		backupKeep = keep
+	}
+	return nil
+}
+
+// backupFile is a file that makes up a backup, with the function writing a
+// consistent snapshot of it
+type backupFile struct {
+	name  string
+	write func(w io.Writer) error
+}
+
+// backupFiles are pushups.db, and the tracker data when DB_DRIVER or DB_PATH keep
+// it in a file of its own
+func backupFiles() ([]backupFile, error) {
+	files := []backupFile{{filepath.Base(db.Path()), backupAccounts}}
+
+	driver, path, err := storageFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if driver != "bolt" || path != filepath.Clean(db.Path()) {
+		files = append(files, backupFile{filepath.Base(path), trackerStore().Backup})
+	}
+	return files, nil
+}
+
+// backupAccounts writes a snapshot of pushups.db from a read transaction, so the
+// server keeps running while it is taken
+func backupAccounts(w io.Writer) error {
+	return db.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+// backupName returns the name of a backup of a file taken at t, e.g.
+// pushups-20240310-080000.db for pushups.db
+func backupName(name string, t time.Time) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-" + t.Format(backupTimeLayout) + ext
+}
+
+// handleBackup downloads a snapshot of pushups.db, or of the tracker data with
+// ?file=data. The snapshot holds the session secret and every password and token
+// hash, so an API token, even an admin's, can't download it.
+func handleBackup(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.Context().Value(tokenContextKey).(APIToken); ok {
+		http.Error(w, "Backups cannot be downloaded with a token", http.StatusForbidden)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	files, err := backupFiles()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	file := files[0]
+	switch r.URL.Query().Get("file") {
+	case "":
+	case "data":
+		file = files[len(files)-1]
+	default:
+		http.Error(w, "file must be empty or data", http.StatusBadRequest)
+		return
+	}
+
+	filename := backupName(file.name, time.Now())
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if err := file.write(w); err != nil {
+		// The snapshot has been partly sent already, so the download is cut short
+		log.Printf("Backup %s: %v", filename, err)
+	}
+}
+
+// writeBackup writes a snapshot of every backup file to dir and returns their paths.
+// Each is written under a temporary name first, so that an interrupted backup is
+// never taken for a complete one.
+func writeBackup(dir string, now time.Time) ([]string, error) {
+	files, err := backupFiles()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, file := range files {
+		path := filepath.Join(dir, backupName(file.name, now))
+		f, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return written, err
+		}
+		err = file.write(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(path+".tmp", path)
+		}
+		if err != nil {
+			os.Remove(path + ".tmp")
+			return written, fmt.Errorf("%s: %v", file.name, err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// rotateBackups deletes all but the newest keep backups of each file in dir and
+// returns the deleted paths
+func rotateBackups(dir string, keep int) ([]string, error) {
+	files, err := backupFiles()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for _, file := range files {
+		ext := filepath.Ext(file.name)
+		prefix := strings.TrimSuffix(file.name, ext) + "-"
+
+		// The timestamps sort in the order the backups were taken
+		var backups []string
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+				continue
+			}
+			stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+			if _, err := time.Parse(backupTimeLayout, stamp); err == nil {
+				backups = append(backups, name)
+			}
+		}
+		sort.Strings(backups)
+
+		for len(backups) > keep {
+			path := filepath.Join(dir, backups[0])
+			if err := os.Remove(path); err != nil {
+				return deleted, err
+			}
+			deleted = append(deleted, path)
+			backups = backups[1:]
+		}
+	}
+	return deleted, nil
+}
+
+// scheduleBackups takes a backup every backupInterval while the server runs
+func scheduleBackups() {
+	dir := backupDir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(db.Path()), dir)
+	}
+	log.Printf("Backing up every %s to %s, keeping %d", backupInterval, dir, backupKeep)
+
+	go func() {
+		for range time.Tick(backupInterval) {
+			written, err := writeBackup(dir, time.Now())
+			if err != nil {
+				log.Printf("Backup failed: %v", err)
+				continue
+			}
+			log.Printf("Backed up to %s", strings.Join(written, ", "))
+			if _, err := rotateBackups(dir, backupKeep); err != nil {
+				log.Printf("Rotating backups failed: %v", err)
+			}
+		}
+	}()
+}
+
+// checkSnapshot opens a snapshot of a driver and checks that it holds valid
+// accounts and/or tracker data
+func checkSnapshot(driver, path string, accounts, data bool) error {
+	store, closeStore, err := openStore(driver, path)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	if accounts {
+		snapshot := store.(tracker.BoltStore).DB
+		err := snapshot.View(func(tx *bolt.Tx) error {
+			for _, name := range []string{"Users", "UserData", "Sessions", "Server", "Tokens"} {
+				if tx.Bucket([]byte(name)) == nil {
+					return fmt.Errorf("bucket %s is missing", name)
+				}
+			}
+			users, err := listUsers(tx)
+			if err != nil {
+				return fmt.Errorf("users: %v", err)
+			}
+			if len(users) == 0 {
+				return fmt.Errorf("there are no users")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if data {
+		usernames, err := store.Users()
+		if err != nil {
+			return err
+		}
+		for _, username := range usernames {
+			if err := tracker.New(store).Verify(username); err != nil {
+				return fmt.Errorf("%s: %v", username, err)
+			}
+		}
+	}
+	return nil
+}
+
+// copyFile copies src to a new file dst
+func copyFile(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// runRestore implements `push_up_tracker restore [-data] file`, which replaces
+// pushups.db, or with -data the tracker data kept apart from it, with a snapshot.
+// The snapshot is checked first and the replaced file is kept next to it.
+func runRestore(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	data := flags.Bool("data", false, "restore the tracker data kept in its own file with DB_DRIVER or DB_PATH")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: restore [-data] file")
+	}
+	snapshot := flags.Arg(0)
+
+	files, err := backupFiles()
+	if err != nil {
+		return err
+	}
+	driver, dataFile, _ := storageFromEnv()
+	shared := len(files) == 1
+
+	target, targetDriver := filepath.Clean(db.Path()), "bolt"
+	if *data {
+		if shared {
+			return fmt.Errorf("the tracker data is kept in %s, restore it without -data", target)
+		}
+		target, targetDriver = dataFile, driver
+	}
+
+	// Check a copy next to the target, which is then moved into place
+	restoring := target + ".restore"
+	if err := copyFile(restoring, snapshot); err != nil {
+		return err
+	}
+	defer os.Remove(restoring)
+	if err := checkSnapshot(targetDriver, restoring, !*data, *data || shared); err != nil {
+		return fmt.Errorf("%s is not a valid snapshot: %v", snapshot, err)
+	}
+
+	// Keep what is replaced
+	replaced := fmt.Sprintf("%s.%s.bak", target, time.Now().Format(backupTimeLayout))
+	f, err := os.OpenFile(replaced, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	file := files[0]
+	if *data {
+		file = files[len(files)-1]
+	}
+	err = file.write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(replaced)
+		return fmt.Errorf("back up %s: %v", target, err)
+	}
+
+	// Files can't be replaced while they are open on every system
+	if err := closeDataStore(); err != nil {
+		return err
+	}
+	if err := db.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(restoring, target); err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored %s from %s, the replaced data is in %s\n", target, snapshot, replaced)
+	return nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestBackupFromEnv(t *testing.T) {
+	origDir, origInterval, origKeep := backupDir, backupInterval, backupKeep
+	defer func() { backupDir, backupInterval, backupKeep = origDir, origInterval, origKeep }()
+
+	t.Setenv("BACKUP_DIR", "/var/backups/pushups")
+	t.Setenv("BACKUP_INTERVAL", "12h")
+	t.Setenv("BACKUP_KEEP", "3")
+	if err := backupFromEnv(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if backupDir != "/var/backups/pushups" || backupInterval != 12*time.Hour || backupKeep != 3 {
+		t.Errorf("Expected the settings to be read, got %s %s %d", backupDir, backupInterval, backupKeep)
+	}
+
+	for key, value := range map[string]string{"BACKUP_INTERVAL": "30s", "BACKUP_KEEP": "0"} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if err := backupFromEnv(); err == nil {
+				t.Errorf("Expected error for %s=%s", key, value)
+			}
+		})
+	}
+}
+
+func TestHandleBackup(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+	t.Setenv("DB_DRIVER", "")
+	t.Setenv("DB_PATH", testDB.Path())
+
+	testDB.Update(func(root *bolt.Tx) error {
+		return testTx(root).PutDay(tracker.DayData{Date: "2024-03-01", Count: 10})
+	})
+
+	// Test 1: Download a snapshot while the database is open
+	req := httptest.NewRequest("GET", "/api/admin/backup", nil)
+	w := httptest.NewRecorder()
+
+	handleBackup(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	file := filepath.Join(t.TempDir(), "snapshot.db")
+	os.WriteFile(file, w.Body.Bytes(), 0600)
+	if err := checkSnapshot("bolt", file, true, true); err != nil {
+		t.Errorf("Expected a valid snapshot, got %v", err)
+	}
+
+	// Test 2: Invalid requests
+	req = httptest.NewRequest("GET", "/api/admin/backup?file=other", nil)
+	w = httptest.NewRecorder()
+
+	handleBackup(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/admin/backup", nil)
+	w = httptest.NewRecorder()
+
+	handleBackup(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+
+	// Test 3: An admin's tokens can't download the secrets in the snapshot
+	for _, scope := range []string{"read", "write"} {
+		var token string
+		testDB.Update(func(tx *bolt.Tx) error {
+			token, _, _ = createToken(tx, testUser, scope+" backup", scope)
+			return nil
+		})
+		req = httptest.NewRequest("GET", "/api/admin/backup", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = httptest.NewRecorder()
+
+		requireAuth(requireAdmin(handleBackup))(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 for a %s token, got %d", scope, w.Code)
+		}
+	}
+}
+
+func TestWriteAndRotateBackups(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+	t.Setenv("DB_DRIVER", "")
+	t.Setenv("DB_PATH", testDB.Path())
+
+	dir := t.TempDir()
+	start := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		written, err := writeBackup(dir, start.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to write backup: %v", err)
+		}
+		if len(written) != 1 {
+			t.Errorf("Expected one file, got %v", written)
+		}
+	}
+	// Files that aren't backups are left alone
+	os.WriteFile(filepath.Join(dir, "test-notes.db"), nil, 0600)
+
+	deleted, err := rotateBackups(dir, 2)
+	if err != nil {
+		t.Fatalf("Failed to rotate backups: %v", err)
+	}
+	if len(deleted) != 2 || filepath.Base(deleted[0]) != "test-20240310-080000.db" {
+		t.Errorf("Expected the two oldest backups to be deleted, got %v", deleted)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	expected := []string{"test-20240310-100000.db", "test-20240310-110000.db", "test-notes.db"}
+	if len(names) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, names)
+	}
+	for i := range names {
+		if names[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, names)
+			break
+		}
+	}
+}
+
+func TestRunRestore(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+	t.Setenv("DB_DRIVER", "")
+	path := testDB.Path()
+	t.Setenv("DB_PATH", path)
+
+	dir := t.TempDir()
+	written, err := writeBackup(dir, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+	// A day added after the backup
+	testDB.Update(func(root *bolt.Tx) error {
+		return testTx(root).PutDay(tracker.DayData{Date: "2024-03-01", Count: 10})
+	})
+
+	// Test 1: Invalid snapshots are refused before anything is replaced
+	invalid := filepath.Join(dir, "invalid.db")
+	os.WriteFile(invalid, []byte("not a database"), 0600)
+	empty := filepath.Join(dir, "empty.db")
+	emptyDB, _ := bolt.Open(empty, 0600, nil)
+	emptyDB.Close()
+
+	for _, args := range [][]string{
+		{"restore"},
+		{"restore", invalid},
+		{"restore", empty},
+		{"restore", "-data", written[0]},
+	} {
+		if err := runCommand(args, testUser); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		if _, found, _ := testTx(root).Day("2024-03-01"); !found {
+			t.Errorf("Expected the live data to be kept")
+		}
+		return nil
+	})
+
+	// Test 2: Restore the backup
+	if err := runCommand([]string{"restore", written[0]}, testUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The restore closed the database
+	replaced, _ := filepath.Glob(path + ".*.bak")
+	for _, file := range replaced {
+		defer os.Remove(file)
+	}
+	if len(replaced) != 1 {
+		t.Errorf("Expected the replaced database to be kept, got %v", replaced)
+	}
+
+	restored, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open the restored database: %v", err)
+	}
+	defer restored.Close()
+	restored.View(func(root *bolt.Tx) error {
+		if _, found, _ := testTx(root).Day("2024-03-01"); found {
+			t.Errorf("Expected the day added after the backup to be gone")
+		}
+		if _, found, _ := getUser(root, testUser); !found {
+			t.Errorf("Expected the account to be restored")
+		}
+		return nil
+	})
+}
+
+func TestRunRestoreData(t *testing.T) {
+	if !tracker.SQLiteSupported {
+		t.Skip("SQLite needs cgo")
+	}
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db and store
+	origDB := db
+	db = testDB
+	origStore, origClose := dataStore, closeDataStore
+	defer func() {
+		db = origDB
+		dataStore, closeDataStore = origStore, origClose
+	}()
+
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "data.sqlite")
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DB_PATH", dataFile)
+
+	store, closeStore, err := openStore("sqlite", dataFile)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", dataFile, err)
+	}
+	dataStore, closeDataStore = store, closeStore
+	defer closeStore()
+	if err := syncUserData(); err != nil {
+		t.Fatalf("Failed to create user data: %v", err)
+	}
+	putDay := func(date string) {
+		store.Update(testUser, func(tx tracker.Tx) error {
+			return tx.PutDay(tracker.DayData{Date: date, Count: 10})
+		})
+	}
+	putDay("2024-03-01")
+
+	written, err := writeBackup(dir, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+	if len(written) != 2 {
+		t.Fatalf("Expected pushups.db and the data to be backed up, got %v", written)
+	}
+	putDay("2024-03-02")
+
+	// The accounts snapshot isn't tracker data
+	if err := runCommand([]string{"restore", "-data", written[0]}, testUser); err == nil {
+		t.Errorf("Expected error for a snapshot of the accounts")
+	}
+
+	if err := runCommand([]string{"restore", "-data", written[1]}, testUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	replaced, _ := filepath.Glob(dataFile + ".*.bak")
+	if len(replaced) != 1 {
+		t.Errorf("Expected the replaced data to be kept, got %v", replaced)
+	}
+
+	restored, closeRestored, err := openStore("sqlite", dataFile)
+	if err != nil {
+		t.Fatalf("Failed to open the restored data: %v", err)
+	}
+	defer closeRestored()
+	restored.View(testUser, func(tx tracker.Tx) error {
+		if _, found, _ := tx.Day("2024-03-01"); !found {
+			t.Errorf("Expected the backed up day to be restored")
+		}
+		if _, found, _ := tx.Day("2024-03-02"); found {
+			t.Errorf("Expected the day added after the backup to be gone")
+		}
+		return nil
+	})
+}
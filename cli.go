@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// dayTracker is what the tracker commands work with: the tracker data itself,
+// or the API of the running server while it holds the database, see apiClient
+type dayTracker interface {
+	Today(username string) (tracker.Day, error)
+	Complete(username string) (tracker.Day, error)
+	Undo(username string) (tracker.Day, error)
+	Log(username string, reps int, note string) (tracker.Day, error)
+	SetTarget(username string, count int) (tracker.Day, error)
+	Streak(username string) (tracker.StreakData, error)
+	Calendar(username string, year int) (tracker.Calendar, error)
+	History(username, from, to string) ([]tracker.DayData, error)
+	Export(username string) (tracker.Export, error)
+}
+
+// localTracker works on the tracker data directly. Today doesn't create today's
+// record, so that it works on a database opened read-only.
+type localTracker struct {
+	*tracker.Service
+}
+
+func (l localTracker) Today(username string) (tracker.Day, error) {
+	return l.Peek(username)
+}
+
+func (l localTracker) Log(username string, reps int, note string) (tracker.Day, error) {
+	day, _, err := l.AddSet(username, l.Date(username), reps, note)
+	return day, err
+}
+
+// remote is set when the commands run through the API of the running server
+var remote dayTracker
+
+// commands returns what the tracker commands work with
+func commands() dayTracker {
+	if remote != nil {
+		return remote
+	}
+	return localTracker{service()}
+}
+
+// commandFlags returns the flags every tracker command has: -user, the account the
+// commands work on by default, and -json to print what the API returns
+func commandFlags(name, defaultUser string) (flags *flag.FlagSet, username *string, asJSON *bool) {
+	flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	username = flags.String("user", defaultUser, "user to work on")
+	asJSON = flags.Bool("json", false, "print JSON like the API instead of text")
+	return flags, username, asJSON
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printDay prints a day with its sets, e.g.
+//
+//	2024-03-10: 25/40 reps, 15 to go
+//	  08:12  15 reps  morning
+func printDay(w io.Writer, day tracker.Day) {
+	state := fmt.Sprintf("%d to go", day.Remaining)
+	switch {
+	case day.Done:
+		state = "done"
+	case tracker.IsDayOff(day.DayData):
+		state = day.Status + " day"
+	}
+	fmt.Fprintf(w, "%s: %d/%d reps, %s\n", day.Date, day.Reps, day.Count, state)
+
+	for _, set := range day.Sets {
+		at := set.Time
+		if t, err := time.Parse(time.RFC3339, set.Time); err == nil {
+			at = t.Format("15:04")
+		}
+		line := fmt.Sprintf("  %s  %d reps", at, set.Reps)
+		if set.Note != "" {
+			line += "  " + set.Note
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// runToday implements `push_up_tracker today`, which shows today's target and sets
+func runToday(w io.Writer, args []string, defaultUser string) error {
+	flags, username, asJSON := commandFlags("today", defaultUser)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	day, err := commands().Today(*username)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(w, day)
+	}
+	printDay(w, day)
+	return nil
+}
+
+// runComplete implements `push_up_tracker complete`, which completes today like
+// the button of the web page, or with -undo reopens it
+func runComplete(w io.Writer, args []string, defaultUser string) error {
+	flags, username, asJSON := commandFlags("complete", defaultUser)
+	undo := flags.Bool("undo", false, "reopen today after it was completed by mistake")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var day tracker.Day
+	var err error
+	if *undo {
+		day, err = commands().Undo(*username)
+	} else {
+		day, err = commands().Complete(*username)
+	}
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(w, day)
+	}
+	printDay(w, day)
+	return nil
+}
+
+// runLog implements `push_up_tracker log [-note text] reps`, which logs a set on today
+func runLog(w io.Writer, args []string, defaultUser string) error {
+	flags, username, asJSON := commandFlags("log", defaultUser)
+	note := flags.String("note", "", "note to attach to the set")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: log [-user name] [-note text] reps")
+	}
+	reps, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("reps must be a number, got %q", flags.Arg(0))
+	}
+
+	day, err := commands().Log(*username, reps, *note)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(w, day)
+	}
+	printDay(w, day)
+	return nil
+}
+
+// runSetTarget implements `push_up_tracker set-target count`, which replaces
+// today's target
+func runSetTarget(w io.Writer, args []string, defaultUser string) error {
+	flags, username, asJSON := commandFlags("set-target", defaultUser)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: set-target [-user name] count")
+	}
+	count, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("target must be a number, got %q", flags.Arg(0))
+	}
+
+	day, err := commands().SetTarget(*username, count)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(w, day)
+	}
+	printDay(w, day)
+	return nil
+}
+
+// runStreak implements `push_up_tracker streak`
+func runStreak(w io.Writer, args []string, defaultUser string) error {
+	flags, username, asJSON := commandFlags("streak", defaultUser)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	streak, err := commands().Streak(*username)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(w, streak)
+	}
+	fmt.Fprintf(w, "Current streak: %d days\n", streak.Current)
+	fmt.Fprintf(w, "Longest streak: %d days\n", streak.Longest)
+	if streak.LastDate != "" {
+		fmt.Fprintf(w, "Last completed: %s\n", streak.LastDate)
+	}
+	fmt.Fprintf(w, "Streak freezes: %d (%d used)\n", streak.Freezes, streak.FreezesUsed)
+	return nil
+}
+
+// calendarMark returns the mark of a date in the text calendar: x for a completed
+// day, - for a missed one, r for a day off, and . for days before the first
+// record, today while it is open and the days after it
+func calendarMark(calendar tracker.Calendar, date time.Time, start string) byte {
+	key := date.Format("2006-01-02")
+	dayData, found := calendar.Days[key]
+	restDay := false
+	for _, weekday := range calendar.RestDays {
+		restDay = restDay || int(date.Weekday()) == weekday
+	}
+
+	switch {
+	case found && dayData.Done:
+		return 'x'
+	case key < start || key >= calendar.Today:
+		return '.'
+	case found && tracker.IsDayOff(dayData), !found && restDay:
+		return 'r'
+	default:
+		return '-'
+	}
+}
+
+// printCalendar prints a year as one row per month and one column per day
+func printCalendar(w io.Writer, calendar tracker.Calendar, year int) {
+	// Day numbers above the columns 1, 5, 10, ... 30
+	header := []byte(strings.Repeat(" ", 31))
+	for _, day := range []int{1, 5, 10, 15, 20, 25, 30} {
+		copy(header[day-1:], strconv.Itoa(day))
+	}
+	fmt.Fprintf(w, "%-5d%s\n", year, strings.TrimRight(string(header), " "))
+
+	// Days before the first record aren't missed. The first record is among the
+	// days if it is in this year, and without any the whole start month is open.
+	startMonth := fmt.Sprintf("%04d-%02d", calendar.StartYear, calendar.StartMonth+1)
+	start := startMonth + "-32"
+	for date := range calendar.Days {
+		if strings.HasPrefix(date, startMonth) && date < start {
+			start = date
+		}
+	}
+
+	for month := time.January; month <= time.December; month++ {
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		var row []byte
+		for date := first; date.Month() == month; date = date.AddDate(0, 0, 1) {
+			row = append(row, calendarMark(calendar, date, start))
+		}
+		fmt.Fprintf(w, "%-5s%s\n", month.String()[:3], row)
+	}
+	fmt.Fprintln(w, "x done, - missed, r day off")
+}
+
+// runCalendar implements `push_up_tracker calendar [-year 2024]`
+func runCalendar(w io.Writer, args []string, defaultUser string) error {
+	flags, username, asJSON := commandFlags("calendar", defaultUser)
+	year := flags.Int("year", 0, "year to show, the current one by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *year < 0 || *year > 9999 {
+		return fmt.Errorf("invalid year %d", *year)
+	}
+
+	calendar, err := commands().Calendar(*username, *year)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(w, calendar)
+	}
+	if *year == 0 {
+		*year = calendar.Year
+	}
+	printCalendar(w, calendar, *year)
+	return nil
+}
+
+// runHistory implements `push_up_tracker history [-from date] [-to date]`, which
+// lists the recorded days with a total at the end
+func runHistory(w io.Writer, args []string, defaultUser string) error {
+	flags, username, asJSON := commandFlags("history", defaultUser)
+	from := flags.String("from", "", "first date, YYYY-MM-DD")
+	to := flags.String("to", "", "last date, YYYY-MM-DD")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	for _, date := range []string{*from, *to} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+		}
+	}
+
+	days, err := commands().History(*username, *from, *to)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(w, days)
+	}
+
+	completed, reps := 0, 0
+	for _, day := range days {
+		state := ""
+		switch {
+		case day.Done:
+			state = "done"
+			completed++
+		case tracker.IsDayOff(day):
+			state = day.Status
+		}
+		reps += day.Reps
+		line := fmt.Sprintf("%s  %4d/%-4d %s", day.Date, day.Reps, day.Count, state)
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+	fmt.Fprintf(w, "%d days, %d completed, %d reps\n", len(days), completed, reps)
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestDayCommands(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db and clock
+	origDB, origClock := db, clock
+	db = testDB
+	defer func() { db, clock = origDB, origClock }()
+	fake := &tracker.OffsetClock{}
+	fake.Set(time.Date(2024, 3, 4, 12, 0, 0, 0, time.Local))
+	clock = fake
+
+	run := func(fn func(w *bytes.Buffer) error) string {
+		t.Helper()
+		var out bytes.Buffer
+		if err := fn(&out); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	// Test 1: Today's target, then a set and a lower target that completes it
+	out := run(func(w *bytes.Buffer) error { return runToday(w, nil, testUser) })
+	if out != "2024-03-04: 0/10 reps, 10 to go\n" {
+		t.Errorf("Unexpected today output %q", out)
+	}
+	out = run(func(w *bytes.Buffer) error { return runLog(w, []string{"-note", "morning", "6"}, testUser) })
+	if !strings.HasPrefix(out, "2024-03-04: 6/10 reps, 4 to go\n") || !strings.Contains(out, "6 reps  morning") {
+		t.Errorf("Expected the set to be logged, got %q", out)
+	}
+	out = run(func(w *bytes.Buffer) error { return runSetTarget(w, []string{"6"}, testUser) })
+	if !strings.HasPrefix(out, "2024-03-04: 6/6 reps, done\n") {
+		t.Errorf("Expected the lower target to complete the day, got %q", out)
+	}
+
+	// Test 2: Complete the next day, back at the starting target
+	fake.Shift(24 * time.Hour)
+	out = run(func(w *bytes.Buffer) error { return runComplete(w, nil, testUser) })
+	if !strings.HasPrefix(out, "2024-03-05: 10/10 reps, done\n") {
+		t.Errorf("Expected the next day to be completed, got %q", out)
+	}
+	out = run(func(w *bytes.Buffer) error { return runStreak(w, nil, testUser) })
+	if !strings.HasPrefix(out, "Current streak: 2 days\nLongest streak: 2 days\n") {
+		t.Errorf("Unexpected streak output %q", out)
+	}
+
+	// Test 3: History and calendar
+	out = run(func(w *bytes.Buffer) error { return runHistory(w, []string{"-from", "2024-03-01"}, testUser) })
+	expected := "2024-03-04     6/6    done\n2024-03-05    10/10   done\n2 days, 2 completed, 16 reps\n"
+	if out != expected {
+		t.Errorf("Expected history %q, got %q", expected, out)
+	}
+	out = run(func(w *bytes.Buffer) error { return runCalendar(w, []string{"-year", "2024"}, testUser) })
+	if !strings.Contains(out, "\nMar  ...xx"+strings.Repeat(".", 26)+"\n") || !strings.Contains(out, "\nFeb  "+strings.Repeat(".", 29)+"\n") {
+		t.Errorf("Unexpected calendar %q", out)
+	}
+	out = run(func(w *bytes.Buffer) error { return runCalendar(w, []string{"-json"}, testUser) })
+	var calendar tracker.Calendar
+	if err := json.Unmarshal([]byte(out), &calendar); err != nil || len(calendar.Days) != 2 {
+		t.Errorf("Expected the calendar as JSON, got %q (%v)", out, err)
+	}
+
+	// Test 4: Invalid arguments
+	for _, args := range [][]string{
+		{"log"},
+		{"log", "many"},
+		{"log", "0"},
+		{"set-target", "0"},
+		{"history", "-to", "March"},
+		{"calendar", "-year", "-1"},
+		{"today", "-user", "nobody"},
+		{"jump"},
+	} {
+		if err := runCommand(args, testUser); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// clock is the time all date logic reads: today, sets, streaks and exports. Logins,
+// sessions and tokens use the real time, so moving the clock doesn't log anyone out.
+var clock tracker.Clock = tracker.SystemClock{}
+
+// parseFakeNow reads a moment given as an RFC 3339 timestamp, a date, which
+// starts at midnight in the server's time zone, or "now"
+func parseFakeNow(value string) (time.Time, error) {
+	if value == "now" {
+		return time.Now(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339, YYYY-MM-DD or now", value)
+}
+
+// clockFromEnv starts a movable clock at FAKE_NOW when it is set. This is meant for
+// debugging and simulating long runs, never for a server people actually use.
+func clockFromEnv() error {
+	value := os.Getenv("FAKE_NOW")
+	if value == "" {
+		return nil
+	}
+
+	start, err := parseFakeNow(value)
+	if err != nil {
+		return err
+	}
+	fake := &tracker.OffsetClock{}
+	fake.Set(start)
+	clock = fake
+
+	log.Printf("Warning: FAKE_NOW is set, the clock starts at %s and can be moved with /api/admin/clock", start.Format(time.RFC3339))
+	return nil
+}
+
+// handleAdminClock reports the clock with GET, moves it with PUT, e.g.
+// {"now": "2024-03-10T23:59:00Z"}, {"shift": "36h"} or {"days": 7}, and puts it
+// back to the real time with DELETE. It only exists when FAKE_NOW is set.
+func handleAdminClock(w http.ResponseWriter, r *http.Request) {
+	fake, ok := clock.(*tracker.OffsetClock)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		var req struct {
+			Now   string `json:"now"`
+			Shift string `json:"shift"`
+			Days  int    `json:"days"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		switch {
+		case req.Now != "":
+			var t time.Time
+			if t, err = parseFakeNow(req.Now); err == nil {
+				fake.Set(t)
+			}
+		case req.Shift != "":
+			var d time.Duration
+			if d, err = time.ParseDuration(req.Shift); err == nil {
+				fake.Shift(d)
+			}
+		case req.Days != 0:
+			fake.Shift(time.Duration(req.Days) * 24 * time.Hour)
+		default:
+			err = errors.New("expected now, shift or days")
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case "DELETE":
+		fake.Reset()
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := struct {
+		Now    string `json:"now"`
+		Offset string `json:"offset"`
+		Today  string `json:"today"`
+	}{
+		Now:    fake.Now().Format(time.RFC3339),
+		Offset: fake.Offset().Round(time.Second).String(),
+		Today:  requestToday(r),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
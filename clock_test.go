@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestParseFakeNow(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"2024-03-10T23:59:00Z", true},
+		{"2024-03-10T23:59:00+05:00", true},
+		{"2024-03-10", true},
+		{"now", true},
+		{"yesterday", false},
+		{"2024-03-10 23:59", false},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseFakeNow(tt.value); (err == nil) != tt.valid {
+			t.Errorf("Expected valid %v for %q, got %v", tt.valid, tt.value, err)
+		}
+	}
+}
+
+func TestHandleAdminClock(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db and clock
+	origDB, origClock := db, clock
+	db = testDB
+	defer func() { db, clock = origDB, origClock }()
+
+	// Test 1: Without FAKE_NOW there is no clock to move
+	req := httptest.NewRequest("GET", "/api/admin/clock", nil)
+	w := httptest.NewRecorder()
+
+	handleAdminClock(w, asTestUser(req))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for the real clock, got %d", w.Code)
+	}
+
+	clock = &tracker.OffsetClock{}
+	var response struct {
+		Now   string `json:"now"`
+		Today string `json:"today"`
+	}
+
+	// Test 2: Set the clock, then move it a day forward
+	for _, body := range []string{`{"now": "2024-03-10T12:00:00Z"}`, `{"days": 1}`} {
+		req = httptest.NewRequest("PUT", "/api/admin/clock", strings.NewReader(body))
+		w = httptest.NewRecorder()
+
+		handleAdminClock(w, asTestUser(req))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !strings.HasPrefix(response.Now, "2024-03-11T12:00") {
+		t.Errorf("Expected the clock a day later, got %s", response.Now)
+	}
+
+	// Test 3: DELETE goes back to the real time
+	req = httptest.NewRequest("DELETE", "/api/admin/clock", nil)
+	w = httptest.NewRecorder()
+
+	handleAdminClock(w, asTestUser(req))
+
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Today != time.Now().Format("2006-01-02") {
+		t.Errorf("Expected today to be the real date, got %s", response.Today)
+	}
+
+	// Test 4: Invalid requests
+	for _, tt := range []struct{ method, body string }{
+		{"PUT", `{}`},
+		{"PUT", `{"shift": "a week"}`},
+		{"PUT", `{"now": "tomorrow"}`},
+		{"POST", `{"days": 1}`},
+	} {
+		req = httptest.NewRequest(tt.method, "/api/admin/clock", strings.NewReader(tt.body))
+		w = httptest.NewRecorder()
+
+		handleAdminClock(w, asTestUser(req))
+
+		if w.Code == http.StatusOK {
+			t.Errorf("Expected error for %s %s, got %d", tt.method, tt.body, w.Code)
+		}
+	}
+}
+
+func TestSimulatedProgression(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db and clock
+	origDB, origClock := db, clock
+	db = testDB
+	defer func() { db, clock = origDB, origClock }()
+
+	fake := &tracker.OffsetClock{}
+	start, _ := time.Parse(time.RFC3339, "2024-03-01T12:00:00Z")
+	fake.Set(start)
+	clock = fake
+
+	settings := envSettings
+	settings.Timezone = "UTC"
+	service().SaveSettings(testUser, settings)
+
+	today := func() tracker.Day {
+		req := httptest.NewRequest("GET", "/api/today", nil)
+		w := httptest.NewRecorder()
+		handleToday(w, asTestUser(req))
+
+		var response tracker.Day
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+	streak := func() tracker.StreakData {
+		req := httptest.NewRequest("GET", "/api/streak", nil)
+		w := httptest.NewRecorder()
+		handleStreak(w, asTestUser(req))
+
+		var response tracker.StreakData
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	// Three weeks of completed days climb the ladder by 2 a day
+	for day := 0; day < 21; day++ {
+		if got := today(); got.Count != 10+2*day || got.Date != start.AddDate(0, 0, day).Format("2006-01-02") {
+			t.Fatalf("Day %d: expected target %d, got %+v", day+1, 10+2*day, got)
+		}
+
+		req := httptest.NewRequest("POST", "/api/today/complete", nil)
+		w := httptest.NewRecorder()
+		handleTodayComplete(w, asTestUser(req))
+
+		fake.Shift(24 * time.Hour)
+	}
+
+	if got := today(); got.Count != 51 {
+		t.Errorf("Expected target 51 at the next tier, got %d", got.Count)
+	}
+	if got := streak(); got.Current != 21 || got.Longest != 21 || got.Freezes != 2 {
+		t.Errorf("Expected a 21 day streak with 2 freezes, got %+v", got)
+	}
+
+	// Skipping that day uses up a freeze
+	fake.Shift(24 * time.Hour)
+	if got := streak(); got.Current != 21 || got.Freezes != 1 || got.FreezesUsed != 1 {
+		t.Errorf("Expected the missed day to be frozen, got %+v", got)
+	}
+}
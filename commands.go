@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// readOnlyCommands only read the data, so they open the database read-only
+var readOnlyCommands = map[string]bool{
+	"today": true, "streak": true, "calendar": true, "history": true, "export": true,
+}
+
+// runCommand runs a command-line subcommand, e.g. `push_up_tracker export`, instead
+// of the server, which is started without one or with `serve`. defaultUser is the
+// account commands work on without -user.
+func runCommand(args []string, defaultUser string) error {
+	switch args[0] {
+	case "today":
+		return runToday(os.Stdout, args[1:], defaultUser)
+	case "complete":
+		return runComplete(os.Stdout, args[1:], defaultUser)
+	case "log":
+		return runLog(os.Stdout, args[1:], defaultUser)
+	case "set-target":
+		return runSetTarget(os.Stdout, args[1:], defaultUser)
+	case "streak":
+		return runStreak(os.Stdout, args[1:], defaultUser)
+	case "calendar":
+		return runCalendar(os.Stdout, args[1:], defaultUser)
+	case "history":
+		return runHistory(os.Stdout, args[1:], defaultUser)
+	case "export":
+		return runExport(args[1:], defaultUser)
+	case "import":
+		return runImport(args[1:], defaultUser)
+	case "rebuild-streaks":
+		return runRebuildStreaks(args[1:])
+	case "migrate":
+		return runMigrate(args[1:])
+	case "restore":
+		return runRestore(args[1:])
+	case "fsck":
+		return runFsck(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q, available commands: serve, today, complete, log, set-target, streak, calendar, history, "+
+			"export, import, rebuild-streaks, migrate, restore, fsck", args[0])
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// handleDay reads a day with GET, edits it with PUT, e.g. {"done": true} or
+// {"reps": 25}, and deletes it with DELETE. Only days within the backfill
+// window can be changed.
+func handleDay(w http.ResponseWriter, r *http.Request, date string) {
+	var day tracker.Day
+	found := true
+	var err error
+	switch r.Method {
+	case "GET":
+		day, found, err = service().Day(requestUser(r), date)
+	case "PUT":
+		var req struct {
+			Done *bool `json:"done"`
+			Reps *int  `json:"reps"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Done == nil && req.Reps == nil {
+			http.Error(w, "Nothing to change, expected done or reps", http.StatusBadRequest)
+			return
+		}
+		if req.Reps != nil && (*req.Reps < 0 || *req.Reps > tracker.MaxImportCount) {
+			http.Error(w, fmt.Sprintf("reps must be between 0 and %d", tracker.MaxImportCount), http.StatusBadRequest)
+			return
+		}
+		day, err = service().EditDay(requestUser(r), date, req.Done, req.Reps)
+	case "DELETE":
+		found, err = service().DeleteDay(requestUser(r), date)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if errors.Is(err, tracker.ErrFutureDate) {
+		http.Error(w, "Date is in the future", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, tracker.ErrOutsideBackfill) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Day not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == "DELETE" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(day)
+}
+
+// handleDayAudit lists the changes made to a day
+func handleDayAudit(w http.ResponseWriter, r *http.Request, date string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entries, err := service().Audit(requestUser(r), date)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
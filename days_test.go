@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestHandleDay(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	twoDaysAgo := time.Now().AddDate(0, 0, -2).Format("2006-01-02")
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		tx.PutConfig("firstDay", twoDaysAgo)
+		return tx.PutDay(tracker.DayData{Date: twoDaysAgo, Count: 10, Reps: 10, Done: true})
+	})
+
+	// Test 1: Backfill yesterday, which has no record
+	req := httptest.NewRequest("PUT", "/api/days/"+yesterday, strings.NewReader(`{"done": true}`))
+	w := httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response tracker.Day
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !response.Done || response.Count != 12 {
+		t.Errorf("Expected completed day with target 12, got %+v", response)
+	}
+
+	// Test 2: GET returns it, and the audit trail has the change
+	req = httptest.NewRequest("GET", "/api/days/"+yesterday, nil)
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/days/"+yesterday+"/audit", nil)
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	var entries []tracker.AuditEntry
+	json.Unmarshal(w.Body.Bytes(), &entries)
+	if len(entries) != 1 || entries[0].Before != nil {
+		t.Errorf("Expected one audit entry for a new day, got %s", w.Body.String())
+	}
+
+	// Test 3: DELETE removes it
+	req = httptest.NewRequest("DELETE", "/api/days/"+yesterday, nil)
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/days/"+yesterday, nil)
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	// Test 4: Days before the backfill window can't be changed
+	old := time.Now().AddDate(0, 0, -30).Format("2006-01-02")
+	req = httptest.NewRequest("PUT", "/api/days/"+old, strings.NewReader(`{"done": true}`))
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+
+	// Test 5: Invalid requests
+	for _, tt := range []struct{ method, body string }{
+		{"PUT", `{}`},
+		{"PUT", `{"reps": -1}`},
+		{"PUT", `not json`},
+		{"POST", `{"done": true}`},
+	} {
+		req = httptest.NewRequest(tt.method, "/api/days/"+yesterday, strings.NewReader(tt.body))
+		w = httptest.NewRecorder()
+
+		handleDays(w, asTestUser(req))
+
+		if w.Code == http.StatusOK {
+			t.Errorf("Expected error for %s %s, got %d", tt.method, tt.body, w.Code)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// exportFormats lists the formats accepted by the export endpoint and command
+var exportFormats = []string{"json", "csv"}
+
+// csvHeader is the first row of a CSV export, one row per day follows
+var csvHeader = []string{"date", "count", "reps", "done", "status"}
+
+// writeExport writes the export as indented JSON, or as CSV with one row per day.
+// The streak and config are only part of the JSON format.
+func writeExport(w io.Writer, export tracker.Export, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(export)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(csvHeader)
+		for _, day := range export.Days {
+			cw.Write([]string{
+				day.Date,
+				strconv.Itoa(day.Count),
+				strconv.Itoa(day.Reps),
+				strconv.FormatBool(day.Done),
+				day.Status,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("format must be one of %v", exportFormats)
+	}
+}
+
+func validExportFormat(format string) bool {
+	for _, f := range exportFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if !validExportFormat(format) {
+		http.Error(w, fmt.Sprintf("format must be one of %v", exportFormats), http.StatusBadRequest)
+		return
+	}
+
+	username := requestUser(r)
+	export, err := service().Export(username)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	contentTypes := map[string]string{"json": "application/json", "csv": "text/csv"}
+	filename := fmt.Sprintf("pushups-%s-%s.%s", username, export.Exported[:10], format)
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	writeExport(w, export, format)
+}
+
+// runExport implements `push_up_tracker export [-user name] [-format json|csv] [-o file]`
+func runExport(args []string, defaultUser string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	username := flags.String("user", defaultUser, "user to export")
+	format := flags.String("format", "json", "export format, json or csv")
+	output := flags.String("o", "", "file to write to instead of standard output")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if !validExportFormat(*format) {
+		return fmt.Errorf("format must be one of %v", exportFormats)
+	}
+
+	export, err := commands().Export(*username)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return writeExport(os.Stdout, export, *format)
+	}
+
+	f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := writeExport(f, export, *format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
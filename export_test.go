@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// seedExportData stores a short history for the test user
+func seedExportData(t *testing.T, testDB *bolt.DB) {
+	t.Helper()
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		tx.PutConfig("firstDay", "2024-03-08")
+		tx.PutConfig("daysAtLevel", "2")
+		tx.PutDay(tracker.DayData{Date: "2024-03-08", Count: 10, Reps: 10, Done: true})
+		tx.PutDay(tracker.DayData{Date: "2024-03-09", Count: 11, Reps: 4})
+		return tx.PutStreak(tracker.StreakData{Current: 1, Longest: 1, LastDate: "2024-03-08"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add test data: %v", err)
+	}
+}
+
+func TestWriteExportCSV(t *testing.T) {
+	export := tracker.Export{Days: []tracker.DayData{
+		{Date: "2024-03-08", Count: 10, Reps: 10, Done: true},
+		{Date: "2024-03-09", Count: 11, Reps: 4, Status: tracker.StatusRest},
+	}}
+
+	var buf bytes.Buffer
+	if err := writeExport(&buf, export, "csv"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "date,count,reps,done,status\n2024-03-08,10,10,true,\n2024-03-09,11,4,false,rest\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	if err := writeExport(&buf, export, "xml"); err == nil {
+		t.Errorf("Expected error for unknown format")
+	}
+}
+
+func TestHandleExport(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	seedExportData(t, testDB)
+
+	// Test 1: JSON is the default format
+	req := httptest.NewRequest("GET", "/api/export", nil)
+	w := httptest.NewRecorder()
+
+	handleExport(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), "pushups-admin-") {
+		t.Errorf("Expected attachment filename, got %q", w.Header().Get("Content-Disposition"))
+	}
+	var export tracker.Export
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil || len(export.Days) != 2 {
+		t.Errorf("Expected JSON export with 2 days, got %v: %s", err, w.Body.String())
+	}
+
+	// Test 2: CSV
+	req = httptest.NewRequest("GET", "/api/export?format=csv", nil)
+	w = httptest.NewRecorder()
+
+	handleExport(w, asTestUser(req))
+
+	if w.Header().Get("Content-Type") != "text/csv" || !strings.HasPrefix(w.Body.String(), "date,count,reps,done,status\n") {
+		t.Errorf("Expected CSV export, got %q: %s", w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	// Test 3: Unknown format
+	req = httptest.NewRequest("GET", "/api/export?format=xml", nil)
+	w = httptest.NewRecorder()
+
+	handleExport(w, asTestUser(req))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	seedExportData(t, testDB)
+
+	output := filepath.Join(t.TempDir(), "export.csv")
+	if err := runCommand([]string{"export", "-format", "csv", "-o", output}, testUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 3 {
+		t.Errorf("Expected header and 2 days, got %q", data)
+	}
+
+	for _, args := range [][]string{
+		{"export", "-user", "nobody"},
+		{"export", "-format", "xml"},
+		{"unknown"},
+	} {
+		if err := runCommand(args, testUser); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// userFsck is what fsck found in one user's data
+type userFsck struct {
+	Username string `json:"username"`
+	tracker.FsckResult
+}
+
+// countProblems returns the number of problems fixing changes and of warnings,
+// which it leaves as they are
+func countProblems(result tracker.FsckResult) (problems, warnings int) {
+	for _, problem := range result.Problems {
+		if problem.Warning {
+			warnings++
+		} else {
+			problems++
+		}
+	}
+	return problems, warnings
+}
+
+// fsckUsers checks the data of one user, or of everyone for "". With fix the
+// data is backed up and fixed if anything but warnings was found, and the backup
+// returned.
+func fsckUsers(username string, fix bool) (results []userFsck, backup string, err error) {
+	usernames := []string{username}
+	if username == "" {
+		usernames, err = trackerStore().Users()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	found := false
+	for _, name := range usernames {
+		result, err := service().Fsck(name, false)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", name, err)
+		}
+		problems, _ := countProblems(result)
+		found = found || problems > 0
+		results = append(results, userFsck{name, result})
+	}
+	if !fix || !found {
+		return results, "", nil
+	}
+
+	backup, err = backupStore()
+	if err != nil {
+		return nil, "", fmt.Errorf("backup: %v", err)
+	}
+	for i := range results {
+		result, err := service().Fsck(results[i].Username, true)
+		if err != nil {
+			return nil, backup, fmt.Errorf("%s: %w", results[i].Username, err)
+		}
+		results[i].FsckResult = result
+	}
+	return results, backup, nil
+}
+
+func printFsck(w io.Writer, results []userFsck) {
+	for _, r := range results {
+		problems, warnings := countProblems(r.FsckResult)
+		state := "found"
+		if r.Fixed {
+			state = "fixed"
+		}
+		line := fmt.Sprintf("%s: %d problems %s", r.Username, problems, state)
+		if problems == 0 {
+			line = r.Username + ": ok"
+		}
+		if warnings > 0 {
+			line += fmt.Sprintf(", %d warnings", warnings)
+		}
+		fmt.Fprintln(w, line)
+		for _, problem := range r.Problems {
+			subject := problem.Check
+			if problem.Key != "" {
+				subject += " " + problem.Key
+			}
+			fmt.Fprintf(w, "  %s: %s, %s\n", subject, problem.Detail, problem.Fix)
+		}
+	}
+}
+
+// runFsck implements `push_up_tracker fsck [-user name] [-fix]`, which checks the
+// tracker data and fails if it has problems other than warnings. With -fix they
+// are fixed after the data is backed up.
+func runFsck(args []string) error {
+	flags := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	username := flags.String("user", "", "only check this user's data")
+	fix := flags.Bool("fix", false, "fix the problems found, after backing up the data")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("usage: fsck [-user name] [-fix]")
+	}
+
+	results, backup, err := fsckUsers(*username, *fix)
+	if err != nil {
+		return err
+	}
+	printFsck(os.Stdout, results)
+	if backup != "" {
+		fmt.Printf("The data from before is in %s\n", backup)
+	}
+
+	problems := 0
+	for _, r := range results {
+		n, _ := countProblems(r.FsckResult)
+		problems += n
+	}
+	if problems > 0 && !*fix {
+		return fmt.Errorf("%d problems found, run fsck -fix to fix them", problems)
+	}
+	return nil
+}
+
+// handleFsck checks the tracker data of every user, or of one with ?user=, on GET
+// and fixes it on POST after backing it up
+func handleFsck(w http.ResponseWriter, r *http.Request) {
+	var fix bool
+	switch r.Method {
+	case "GET":
+	case "POST":
+		fix = true
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	results, backup, err := fsckUsers(r.URL.Query().Get("user"), fix)
+	if errors.Is(err, tracker.ErrUserNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Users []userFsck `json:"users"`
+		// Backup is the file the data was backed up to before fixing it
+		Backup string `json:"backup,omitempty"`
+	}{results, backup}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
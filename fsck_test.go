@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestHandleFsck(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+	t.Setenv("DB_DRIVER", "")
+	t.Setenv("DB_PATH", testDB.Path())
+
+	// A record under a key that isn't a date
+	days := func(root *bolt.Tx) *bolt.Bucket {
+		return root.Bucket([]byte("UserData")).Bucket([]byte(testUser)).Bucket([]byte("Days"))
+	}
+	testDB.Update(func(root *bolt.Tx) error {
+		return days(root).Put([]byte("notes"), []byte(`{}`))
+	})
+
+	// Test 1: Check everyone
+	req := httptest.NewRequest("GET", "/api/admin/fsck", nil)
+	w := httptest.NewRecorder()
+
+	handleFsck(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Users  []userFsck `json:"users"`
+		Backup string     `json:"backup"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	if len(response.Users) != 1 || len(response.Users[0].Problems) != 1 || response.Users[0].Fixed || response.Backup != "" {
+		t.Errorf("Expected the damaged record to be reported, got %+v", response)
+	}
+	if problem := response.Users[0].Problems[0]; problem.Check != "record" || problem.Key != "day notes" {
+		t.Errorf("Expected the record under notes, got %+v", problem)
+	}
+
+	// Test 2: Unknown users and methods
+	req = httptest.NewRequest("GET", "/api/admin/fsck?user=nobody", nil)
+	w = httptest.NewRecorder()
+
+	handleFsck(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("PUT", "/api/admin/fsck", nil)
+	w = httptest.NewRecorder()
+
+	handleFsck(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+
+	// Test 3: Fix the data, which is backed up first
+	req = httptest.NewRequest("POST", "/api/admin/fsck?user="+testUser, nil)
+	w = httptest.NewRecorder()
+
+	handleFsck(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	response.Backup = ""
+	json.NewDecoder(w.Body).Decode(&response)
+	if response.Backup != "" {
+		defer os.Remove(response.Backup)
+	}
+	if len(response.Users) != 1 || !response.Users[0].Fixed || response.Backup == "" {
+		t.Errorf("Expected the data to be backed up and fixed, got %+v", response)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		if days(root).Get([]byte("notes")) != nil {
+			t.Errorf("Expected the damaged record to be deleted")
+		}
+		return nil
+	})
+}
+
+func TestRunFsck(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+	t.Setenv("DB_DRIVER", "")
+	t.Setenv("DB_PATH", testDB.Path())
+
+	backups := func() []string {
+		files, _ := filepath.Glob(testDB.Path() + ".*.bak")
+		return files
+	}
+
+	// Test 1: Clean data passes
+	if err := runCommand([]string{"fsck"}, testUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Test 2: A streak that doesn't match the history fails the check
+	testDB.Update(func(root *bolt.Tx) error {
+		streak := root.Bucket([]byte("UserData")).Bucket([]byte(testUser)).Bucket([]byte("Streak"))
+		return streak.Put([]byte("current"), []byte(`{"current":5,"longest":5}`))
+	})
+	if err := runCommand([]string{"fsck", "-user", testUser}, testUser); err == nil {
+		t.Errorf("Expected error for data with problems")
+	}
+	if len(backups()) != 0 {
+		t.Errorf("Expected no backup without -fix, got %v", backups())
+	}
+
+	// Test 3: Fixing backs up the data first
+	if err := runCommand([]string{"fsck", "-fix"}, testUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	files := backups()
+	for _, file := range files {
+		defer os.Remove(file)
+	}
+	if len(files) != 1 {
+		t.Errorf("Expected one backup, got %v", files)
+	}
+	if err := runCommand([]string{"fsck"}, testUser); err != nil {
+		t.Errorf("Expected no problems after fixing, got %v", err)
+	}
+
+	// Test 4: Invalid arguments
+	for _, args := range [][]string{
+		{"fsck", "extra"},
+		{"fsck", "-user", "nobody"},
+	} {
+		if err := runCommand(args, testUser); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
@@ -5,6 +5,8 @@ go 1.21
 require (
 	github.com/boltdb/bolt v1.3.1
 	github.com/joho/godotenv v1.5.1
+	github.com/mattn/go-sqlite3 v1.14.22
+	golang.org/x/crypto v0.17.0
 )
 
 require golang.org/x/sys v0.15.0 // indirect
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// maxImportSize bounds the size of an uploaded file
+const maxImportSize = 10 << 20
+
+// parseImport reads the days of a file in the export format, and for JSON the sets
+// logged on them
+func parseImport(r io.Reader, format string) ([]tracker.DayData, map[string][]tracker.SetData, error) {
+	switch format {
+	case "json":
+		var export tracker.Export
+		if err := json.NewDecoder(r).Decode(&export); err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		return export.Days, export.Sets, nil
+	case "csv":
+		days, err := parseImportCSV(r)
+		return days, nil, err
+	default:
+		return nil, nil, fmt.Errorf("format must be one of %v", exportFormats)
+	}
+}
+
+// parseImportCSV reads days from CSV with a header row. The date and count columns
+// are required, reps, done and status may be left out, in any order.
+func parseImportCSV(r io.Reader) ([]tracker.DayData, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "count"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV is missing the %s column", name)
+		}
+	}
+
+	var days []tracker.DayData
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		dayData := tracker.DayData{Date: field("date")}
+		if dayData.Count, err = strconv.Atoi(field("count")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid count %q", line, field("count"))
+		}
+		if value := field("reps"); value != "" {
+			if dayData.Reps, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("line %d: invalid reps %q", line, value)
+			}
+		}
+		if value := field("done"); value != "" {
+			if dayData.Done, err = strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("line %d: invalid done %q", line, value)
+			}
+		}
+		dayData.Status = field("status")
+		days = append(days, dayData)
+	}
+	return days, nil
+}
+
+// handleImport imports days posted in the export format. The format is taken from
+// ?format= or the Content-Type, ?conflict= picks skip, overwrite or merge and
+// ?dryRun=true reports the changes without saving them.
+func handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = "csv"
+		}
+	}
+	if !validExportFormat(format) {
+		http.Error(w, fmt.Sprintf("format must be one of %v", exportFormats), http.StatusBadRequest)
+		return
+	}
+
+	conflict := query.Get("conflict")
+	if conflict == "" {
+		conflict = "skip"
+	}
+	if !tracker.ValidConflictMode(conflict) {
+		http.Error(w, fmt.Sprintf("conflict must be one of %v", tracker.ConflictModes), http.StatusBadRequest)
+		return
+	}
+
+	dryRun := false
+	if value := query.Get("dryRun"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "dryRun must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	days, sets, err := parseImport(http.MaxBytesReader(w, r.Body, maxImportSize), format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := tracker.ValidateImport(days, sets, requestToday(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := service().Import(requestUser(r), days, sets, conflict, dryRun)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// runImport implements `push_up_tracker import [-user name] [-format json|csv]
+// [-conflict skip|overwrite|merge] [-dry-run] file`
+func runImport(args []string, defaultUser string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	username := flags.String("user", defaultUser, "user to import into")
+	format := flags.String("format", "", "file format, json or csv (default from the file extension)")
+	conflict := flags.String("conflict", "skip", "what to do with days that already exist: skip, overwrite or merge")
+	dryRun := flags.Bool("dry-run", false, "report the changes without saving them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: import [-user name] [-format json|csv] [-conflict skip|overwrite|merge] [-dry-run] file")
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	if !validExportFormat(*format) {
+		return fmt.Errorf("format must be one of %v", exportFormats)
+	}
+	if !tracker.ValidConflictMode(*conflict) {
+		return fmt.Errorf("conflict must be one of %v", tracker.ConflictModes)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	days, sets, err := parseImport(f, *format)
+	if err != nil {
+		return err
+	}
+	result, err := service().Import(*username, days, sets, *conflict, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	verb := "Imported"
+	if result.DryRun {
+		verb = "Would import"
+	}
+	fmt.Printf("%s %d days for %s: %d added, %d overwritten, %d merged, %d skipped\n",
+		verb, len(days), *username, result.Added, result.Overwritten, result.Merged, result.Skipped)
+	fmt.Printf("First day %s, current streak %d, longest streak %d\n",
+		result.FirstDay, result.Streak.Current, result.Streak.Longest)
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	// Columns in another order, with done left out
+	days, sets, err := parseImport(strings.NewReader("count,date,reps\n10,2024-03-08,10\n11, 2024-03-09,\n"), "csv")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(days) != 2 || sets != nil || days[0] != (tracker.DayData{Date: "2024-03-08", Count: 10, Reps: 10}) || days[1].Date != "2024-03-09" {
+		t.Errorf("Unexpected days %+v", days)
+	}
+
+	for _, input := range []string{
+		"date,reps\n2024-03-08,10\n",
+		"date,count\n2024-03-08,ten\n",
+		"date,count,done\n2024-03-08,10,maybe\n",
+	} {
+		if _, _, err := parseImport(strings.NewReader(input), "csv"); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestHandleImport(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	body := `{"days": [{"date": "2024-03-08", "count": 10, "reps": 10, "done": true}]}`
+
+	// Test 1: A dry run reports the changes without saving them
+	req := httptest.NewRequest("POST", "/api/import?dryRun=true", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handleImport(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result tracker.ImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if !result.DryRun || result.Added != 1 || result.Streak.Longest != 1 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		if _, found, _ := testTx(root).Day("2024-03-08"); found {
+			t.Errorf("Expected dry run not to save the day")
+		}
+		return nil
+	})
+
+	// Test 2: CSV is picked from the Content-Type
+	req = httptest.NewRequest("POST", "/api/import", strings.NewReader("date,count,reps,done\n2024-03-08,10,10,true\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+
+	handleImport(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		if day, found, _ := testTx(root).Day("2024-03-08"); !found || !day.Done {
+			t.Errorf("Expected imported day, got %+v", day)
+		}
+		return nil
+	})
+
+	// Test 3: Invalid requests
+	for _, tt := range []struct{ query, body string }{
+		{"conflict=replace", body},
+		{"format=xml", body},
+		{"dryRun=maybe", body},
+		{"", `{"days": [{"date": "08/03/2024", "count": 10}]}`},
+		{"", `not json`},
+	} {
+		req = httptest.NewRequest("POST", "/api/import?"+tt.query, strings.NewReader(tt.body))
+		w = httptest.NewRecorder()
+
+		handleImport(w, asTestUser(req))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q %s, got %d", tt.query, tt.body, w.Code)
+		}
+	}
+}
+
+func TestRunImport(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	path := filepath.Join(t.TempDir(), "history.csv")
+	os.WriteFile(path, []byte("date,count,reps,done\n2024-03-08,10,10,true\n"), 0600)
+
+	if err := runCommand([]string{"import", "-conflict", "overwrite", path}, testUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		if _, found, _ := testTx(root).Day("2024-03-08"); !found {
+			t.Errorf("Expected imported day")
+		}
+		return nil
+	})
+
+	for _, args := range [][]string{
+		{"import"},
+		{"import", "-conflict", "replace", path},
+		{"import", filepath.Join(t.TempDir(), "missing.csv")},
+	} {
+		if err := runCommand(args, testUser); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// LeaderboardEntry is one user's standing over the leaderboard window
+type LeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	Username string `json:"username"`
+	tracker.Summary
+}
+
+const (
+	defaultLeaderboardDays = 30
+	maxLeaderboardDays     = 365
+)
+
+// leaderboardSorts lists the accepted values of the sort parameter
+var leaderboardSorts = []string{"streak", "longest", "reps", "rate"}
+
+// buildLeaderboard ranks every user who hasn't opted out
+func buildLeaderboard(users []User, from, today, sortBy string) ([]LeaderboardEntry, error) {
+	entries := []LeaderboardEntry{}
+	for _, user := range users {
+		if user.LeaderboardOptOut {
+			continue
+		}
+		summary, err := service().Summary(user.Username, from, today)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: %v", user.Username, err)
+		}
+		entries = append(entries, LeaderboardEntry{Username: user.Username, Summary: summary})
+	}
+
+	key := func(e LeaderboardEntry) []float64 {
+		switch sortBy {
+		case "longest":
+			return []float64{float64(e.LongestStreak), float64(e.CurrentStreak), float64(e.TotalReps)}
+		case "reps":
+			return []float64{float64(e.TotalReps), e.CompletionRate, float64(e.CurrentStreak)}
+		case "rate":
+			return []float64{e.CompletionRate, float64(e.TotalReps), float64(e.CurrentStreak)}
+		default:
+			return []float64{float64(e.CurrentStreak), float64(e.LongestStreak), float64(e.TotalReps)}
+		}
+	}
+	// Users are listed by name, a stable sort keeps that order for ties
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := key(entries[i]), key(entries[j])
+		for n := range a {
+			if a[n] != b[n] {
+				return a[n] > b[n]
+			}
+		}
+		return false
+	})
+
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries, nil
+}
+
+// handleLeaderboard ranks the users over the last ?days= days, or changes whether
+// the requesting user appears on it with PUT {"optOut": true}
+func handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		var req struct {
+			OptOut bool `json:"optOut"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		err := db.Update(func(tx *bolt.Tx) error {
+			user, found, err := getUser(tx, requestUser(r))
+			if err != nil {
+				return err
+			}
+			if !found {
+				return errUserNotFound
+			}
+			user.LeaderboardOptOut = req.OptOut
+			return putUser(tx, user)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := defaultLeaderboardDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxLeaderboardDays {
+			http.Error(w, fmt.Sprintf("days must be between 1 and %d", maxLeaderboardDays), http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = "streak"
+	}
+	valid := false
+	for _, s := range leaderboardSorts {
+		if s == sortBy {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		http.Error(w, fmt.Sprintf("sort must be one of %v", leaderboardSorts), http.StatusBadRequest)
+		return
+	}
+
+	// The window ends on the current date of the user looking at the leaderboard
+	today := requestToday(r)
+	todayTime, _ := time.Parse("2006-01-02", today)
+	from := todayTime.AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+
+	var users []User
+	var optOut bool
+	err := db.View(func(tx *bolt.Tx) error {
+		user, _, err := getUser(tx, requestUser(r))
+		if err != nil {
+			return err
+		}
+		optOut = user.LeaderboardOptOut
+
+		users, err = listUsers(tx)
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	entries, err := buildLeaderboard(users, from, today, sortBy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Days    int                `json:"days"`
+		From    string             `json:"from"`
+		To      string             `json:"to"`
+		Sort    string             `json:"sort"`
+		OptOut  bool               `json:"optOut"`
+		Entries []LeaderboardEntry `json:"entries"`
+	}{
+		Days:    days,
+		From:    from,
+		To:      today,
+		Sort:    sortBy,
+		OptOut:  optOut,
+		Entries: entries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
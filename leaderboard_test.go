@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleLeaderboard(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	today := time.Now().Format("2006-01-02")
+
+	_, err := addUser("alice", "secret", false)
+	if err == nil {
+		_, _, err = service().AddSet("alice", today, 25, "")
+	}
+	if err != nil {
+		t.Fatalf("Failed to add test data: %v", err)
+	}
+
+	var response struct {
+		Days    int                `json:"days"`
+		OptOut  bool               `json:"optOut"`
+		Entries []LeaderboardEntry `json:"entries"`
+	}
+
+	// Test 1: Alice completed today and leads on streak
+	req := httptest.NewRequest("GET", "/api/leaderboard?days=7", nil)
+	w := httptest.NewRecorder()
+
+	handleLeaderboard(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Days != 7 || len(response.Entries) != 2 {
+		t.Fatalf("Expected 2 entries over 7 days, got %+v", response)
+	}
+	first := response.Entries[0]
+	if first.Username != "alice" || first.Rank != 1 || first.CurrentStreak != 1 || first.TotalReps != 25 {
+		t.Errorf("Expected alice to lead, got %+v", first)
+	}
+
+	// Test 2: Opting out removes the user from the board
+	req = httptest.NewRequest("PUT", "/api/leaderboard", strings.NewReader(`{"optOut": true}`))
+	w = httptest.NewRecorder()
+
+	handleLeaderboard(w, withUser(req, "alice"))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	response.Entries = nil
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !response.OptOut || len(response.Entries) != 1 || response.Entries[0].Username != testUser {
+		t.Errorf("Expected only %s after alice opted out, got %+v", testUser, response)
+	}
+
+	// Test 3: Invalid parameters
+	for _, query := range []string{"days=0", "days=1000", "sort=name"} {
+		req = httptest.NewRequest("GET", "/api/leaderboard?"+query, nil)
+		w = httptest.NewRecorder()
+
+		handleLeaderboard(w, asTestUser(req))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+}
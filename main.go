@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
@@ -14,25 +15,27 @@ import (
 
 	"github.com/boltdb/bolt"
 	"github.com/joho/godotenv"
+	"github.com/rhamdeew/push_up_tracker/tracker"
 )
 
 var (
-	db          *bolt.DB
-	tmpl        *template.Template
-	todayCount  int
-	todayTarget int
+	db   *bolt.DB
+	tmpl *template.Template
 )
 
-type DayData struct {
-	Date  string `json:"date"`
-	Count int    `json:"count"`
-	Done  bool   `json:"done"`
+// service returns the tracker on the open store, with the defaults from the
+// environment and the clock all date logic reads
+func service() *tracker.Service {
+	return &tracker.Service{
+		Store:    trackerStore(),
+		Defaults: envSettings,
+		Clock:    clock,
+	}
 }
 
-type StreakData struct {
-	Current  int    `json:"current"`
-	Longest  int    `json:"longest"`
-	LastDate string `json:"lastDate"`
+// requestToday returns the current date for the user making the request
+func requestToday(r *http.Request) string {
+	return service().Date(requestUser(r))
 }
 
 func main() {
@@ -57,8 +60,29 @@ func main() {
 		password = "admin"
 	}
 
-	// Initialize BoltDB
+	// Load target defaults, these can be overridden later with PUT /api/settings
 	var err error
+	envSettings, err = settingsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid target settings: %v", err)
+	}
+
+	err = authFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid auth settings: %v", err)
+	}
+
+	err = clockFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid FAKE_NOW: %v", err)
+	}
+
+	err = backupFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid backup settings: %v", err)
+	}
+
+	// Initialize BoltDB
 	dbPath := filepath.Join(".", "pushups.db")
 
 	// Ensure working directory is the installation directory
@@ -68,44 +92,149 @@ func main() {
 	}
 	dbPath = filepath.Join(workingDir, "pushups.db")
 
-	db, err = bolt.Open(dbPath, 0600, nil)
+	command := ""
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		command = os.Args[1]
+	}
+
+	// Commands that only read open the database read-only, unless it has yet to
+	// be created. Fail instead of waiting forever when another process, such as
+	// the running server, holds the database: commands then go through its API.
+	readOnly := readOnlyCommands[command]
+	if _, err := os.Stat(dbPath); err != nil {
+		readOnly = false
+	}
+	db, err = bolt.Open(dbPath, 0600, &bolt.Options{Timeout: time.Second, ReadOnly: readOnly})
+	if err == bolt.ErrTimeout && command != "" {
+		err = runRemoteCommand(os.Args[1:], username, port, dbPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Open %s: %v", dbPath, err)
 	}
 	defer db.Close()
 
-	// Create buckets
-	err = db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte("Days"))
+	// Create buckets, each user's own buckets are nested under UserData. A
+	// read-only command doesn't need the session secret.
+	if !readOnly {
+		err = db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists([]byte("Users"))
+			if err != nil {
+				return fmt.Errorf("create bucket: %s", err)
+			}
+			_, err = tx.CreateBucketIfNotExists([]byte("UserData"))
+			if err != nil {
+				return fmt.Errorf("create bucket: %s", err)
+			}
+			_, err = tx.CreateBucketIfNotExists([]byte("Sessions"))
+			if err != nil {
+				return fmt.Errorf("create bucket: %s", err)
+			}
+			_, err = tx.CreateBucketIfNotExists([]byte("Server"))
+			if err != nil {
+				return fmt.Errorf("create bucket: %s", err)
+			}
+			_, err = tx.CreateBucketIfNotExists([]byte("Tokens"))
+			if err != nil {
+				return fmt.Errorf("create bucket: %s", err)
+			}
+			sessionSecret, err = loadSessionSecret(tx)
+			if err != nil {
+				return fmt.Errorf("session secret: %s", err)
+			}
+			return bootstrapUsers(tx, username, password)
+		})
 		if err != nil {
-			return fmt.Errorf("create bucket: %s", err)
+			log.Fatal(err)
 		}
-		_, err = tx.CreateBucketIfNotExists([]byte("Streak"))
+	}
+
+	// The tracker data is kept in pushups.db too, unless DB_DRIVER or DB_PATH
+	// point elsewhere
+	driver, storePath, err := storageFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid storage settings: %v", err)
+	}
+	dataStore, closeDataStore, err = openStore(driver, storePath)
+	if err != nil {
+		log.Fatalf("Open %s: %v", storePath, err)
+	}
+	defer closeDataStore()
+
+	if !readOnly {
+		err = syncUserData()
 		if err != nil {
-			return fmt.Errorf("create bucket: %s", err)
+			log.Fatalf("Create user data: %v", err)
+		}
+	}
+
+	// Bring old data up to the current schema. The migrate command reports and
+	// applies the migrations itself, and restore replaces the data anyway.
+	if !readOnly && command != "migrate" && command != "restore" {
+		migrations, backup, err := migrateSchema(false)
+		if backup != "" {
+			log.Printf("Backed up the data to %s before migrating it", backup)
 		}
-		_, err = tx.CreateBucketIfNotExists([]byte("Config"))
 		if err != nil {
-			return fmt.Errorf("create bucket: %s", err)
+			log.Fatalf("Migrate: %v", err)
+		}
+		for _, m := range migrations {
+			if len(m.Applied) > 0 {
+				log.Printf("Migrated the data of %s from schema version %d to %d", m.Username, m.From, m.To)
+			}
 		}
-		return nil
-	})
-	if err != nil {
-		log.Fatal(err)
 	}
 
-	// Initialize today's count
+	// Run a command instead of the server when one is given
+	if command != "" {
+		err = runCommand(os.Args[1:], username)
+		closeDataStore()
+		db.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Initialize today's records
 	initializeTodayCount()
 
+	if backupInterval > 0 {
+		scheduleBackups()
+	}
+
 	// Load templates
 	tmpl = template.Must(template.ParseGlob("templates/*.html"))
 
 	// Setup routes
-	http.HandleFunc("/", basicAuth(handleIndex, username, password))
-	http.HandleFunc("/api/today", basicAuth(handleToday, username, password))
-	http.HandleFunc("/api/today/complete", basicAuth(handleTodayComplete, username, password))
-	http.HandleFunc("/api/calendar", basicAuth(handleCalendar, username, password))
-	http.HandleFunc("/api/streak", basicAuth(handleStreak, username, password))
+	http.HandleFunc("/", requireAuth(handleIndex))
+	http.HandleFunc("/login", handleLogin)
+	http.HandleFunc("/logout", requireAuth(handleLogout))
+	http.HandleFunc("/api/today", requireAuth(handleToday))
+	http.HandleFunc("/api/today/complete", requireAuth(handleTodayComplete))
+	http.HandleFunc("/api/today/reps", requireAuth(handleTodayReps))
+	http.HandleFunc("/api/calendar", requireAuth(handleCalendar))
+	http.HandleFunc("/api/streak", requireAuth(handleStreak))
+	http.HandleFunc("/api/streak/freeze", requireAuth(handleStreakFreeze))
+	http.HandleFunc("/api/leaderboard", requireAuth(handleLeaderboard))
+	http.HandleFunc("/api/stats", requireAuth(handleStats))
+	http.HandleFunc("/api/export", requireAuth(handleExport))
+	http.HandleFunc("/api/import", requireAuth(handleImport))
+	http.HandleFunc("/api/days/", requireAuth(handleDays))
+	http.HandleFunc("/api/admin/progression", requireAuth(handleProgression))
+	http.HandleFunc("/api/admin/users", requireAuth(requireAdmin(handleUsers)))
+	http.HandleFunc("/api/admin/users/", requireAuth(requireAdmin(handleUsers)))
+	http.HandleFunc("/api/admin/backup", requireAuth(requireAdmin(handleBackup)))
+	http.HandleFunc("/api/admin/fsck", requireAuth(requireAdmin(handleFsck)))
+	http.HandleFunc("/api/settings", requireAuth(handleSettings))
+	http.HandleFunc("/api/tokens", requireAuth(handleTokens))
+	http.HandleFunc("/api/tokens/", requireAuth(handleTokens))
+	if _, ok := clock.(*tracker.OffsetClock); ok {
+		http.HandleFunc("/api/admin/clock", requireAuth(requireAdmin(handleAdminClock)))
+	}
 	http.HandleFunc("/static/", func(w http.ResponseWriter, r *http.Request) {
 		// Security: Validate path to prevent directory traversal
 		path := r.URL.Path[1:]
@@ -125,477 +254,138 @@ func main() {
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
 
+// initializeTodayCount creates today's record for every user
 func initializeTodayCount() {
-	today := time.Now().Format("2006-01-02")
-
-	err := db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Days"))
-		data := b.Get([]byte(today))
-
-		if data == nil {
-			// Check if this is the first day (database initialization)
-			firstDay, err := getFirstDay(tx)
-			if err != nil {
-				return err
-			}
-
-			if firstDay == "" {
-				// Database is empty, this is initialization day
-				firstDay = today
-				err = setFirstDay(tx, firstDay)
-				if err != nil {
-					return err
-				}
-				todayTarget = 10
-			} else {
-				// Calculate target based on yesterday's completion
-				todayTime, _ := time.Parse("2006-01-02", today)
-				yesterday := todayTime.AddDate(0, 0, -1).Format("2006-01-02")
-
-				yesterdayData := b.Get([]byte(yesterday))
-				if yesterdayData != nil {
-					var yesterdayDayData DayData
-					err := json.Unmarshal(yesterdayData, &yesterdayDayData)
-					if err != nil {
-						return err
-					}
-
-					if yesterdayDayData.Done {
-						// Yesterday was completed, apply progression
-						todayTarget = calculateNextTarget(yesterdayDayData.Count, tx)
-					} else {
-						// Yesterday was skipped, keep same target
-						todayTarget = yesterdayDayData.Count
-					}
-				} else {
-					// No yesterday data, start with 10
-					todayTarget = 10
-				}
-			}
-
-			dayData := DayData{
-				Date:  today,
-				Count: todayTarget,
-				Done:  false,
-			}
-
-			jsonData, err := json.Marshal(dayData)
-			if err != nil {
-				return err
-			}
-
-			err = b.Put([]byte(today), jsonData)
-			if err != nil {
-				return err
-			}
-
-			todayCount = todayTarget
-		} else {
-			var dayData DayData
-			err := json.Unmarshal(data, &dayData)
-			if err != nil {
-				return err
-			}
-			todayCount = dayData.Count
-		}
-
-		return nil
+	var users []User
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		users, err = listUsers(tx)
+		return err
 	})
-
 	if err != nil {
 		log.Printf("Error initializing today count: %v", err)
-	}
-}
-
-// calculateTarget calculates the target count based on progression rules (legacy)
-func calculateTarget(startCount, daysSince int) int {
-	target := startCount
-
-	for i := 0; i < daysSince; i++ {
-		if target < 50 {
-			// Increase by 2 when count < 50
-			target += 2
-		} else if target >= 50 && target < 100 {
-			// Increase by 1 when count >= 50 and < 100
-			target += 1
-		} else if target >= 100 && target < 200 {
-			// Increase by 1 every 2 days when count >= 100 and < 200
-			if i%2 == 0 { // Only increase on even days (every other day)
-				target += 1
-			}
-		}
-		// When target reaches 200, stop increasing
-		if target >= 200 {
-			target = 200
-			break
-		}
+		return
 	}
 
-	return target
-}
-
-// calculateNextTarget calculates the next target based on current count and completion
-func calculateNextTarget(currentCount int, tx *bolt.Tx) int {
-	if currentCount >= 200 {
-		return 200
-	}
-
-	if currentCount < 50 {
-		// Increase by 2 when count < 50
-		return currentCount + 2
-	} else if currentCount >= 50 && currentCount < 100 {
-		// Increase by 1 when count >= 50 and < 100
-		return currentCount + 1
-	} else if currentCount >= 100 && currentCount < 200 {
-		// Increase by 1 every 2 completed days when count >= 100 and < 200
-		daysAtLevel := getDaysAtCurrentLevel(tx)
-		if daysAtLevel >= 1 {
-			// We've been at this level for 2 days (yesterday + day before), time to increase
-			setDaysAtCurrentLevel(tx, 0)
-			newTarget := currentCount + 1
-			if newTarget > 200 {
-				return 200
-			}
-			return newTarget
-		} else {
-			// First day at this level, don't increase yet
-			setDaysAtCurrentLevel(tx, daysAtLevel+1)
-			return currentCount
+	for _, user := range users {
+		if _, err := service().Today(user.Username); err != nil {
+			log.Printf("Error initializing today count for %s: %v", user.Username, err)
 		}
 	}
-
-	return currentCount
-}
-
-// getDaysAtCurrentLevel retrieves the counter for days at current level (for 100-200 range)
-func getDaysAtCurrentLevel(tx *bolt.Tx) int {
-	b := tx.Bucket([]byte("Config"))
-	data := b.Get([]byte("daysAtLevel"))
-	if data == nil {
-		return 0
-	}
-	days, _ := strconv.Atoi(string(data))
-	return days
-}
-
-// setDaysAtCurrentLevel sets the counter for days at current level
-func setDaysAtCurrentLevel(tx *bolt.Tx, days int) error {
-	b := tx.Bucket([]byte("Config"))
-	return b.Put([]byte("daysAtLevel"), []byte(strconv.Itoa(days)))
 }
 
-func basicAuth(next http.HandlerFunc, username, password string) http.HandlerFunc {
+// basicAuth checks the credentials against the user store and attaches the user to the request
+func basicAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user, pass, ok := r.BasicAuth()
-		if !ok || user != username || pass != password {
+		if ok {
+			db.View(func(tx *bolt.Tx) error {
+				_, ok = authenticate(tx, user, pass)
+				return nil
+			})
+		}
+		if !ok {
 			w.Header().Set("WWW-Authenticate", `Basic realm="Push Up Tracker"`)
 			http.Error(w, "Unauthorized.", http.StatusUnauthorized)
 			return
 		}
-		next(w, r)
+		next(w, withUser(r, user))
 	}
 }
 
 func handleIndex(w http.ResponseWriter, r *http.Request) {
-	err := tmpl.ExecuteTemplate(w, "index.html", nil)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// The page sends the CSRF token with every change made through the session
+	session, _ := requestSession(r)
+	data := struct {
+		Username  string
+		CSRFToken string
+	}{
+		Username:  requestUser(r),
+		CSRFToken: session.CSRFToken,
 	}
-}
 
-func getFirstDay(tx *bolt.Tx) (string, error) {
-	b := tx.Bucket([]byte("Config"))
-	data := b.Get([]byte("firstDay"))
-	if data == nil {
-		return "", nil
+	err := tmpl.ExecuteTemplate(w, "index.html", data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
-	return string(data), nil
-}
-
-func setFirstDay(tx *bolt.Tx, firstDay string) error {
-	b := tx.Bucket([]byte("Config"))
-	return b.Put([]byte("firstDay"), []byte(firstDay))
 }
 
 func handleToday(w http.ResponseWriter, r *http.Request) {
-	today := time.Now().Format("2006-01-02")
-
-	var dayData DayData
-	err := db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Days"))
-		data := b.Get([]byte(today))
-
-		if data == nil {
-			// Today's data doesn't exist, create it
-			firstDay, err := getFirstDay(tx)
-			if err != nil {
-				return err
-			}
-
-			var targetCount int
-			if firstDay == "" {
-				// Database is empty, this is initialization day
-				firstDay = today
-				err = setFirstDay(tx, firstDay)
-				if err != nil {
-					return err
-				}
-				targetCount = 10
-			} else {
-				// Calculate target based on yesterday's completion
-				todayTime, _ := time.Parse("2006-01-02", today)
-				yesterday := todayTime.AddDate(0, 0, -1).Format("2006-01-02")
-
-				yesterdayData := b.Get([]byte(yesterday))
-				if yesterdayData != nil {
-					var yesterdayDayData DayData
-					err := json.Unmarshal(yesterdayData, &yesterdayDayData)
-					if err != nil {
-						return err
-					}
-
-					if yesterdayDayData.Done {
-						// Yesterday was completed, apply progression
-						targetCount = calculateNextTarget(yesterdayDayData.Count, tx)
-					} else {
-						// Yesterday was skipped, keep same target
-						targetCount = yesterdayDayData.Count
-					}
-				} else {
-					// No yesterday data, start with 10
-					targetCount = 10
-				}
-			}
-
-			dayData = DayData{
-				Date:  today,
-				Count: targetCount,
-				Done:  false,
-			}
-
-			jsonData, err := json.Marshal(dayData)
-			if err != nil {
-				return err
-			}
-
-			err = b.Put([]byte(today), jsonData)
-			if err != nil {
-				return err
-			}
-		} else {
-			err := json.Unmarshal(data, &dayData)
-			if err != nil {
-				return err
-			}
-		}
-
-		return nil
-	})
-
+	day, err := service().Today(requestUser(r))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(dayData)
+	json.NewEncoder(w).Encode(day)
 }
 
+// handleTodayComplete completes today with POST and undoes that with DELETE
 func handleTodayComplete(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
+	var day tracker.Day
+	var err error
+	switch r.Method {
+	case "POST":
+		day, err = service().Complete(requestUser(r))
+	case "DELETE":
+		day, err = service().Undo(requestUser(r))
+	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	today := time.Now().Format("2006-01-02")
-
-	err := db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Days"))
-		data := b.Get([]byte(today))
-
-		var dayData DayData
-		if data != nil {
-			err := json.Unmarshal(data, &dayData)
-			if err != nil {
-				return err
-			}
-		} else {
-			dayData = DayData{
-				Date:  today,
-				Count: todayCount,
-				Done:  false,
-			}
-		}
-
-		dayData.Done = true
-
-		jsonData, err := json.Marshal(dayData)
-		if err != nil {
-			return err
-		}
-
-		err = b.Put([]byte(today), jsonData)
-		if err != nil {
-			return err
-		}
-
-		// Update streak
-		updateStreak(tx, today)
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(dayData)
-		return nil
-	})
-
+	if errors.Is(err, tracker.ErrCompletedBySets) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-}
-
-func updateStreak(tx *bolt.Tx, today string) {
-	b := tx.Bucket([]byte("Streak"))
-	data := b.Get([]byte("current"))
-
-	var streak StreakData
-	if data != nil {
-		json.Unmarshal(data, &streak)
-	}
-
-	todayTime, _ := time.Parse("2006-01-02", today)
-	yesterday := todayTime.AddDate(0, 0, -1).Format("2006-01-02")
-
-	// Check if yesterday was completed
-	daysBucket := tx.Bucket([]byte("Days"))
-	yesterdayData := daysBucket.Get([]byte(yesterday))
 
-	if yesterdayData != nil {
-		var yesterdayDayData DayData
-		json.Unmarshal(yesterdayData, &yesterdayDayData)
-
-		if yesterdayDayData.Done {
-			streak.Current++
-		} else {
-			streak.Current = 1
-		}
-	} else {
-		streak.Current = 1
-	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(day)
+}
 
-	if streak.Current > streak.Longest {
-		streak.Longest = streak.Current
+func handleTodayReps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	streak.LastDate = today
-
-	jsonData, _ := json.Marshal(streak)
-	b.Put([]byte("current"), jsonData)
+	// Adding reps to today is logging a set for today's date
+	handleDaySets(w, r, requestToday(r))
 }
 
 func handleCalendar(w http.ResponseWriter, r *http.Request) {
-	year := r.URL.Query().Get("year")
-	if year == "" {
-		year = strconv.Itoa(time.Now().Year())
-	}
-
-	var firstRecordDate string
-	err := db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Days"))
-
-		cursor := b.Cursor()
-		k, _ := cursor.First()
-		if k != nil {
-			firstRecordDate = string(k)
-		}
-		return nil
-	})
-
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	var startMonth, startYear int
-	if firstRecordDate != "" {
-		firstDate, err := time.Parse("2006-01-02", firstRecordDate)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	year := 0
+	if value := r.URL.Query().Get("year"); value != "" {
+		var err error
+		if year, err = strconv.Atoi(value); err != nil || year < 1 || year > 9999 {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
 			return
 		}
-		startMonth = int(firstDate.Month() - 1) // Go months are 1-based, JS is 0-based
-		startYear = firstDate.Year()
-	} else {
-		// No records, start from current month
-		now := time.Now()
-		startMonth = int(now.Month() - 1) // Convert to 0-based
-		startYear = now.Year()
-	}
-
-	calendar := make(map[string]DayData)
-
-	err = db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Days"))
-
-		cursor := b.Cursor()
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			dateStr := string(k)
-			if len(dateStr) >= 4 && dateStr[:4] == year {
-				var dayData DayData
-				err := json.Unmarshal(v, &dayData)
-				if err != nil {
-					continue
-				}
-				calendar[dateStr] = dayData
-			}
-		}
-		return nil
-	})
+	}
 
+	calendar, err := service().Calendar(requestUser(r), year)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	response := struct {
-		Year       int                `json:"year"`
-		StartMonth int                `json:"startMonth"`
-		StartYear  int                `json:"startYear"`
-		Days       map[string]DayData `json:"days"`
-	}{
-		Year:       time.Now().Year(),
-		StartMonth: startMonth,
-		StartYear:  startYear,
-		Days:       calendar,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(calendar)
 }
 
+// handleStreak derives the streak from the history, so it is never stale after missed days
 func handleStreak(w http.ResponseWriter, r *http.Request) {
-	err := db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Streak"))
-		data := b.Get([]byte("current"))
-
-		var streak StreakData
-		if data != nil {
-			err := json.Unmarshal(data, &streak)
-			if err != nil {
-				return err
-			}
-		} else {
-			streak = StreakData{
-				Current:  0,
-				Longest:  0,
-				LastDate: "",
-			}
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(streak)
-		return nil
-	})
-
+	streak, err := service().Streak(requestUser(r))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(streak)
 }
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
 )
 
 // Mock database for testing
@@ -24,21 +25,33 @@ func setupTestDB(t *testing.T) *bolt.DB {
 		t.Fatalf("Failed to create test DB: %v", err)
 	}
 
-	// Create test data
+	// Create the test user, whose buckets the handlers work on
 	err = db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte("Days"))
+		_, err := tx.CreateBucketIfNotExists([]byte("Users"))
 		if err != nil {
 			return err
 		}
-		_, err = tx.CreateBucketIfNotExists([]byte("Streak"))
+		_, err = tx.CreateBucketIfNotExists([]byte("UserData"))
 		if err != nil {
 			return err
 		}
-		_, err = tx.CreateBucketIfNotExists([]byte("Config"))
+		_, err = tx.CreateBucketIfNotExists([]byte("Sessions"))
 		if err != nil {
 			return err
 		}
-		return nil
+		_, err = tx.CreateBucketIfNotExists([]byte("Server"))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte("Tokens"))
+		if err != nil {
+			return err
+		}
+		_, err = createUser(tx, testUser, "admin", true)
+		if err != nil {
+			return err
+		}
+		return tracker.CreateBoltUser(tx, testUser)
 	})
 	if err != nil {
 		t.Fatalf("Failed to setup test DB: %v", err)
@@ -53,6 +66,25 @@ func cleanupTestDB(t *testing.T, db *bolt.DB) {
 	os.Remove("test.db")
 }
 
+// testUser is the account the tests run as
+const testUser = "admin"
+
+// testScope returns the test user's buckets
+func testScope(tx *bolt.Tx) *bolt.Bucket {
+	return tx.Bucket([]byte("UserData")).Bucket([]byte(testUser))
+}
+
+// testTx returns the test user's data within root
+func testTx(root *bolt.Tx) tracker.Tx {
+	tx, _ := tracker.BoltTx(root, testUser)
+	return tx
+}
+
+// asTestUser attaches the test user to a request, as basicAuth would
+func asTestUser(r *http.Request) *http.Request {
+	return withUser(r, testUser)
+}
+
 func TestBasicAuth(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -107,43 +139,30 @@ func TestBasicAuth(t *testing.T) {
 	}
 }
 
-func TestSetAndGetFirstDay(t *testing.T) {
+func TestBasicAuthHandler(t *testing.T) {
 	testDB := setupTestDB(t)
 	defer cleanupTestDB(t, testDB)
 
-	// Test setting first day
-	err := testDB.Update(func(tx *bolt.Tx) error {
-		return setFirstDay(tx, "2024-01-01")
-	})
-	if err != nil {
-		t.Errorf("Failed to set first day: %v", err)
-	}
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
 
-	// Test getting first day
-	var firstDay string
-	err = testDB.View(func(tx *bolt.Tx) error {
-		fd, err := getFirstDay(tx)
-		if err != nil {
-			return err
-		}
-		firstDay = fd
-		return nil
+	err := testDB.Update(func(tx *bolt.Tx) error {
+		_, err := createUser(tx, "testuser", "testpass", false)
+		return err
 	})
 	if err != nil {
-		t.Errorf("Failed to get first day: %v", err)
-	}
-
-	if firstDay != "2024-01-01" {
-		t.Errorf("Expected first day '2024-01-01', got '%s'", firstDay)
+		t.Fatalf("Failed to create user: %v", err)
 	}
-}
 
-func TestBasicAuthHandler(t *testing.T) {
 	// Test basicAuth middleware
+	var gotUser string
 	handler := basicAuth(func(w http.ResponseWriter, r *http.Request) {
+		gotUser = requestUser(r)
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("success"))
-	}, "testuser", "testpass")
+	})
 
 	// Test with correct credentials
 	req := httptest.NewRequest("GET", "/", nil)
@@ -155,6 +174,9 @@ func TestBasicAuthHandler(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
+	if gotUser != "testuser" {
+		t.Errorf("Expected request user testuser, got %q", gotUser)
+	}
 
 	// Test with incorrect credentials
 	req = httptest.NewRequest("GET", "/", nil)
@@ -166,148 +188,16 @@ func TestBasicAuthHandler(t *testing.T) {
 	if w.Code != http.StatusUnauthorized {
 		t.Errorf("Expected status 401, got %d", w.Code)
 	}
-}
-
-func TestProgressiveLoad(t *testing.T) {
-	testDB := setupTestDB(t)
-	defer cleanupTestDB(t, testDB)
-
-	// Set first day to 2024-01-01
-	err := testDB.Update(func(tx *bolt.Tx) error {
-		return setFirstDay(tx, "2024-01-01")
-	})
-	if err != nil {
-		t.Fatalf("Failed to set first day: %v", err)
-	}
-
-	// Test different scenarios
-	tests := []struct {
-		name          string
-		targetDate    string
-		expectedCount int
-	}{
-		{"Day 1", "2024-01-01", 10},    // Start at 10
-		{"Day 2", "2024-01-02", 12},    // +2 (10+2)
-		{"Day 10", "2024-01-10", 28},   // +2 each day for 9 days (10+9*2)
-		{"Day 25", "2024-01-25", 54},   // Reached 50, now +1 per day
-		{"Day 65", "2024-03-05", 94},   // Almost at 100
-		{"Day 75", "2024-03-15", 102},  // After 100, +1 every 2 days
-		{"Day 269", "2024-09-26", 200}, // Reached maximum of 200
-		{"Day 365", "2024-12-31", 200}, // Stay at 200 permanently
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			var firstDay string
-			testDB.View(func(tx *bolt.Tx) error {
-				fd, err := getFirstDay(tx)
-				if err != nil {
-					return err
-				}
-				firstDay = fd
-				return nil
-			})
-
-			firstTime, _ := time.Parse("2006-01-02", firstDay)
-			targetTime, _ := time.Parse("2006-01-02", tt.targetDate)
-
-			daysSince := int(targetTime.Sub(firstTime).Hours() / 24)
-			expected := calculateTarget(10, daysSince)
-
-			if expected != tt.expectedCount {
-				t.Errorf("Expected count %d, got %d", tt.expectedCount, expected)
-			}
-		})
-	}
-}
-
-func TestDayDataOperations(t *testing.T) {
-	testDB := setupTestDB(t)
-	defer cleanupTestDB(t, testDB)
-
-	// Test storing and retrieving day data
-	dayData := DayData{
-		Date:  "2024-01-01",
-		Count: 5,
-		Done:  true,
-	}
-
-	jsonData, _ := json.Marshal(dayData)
-
-	// Store data
-	err := testDB.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Days"))
-		return b.Put([]byte(dayData.Date), jsonData)
-	})
-	if err != nil {
-		t.Fatalf("Failed to store day data: %v", err)
-	}
-
-	// Retrieve data
-	var retrieved DayData
-	err = testDB.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Days"))
-		data := b.Get([]byte(dayData.Date))
-		return json.Unmarshal(data, &retrieved)
-	})
-	if err != nil {
-		t.Fatalf("Failed to retrieve day data: %v", err)
-	}
 
-	// Verify data
-	if retrieved.Date != dayData.Date {
-		t.Errorf("Expected date %s, got %s", dayData.Date, retrieved.Date)
-	}
-	if retrieved.Count != dayData.Count {
-		t.Errorf("Expected count %d, got %d", dayData.Count, retrieved.Count)
-	}
-	if retrieved.Done != dayData.Done {
-		t.Errorf("Expected done %v, got %v", dayData.Done, retrieved.Done)
-	}
-}
-
-func TestStreakDataOperations(t *testing.T) {
-	testDB := setupTestDB(t)
-	defer cleanupTestDB(t, testDB)
-
-	// Test storing and retrieving streak data
-	streakData := StreakData{
-		Current:  5,
-		Longest:  10,
-		LastDate: "2024-01-05",
-	}
-
-	jsonData, _ := json.Marshal(streakData)
-
-	// Store data
-	err := testDB.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Streak"))
-		return b.Put([]byte("current"), jsonData)
-	})
-	if err != nil {
-		t.Fatalf("Failed to store streak data: %v", err)
-	}
+	// Test with the wrong password for an existing user
+	req = httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("testuser", "admin")
+	w = httptest.NewRecorder()
 
-	// Retrieve data
-	var retrieved StreakData
-	err = testDB.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Streak"))
-		data := b.Get([]byte("current"))
-		return json.Unmarshal(data, &retrieved)
-	})
-	if err != nil {
-		t.Fatalf("Failed to retrieve streak data: %v", err)
-	}
+	handler(w, req)
 
-	// Verify data
-	if retrieved.Current != streakData.Current {
-		t.Errorf("Expected current %d, got %d", streakData.Current, retrieved.Current)
-	}
-	if retrieved.Longest != streakData.Longest {
-		t.Errorf("Expected longest %d, got %d", streakData.Longest, retrieved.Longest)
-	}
-	if retrieved.LastDate != streakData.LastDate {
-		t.Errorf("Expected last date %s, got %s", streakData.LastDate, retrieved.LastDate)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
 	}
 }
 
@@ -332,7 +222,7 @@ func TestStaticFileSecurity(t *testing.T) {
 	// Test valid static file
 	req := httptest.NewRequest("GET", "/static/style.css", nil)
 	w := httptest.NewRecorder()
-	handler(w, req)
+	handler(w, asTestUser(req))
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected valid static file to return 200, got %d", w.Code)
@@ -341,7 +231,7 @@ func TestStaticFileSecurity(t *testing.T) {
 	// Test directory traversal
 	req = httptest.NewRequest("GET", "/static/../../../etc/passwd", nil)
 	w = httptest.NewRecorder()
-	handler(w, req)
+	handler(w, asTestUser(req))
 
 	if w.Code != http.StatusNotFound {
 		t.Errorf("Expected directory traversal to return 404, got %d", w.Code)
@@ -350,7 +240,7 @@ func TestStaticFileSecurity(t *testing.T) {
 	// Test .go file access
 	req = httptest.NewRequest("GET", "/static/main.go", nil)
 	w = httptest.NewRecorder()
-	handler(w, req)
+	handler(w, asTestUser(req))
 
 	if w.Code != http.StatusNotFound {
 		t.Errorf("Expected .go file access to return 404, got %d", w.Code)
@@ -482,15 +372,11 @@ func TestMainSetup(t *testing.T) {
 	// Save original db
 	origDB := db
 	origTmpl := tmpl
-	origTodayCount := todayCount
-	origTodayTarget := todayTarget
 
 	db = testDB
 	defer func() {
 		db = origDB
 		tmpl = origTmpl
-		todayCount = origTodayCount
-		todayTarget = origTodayTarget
 	}()
 
 	// Test template loading - skip if templates don't exist
@@ -503,18 +389,26 @@ func TestMainSetup(t *testing.T) {
 	// Test initializeTodayCount functionality
 	initializeTodayCount()
 
-	// Verify today's count and target are set
-	if todayCount <= 0 {
-		t.Errorf("Expected todayCount to be greater than 0, got %d", todayCount)
-	}
-	if todayTarget <= 0 {
-		t.Errorf("Expected todayTarget to be greater than 0, got %d", todayTarget)
+	// Verify today's record was created for the user
+	today := time.Now().Format("2006-01-02")
+	var todayData tracker.DayData
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		data := tx.Bucket([]byte("Days")).Get([]byte(today))
+		if data == nil {
+			return fmt.Errorf("No data found for today")
+		}
+		return json.Unmarshal(data, &todayData)
+	})
+	if todayData.Count <= 0 {
+		t.Errorf("Expected today's count to be greater than 0, got %d", todayData.Count)
 	}
 
 	// Test that first day is set correctly in the config
 	var firstDay string
-	testDB.View(func(tx *bolt.Tx) error {
-		fd, err := getFirstDay(tx)
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		fd, err := tx.Config("firstDay")
 		if err != nil {
 			return err
 		}
@@ -527,7 +421,8 @@ func TestMainSetup(t *testing.T) {
 	}
 
 	// Test error handling in initializeTodayCount - set first day with invalid format
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Config"))
 		return b.Put([]byte("firstDay"), []byte("invalid-date"))
 	})
@@ -538,14 +433,9 @@ func TestMainSetup(t *testing.T) {
 	// This should log an error but not panic
 	initializeTodayCount()
 
-	// Check that global variables are still set properly
-	if todayCount <= 0 {
-		t.Errorf("Expected todayCount to be set even with error, got %d", todayCount)
-	}
-
 	// Test error when existing data has invalid JSON format
-	today := time.Now().Format("2006-01-02")
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		return b.Put([]byte(today), []byte("{invalid json}"))
 	})
@@ -573,14 +463,8 @@ func TestInitializeTodayCount(t *testing.T) {
 
 	// Save original db
 	origDB := db
-	origTodayCount := todayCount
-	origTodayTarget := todayTarget
 	db = testDB
-	defer func() {
-		db = origDB
-		todayCount = origTodayCount
-		todayTarget = origTodayTarget
-	}()
+	defer func() { db = origDB }()
 
 	// Test initialization when no data exists for today
 	// Need to test both scenarios: first day and subsequent days
@@ -588,7 +472,8 @@ func TestInitializeTodayCount(t *testing.T) {
 	// Test 1: First day initialization
 	today := time.Now().Format("2006-01-02")
 
-	err := testDB.Update(func(tx *bolt.Tx) error {
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		// Clear any existing config
 		b := tx.Bucket([]byte("Config"))
 		b.Delete([]byte("firstDay"))
@@ -606,8 +491,9 @@ func TestInitializeTodayCount(t *testing.T) {
 
 	// Verify first day was set
 	var firstDay string
-	testDB.View(func(tx *bolt.Tx) error {
-		fd, err := getFirstDay(tx)
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		fd, err := tx.Config("firstDay")
 		if err != nil {
 			return err
 		}
@@ -620,8 +506,9 @@ func TestInitializeTodayCount(t *testing.T) {
 	}
 
 	// Verify day data was created for today
-	var dayData DayData
-	testDB.View(func(tx *bolt.Tx) error {
+	var dayData tracker.DayData
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		data := b.Get([]byte(today))
 		if data == nil {
@@ -644,14 +531,15 @@ func TestInitializeTodayCount(t *testing.T) {
 	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
 
 	// Create data for tomorrow to simulate subsequent day
-	tomorrowDayData := DayData{
+	tomorrowDayData := tracker.DayData{
 		Date:  tomorrow,
 		Count: 12, // Higher count for subsequent day
 		Done:  false,
 	}
 	tomorrowJSON, _ := json.Marshal(tomorrowDayData)
 
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		daysB := tx.Bucket([]byte("Days"))
 		return daysB.Put([]byte(tomorrow), tomorrowJSON)
 	})
@@ -660,9 +548,10 @@ func TestInitializeTodayCount(t *testing.T) {
 	}
 
 	// Test when today's data already exists (should not overwrite)
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		daysB := tx.Bucket([]byte("Days"))
-		existingDayData := DayData{
+		existingDayData := tracker.DayData{
 			Date:  today,
 			Count: 15, // Different count to test it's not overwritten
 			Done:  true,
@@ -678,7 +567,8 @@ func TestInitializeTodayCount(t *testing.T) {
 	initializeTodayCount()
 
 	// Verify existing data was not changed
-	testDB.View(func(tx *bolt.Tx) error {
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		data := b.Get([]byte(today))
 		if data == nil {
@@ -693,11 +583,6 @@ func TestInitializeTodayCount(t *testing.T) {
 	if dayData.Done != true { // Should be the original value
 		t.Errorf("Expected done true to be preserved, got %v", dayData.Done)
 	}
-
-	// Check that todayCount is properly set
-	if todayCount != 15 { // Should match the existing data
-		t.Errorf("Expected todayCount to be 15, got %d", todayCount)
-	}
 }
 
 func TestHandleIndex(t *testing.T) {
@@ -726,7 +611,7 @@ func TestHandleIndex(t *testing.T) {
 	req.SetBasicAuth("admin", "admin") // Use default credentials
 	w := httptest.NewRecorder()
 
-	handleIndex(w, req)
+	handleIndex(w, asTestUser(req))
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", w.Code)
@@ -741,7 +626,7 @@ func TestHandleIndex(t *testing.T) {
 	w = httptest.NewRecorder()
 
 	// This should return an error because the template doesn't exist
-	handleIndex(w, req)
+	handleIndex(w, asTestUser(req))
 
 	if w.Code != http.StatusInternalServerError {
 		t.Errorf("Expected status 500 for missing template, got %d", w.Code)
@@ -761,14 +646,15 @@ func TestHandleToday(t *testing.T) {
 	today := time.Now().Format("2006-01-02")
 
 	// Add test data
-	dayData := DayData{
+	dayData := tracker.DayData{
 		Date:  today,
 		Count: 15,
 		Done:  false,
 	}
 
 	jsonData, _ := json.Marshal(dayData)
-	err := testDB.Update(func(tx *bolt.Tx) error {
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		return b.Put([]byte(today), jsonData)
 	})
@@ -781,14 +667,14 @@ func TestHandleToday(t *testing.T) {
 	req.SetBasicAuth("admin", "admin")
 	w := httptest.NewRecorder()
 
-	handleToday(w, req)
+	handleToday(w, asTestUser(req))
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
 
 	// Verify the response
-	var response DayData
+	var response tracker.DayData
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	if err != nil {
 		t.Errorf("Failed to unmarshal response: %v", err)
@@ -805,7 +691,8 @@ func TestHandleToday(t *testing.T) {
 	}
 
 	// Test auto-creation when no data exists
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		return b.Delete([]byte(today))
 	})
@@ -817,7 +704,7 @@ func TestHandleToday(t *testing.T) {
 	req.SetBasicAuth("admin", "admin")
 	w = httptest.NewRecorder()
 
-	handleToday(w, req)
+	handleToday(w, asTestUser(req))
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200 for auto-created data, got %d", w.Code)
@@ -841,7 +728,8 @@ func TestHandleToday(t *testing.T) {
 
 	// Test with invalid JSON data in database
 	invalidJSON := []byte("{invalid json}")
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		return b.Put([]byte(today), invalidJSON)
 	})
@@ -853,7 +741,7 @@ func TestHandleToday(t *testing.T) {
 	req.SetBasicAuth("admin", "admin")
 	w = httptest.NewRecorder()
 
-	handleToday(w, req)
+	handleToday(w, asTestUser(req))
 
 	if w.Code != http.StatusInternalServerError {
 		t.Errorf("Expected status 500 for invalid JSON, got %d", w.Code)
@@ -878,21 +766,23 @@ func TestHandleTodayOvernightScenario(t *testing.T) {
 	today := time.Now().Format("2006-01-02")
 
 	// Set firstDay to yesterday
-	err := testDB.Update(func(tx *bolt.Tx) error {
-		return setFirstDay(tx, yesterday)
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		return tx.PutConfig("firstDay", yesterday)
 	})
 	if err != nil {
 		t.Fatalf("Failed to set firstDay: %v", err)
 	}
 
 	// Create yesterday's record with target 10
-	yesterdayData := DayData{
+	yesterdayData := tracker.DayData{
 		Date:  yesterday,
 		Count: 10,
 		Done:  true,
 	}
 	jsonData, _ := json.Marshal(yesterdayData)
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		return b.Put([]byte(yesterday), jsonData)
 	})
@@ -908,14 +798,14 @@ func TestHandleTodayOvernightScenario(t *testing.T) {
 	req.SetBasicAuth("admin", "admin")
 	w := httptest.NewRecorder()
 
-	handleToday(w, req)
+	handleToday(w, asTestUser(req))
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
 
 	// Verify today's data was auto-created with correct progression
-	var response DayData
+	var response tracker.DayData
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	if err != nil {
 		t.Fatalf("Failed to unmarshal response: %v", err)
@@ -937,10 +827,11 @@ func TestHandleTodayOvernightScenario(t *testing.T) {
 
 	// Step 3: Test that skipping a day keeps the same target
 	// Mark today as not done (skip it)
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		data := b.Get([]byte(today))
-		var todayData DayData
+		var todayData tracker.DayData
 		json.Unmarshal(data, &todayData)
 		todayData.Done = false // Explicitly not done (skipped)
 		jsonData, _ := json.Marshal(todayData)
@@ -958,21 +849,23 @@ func TestHandleTodayOvernightScenario(t *testing.T) {
 
 	// Temporarily mock "today" as tomorrow by updating the date in the request
 	// We'll create tomorrow's data directly instead
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		return b.Delete([]byte(tomorrow))
 	})
 
 	// Manually test the logic: if yesterday (today) was not done, keep same count
 	var tomorrowTarget int
-	err = testDB.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Days"))
+	err = testDB.View(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		b := testScope(root).Bucket([]byte("Days"))
 		data := b.Get([]byte(today))
-		var todayData DayData
+		var todayData tracker.DayData
 		json.Unmarshal(data, &todayData)
 
 		if todayData.Done {
-			tomorrowTarget = calculateNextTarget(todayData.Count, tx)
+			tomorrowTarget = tracker.StepStrategy{Steps: envSettings.Tiers, Max: envSettings.MaxTarget}.NextTarget(todayData.Count, tx)
 		} else {
 			// Skipped day - keep same target
 			tomorrowTarget = todayData.Count
@@ -996,21 +889,23 @@ func TestHandleTodayOvernightScenario(t *testing.T) {
 		dayDate := time.Now().AddDate(0, 0, -5+i).Format("2006-01-02")
 		if i > 0 {
 			// Calculate next target based on previous day (if completed)
-			err = testDB.Update(func(tx *bolt.Tx) error {
-				currentCount = calculateNextTarget(currentCount, tx)
+			err = testDB.Update(func(root *bolt.Tx) error {
+				tx := testTx(root)
+				currentCount = tracker.StepStrategy{Steps: envSettings.Tiers, Max: envSettings.MaxTarget}.NextTarget(currentCount, tx)
 				return nil
 			})
 			if err != nil {
 				t.Fatalf("Failed to calculate next target: %v", err)
 			}
 		}
-		dayData := DayData{
+		dayData := tracker.DayData{
 			Date:  dayDate,
 			Count: currentCount,
 			Done:  true,
 		}
 		jsonData, _ := json.Marshal(dayData)
-		err = testDB.Update(func(tx *bolt.Tx) error {
+		err = testDB.Update(func(root *bolt.Tx) error {
+			tx := testScope(root)
 			b := tx.Bucket([]byte("Days"))
 			return b.Put([]byte(dayDate), jsonData)
 		})
@@ -1033,25 +928,21 @@ func TestHandleTodayComplete(t *testing.T) {
 
 	// Save original db
 	origDB := db
-	origTodayCount := todayCount
 	db = testDB
-	defer func() {
-		db = origDB
-		todayCount = origTodayCount
-	}()
+	defer func() { db = origDB }()
 
 	today := time.Now().Format("2006-01-02")
-	todayCount = 15
 
 	// Test 1: Completing today's workout with existing data
-	dayData := DayData{
+	dayData := tracker.DayData{
 		Date:  today,
 		Count: 15,
 		Done:  false,
 	}
 
 	jsonData, _ := json.Marshal(dayData)
-	err := testDB.Update(func(tx *bolt.Tx) error {
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		return b.Put([]byte(today), jsonData)
 	})
@@ -1064,14 +955,14 @@ func TestHandleTodayComplete(t *testing.T) {
 	req.SetBasicAuth("admin", "admin")
 	w := httptest.NewRecorder()
 
-	handleTodayComplete(w, req)
+	handleTodayComplete(w, asTestUser(req))
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
 
 	// Verify the response
-	var response DayData
+	var response tracker.DayData
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	if err != nil {
 		t.Errorf("Failed to unmarshal response: %v", err)
@@ -1083,7 +974,8 @@ func TestHandleTodayComplete(t *testing.T) {
 
 	// Test 2: Completing workout with no existing data (should create new)
 	// Clear today's data
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		return b.Delete([]byte(today))
 	})
@@ -1095,7 +987,7 @@ func TestHandleTodayComplete(t *testing.T) {
 	req.SetBasicAuth("admin", "admin")
 	w = httptest.NewRecorder()
 
-	handleTodayComplete(w, req)
+	handleTodayComplete(w, asTestUser(req))
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200 for new day, got %d", w.Code)
@@ -1115,7 +1007,7 @@ func TestHandleTodayComplete(t *testing.T) {
 	req.SetBasicAuth("admin", "admin")
 	w = httptest.NewRecorder()
 
-	handleTodayComplete(w, req)
+	handleTodayComplete(w, asTestUser(req))
 
 	if w.Code != http.StatusMethodNotAllowed {
 		t.Errorf("Expected status 405 for GET request, got %d", w.Code)
@@ -1129,7 +1021,7 @@ func TestHandleTodayComplete(t *testing.T) {
 	req.SetBasicAuth("admin", "admin")
 	w = httptest.NewRecorder()
 
-	handleTodayComplete(w, req)
+	handleTodayComplete(w, asTestUser(req))
 
 	if w.Code != http.StatusInternalServerError {
 		t.Errorf("Expected status 500 for DB error, got %d", w.Code)
@@ -1142,18 +1034,14 @@ func TestHandleTodayCompleteErrorCases(t *testing.T) {
 
 	// Save original db
 	origDB := db
-	origTodayCount := todayCount
 	db = testDB
-	defer func() {
-		db = origDB
-		todayCount = origTodayCount
-	}()
+	defer func() { db = origDB }()
 
 	today := time.Now().Format("2006-01-02")
-	todayCount = 15
 
 	// Test error case with invalid JSON data already exists
-	err := testDB.Update(func(tx *bolt.Tx) error {
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		return b.Put([]byte(today), []byte("{invalid json}"))
 	})
@@ -1166,14 +1054,14 @@ func TestHandleTodayCompleteErrorCases(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	// Should fail with 500 due to invalid JSON
-	handleTodayComplete(w, req)
+	handleTodayComplete(w, asTestUser(req))
 
 	if w.Code != http.StatusInternalServerError {
 		t.Errorf("Expected status 500 for invalid JSON, got %d", w.Code)
 	}
 }
 
-func TestUpdateStreak(t *testing.T) {
+func TestHandleTodayUndo(t *testing.T) {
 	testDB := setupTestDB(t)
 	defer cleanupTestDB(t, testDB)
 
@@ -1185,139 +1073,80 @@ func TestUpdateStreak(t *testing.T) {
 	today := time.Now().Format("2006-01-02")
 	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
 
-	// Test case 1: First day (no yesterday data)
-	err := testDB.Update(func(tx *bolt.Tx) error {
-		updateStreak(tx, today)
-		return nil
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		tx.PutDay(tracker.DayData{Date: yesterday, Count: 10, Reps: 10, Done: true})
+		return tx.PutDay(tracker.DayData{Date: today, Count: 12})
 	})
+	if err == nil {
+		_, _, err = service().AddSet(testUser, today, 5, "")
+	}
 	if err != nil {
-		t.Fatalf("Failed to update streak: %v", err)
+		t.Fatalf("Failed to add test data: %v", err)
 	}
 
-	var streak StreakData
-	testDB.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Streak"))
-		data := b.Get([]byte("current"))
-		if data != nil {
-			return json.Unmarshal(data, &streak)
-		}
-		return nil
-	})
+	req := httptest.NewRequest("POST", "/api/today/complete", nil)
+	w := httptest.NewRecorder()
 
-	if streak.Current != 1 {
-		t.Errorf("Expected current streak to be 1 for first day, got %d", streak.Current)
-	}
-	if streak.Longest != 1 {
-		t.Errorf("Expected longest streak to be 1 for first day, got %d", streak.Longest)
-	}
-	if streak.LastDate != today {
-		t.Errorf("Expected last date to be %s, got %s", today, streak.LastDate)
-	}
+	handleTodayComplete(w, asTestUser(req))
 
-	// Test case 2: Yesterday was completed
-	yesterdayData := DayData{
-		Date:  yesterday,
-		Count: 10,
-		Done:  true,
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
 	}
-	yesterdayJSON, _ := json.Marshal(yesterdayData)
 
-	err = testDB.Update(func(tx *bolt.Tx) error {
-		// Add yesterday's data
-		b := tx.Bucket([]byte("Days"))
-		err := b.Put([]byte(yesterday), yesterdayJSON)
-		if err != nil {
-			return err
-		}
+	// Test 1: Undoing removes the final set and reopens the day
+	req = httptest.NewRequest("DELETE", "/api/today/complete", nil)
+	w = httptest.NewRecorder()
 
-		// Set initial streak to 1 (yesterday's streak)
-		streak := StreakData{
-			Current:  1,
-			Longest:  1,
-			LastDate: yesterday,
-		}
-		streakJSON, _ := json.Marshal(streak)
-		streakB := tx.Bucket([]byte("Streak"))
-		err = streakB.Put([]byte("current"), streakJSON)
-		return err
-	})
-	if err != nil {
-		t.Fatalf("Failed to add yesterday data and initial streak: %v", err)
-	}
+	handleTodayComplete(w, asTestUser(req))
 
-	// Update streak for today
-	err = testDB.Update(func(tx *bolt.Tx) error {
-		updateStreak(tx, today)
-		return nil
-	})
-	if err != nil {
-		t.Fatalf("Failed to update streak: %v", err)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response tracker.Day
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Done || response.Reps != 5 || len(response.Sets) != 1 || response.Remaining != 7 {
+		t.Errorf("Expected the day back at 5 reps, got %+v", response)
 	}
 
-	testDB.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Streak"))
-		data := b.Get([]byte("current"))
-		if data != nil {
-			return json.Unmarshal(data, &streak)
+	// The streak is back to what it was before completing today
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		streak, _ := tx.Streak()
+		if streak.Current != 1 || streak.LastDate != yesterday {
+			t.Errorf("Expected streak of 1 ending yesterday, got %+v", streak)
+		}
+		if entries, _ := tx.Audit(today); len(entries) != 1 || entries[0].Action != "undo" {
+			t.Errorf("Expected an audit entry for the undo, got %+v", entries)
 		}
 		return nil
 	})
 
-	if streak.Current != 2 {
-		t.Errorf("Expected current streak to be 2 when yesterday was completed, got %d", streak.Current)
-	}
-
-	// Test case 3: Yesterday was not completed
-	yesterdayNotDone := DayData{
-		Date:  yesterday,
-		Count: 10,
-		Done:  false,
-	}
-	yesterdayNotDoneJSON, _ := json.Marshal(yesterdayNotDone)
+	// Test 2: Undoing an open day changes nothing
+	req = httptest.NewRequest("DELETE", "/api/today/complete", nil)
+	w = httptest.NewRecorder()
 
-	err = testDB.Update(func(tx *bolt.Tx) error {
-		// Update yesterday's data to not done
-		b := tx.Bucket([]byte("Days"))
-		err := b.Put([]byte(yesterday), yesterdayNotDoneJSON)
-		if err != nil {
-			return err
-		}
+	handleTodayComplete(w, asTestUser(req))
 
-		// Set initial streak to 1 (yesterday's streak)
-		streak := StreakData{
-			Current:  1,
-			Longest:  1,
-			LastDate: yesterday,
-		}
-		streakJSON, _ := json.Marshal(streak)
-		streakB := tx.Bucket([]byte("Streak"))
-		err = streakB.Put([]byte("current"), streakJSON)
-		return err
-	})
-	if err != nil {
-		t.Fatalf("Failed to update yesterday data and set initial streak: %v", err)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.Done || response.Reps != 5 {
+		t.Errorf("Expected the open day unchanged, got %d %+v", w.Code, response)
 	}
 
-	// Update streak for today
-	err = testDB.Update(func(tx *bolt.Tx) error {
-		updateStreak(tx, today)
-		return nil
-	})
-	if err != nil {
-		t.Fatalf("Failed to update streak again: %v", err)
+	// Test 3: A day completed by logged sets stays done, a set has to be deleted
+	if _, _, err := service().AddSet(testUser, today, 7, ""); err != nil {
+		t.Fatalf("Failed to log a set: %v", err)
 	}
+	req = httptest.NewRequest("DELETE", "/api/today/complete", nil)
+	w = httptest.NewRecorder()
 
-	testDB.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Streak"))
-		data := b.Get([]byte("current"))
-		if data != nil {
-			return json.Unmarshal(data, &streak)
-		}
-		return nil
-	})
+	handleTodayComplete(w, asTestUser(req))
 
-	if streak.Current != 1 {
-		t.Errorf("Expected current streak to be 1 when yesterday was not completed, got %d", streak.Current)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+	}
+	if day, _, _ := service().Day(testUser, today); !day.Done || day.Reps != 12 || len(day.Sets) != 2 {
+		t.Errorf("Expected the day to stay done, got %+v", day)
 	}
 }
 
@@ -1337,7 +1166,7 @@ func TestHandleCalendar(t *testing.T) {
 	req.SetBasicAuth("admin", "admin")
 	w := httptest.NewRecorder()
 
-	handleCalendar(w, req)
+	handleCalendar(w, asTestUser(req))
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", w.Code)
@@ -1345,10 +1174,10 @@ func TestHandleCalendar(t *testing.T) {
 
 	// Verify the response structure
 	var response struct {
-		Year       int                `json:"year"`
-		StartMonth int                `json:"startMonth"`
-		StartYear  int                `json:"startYear"`
-		Days       map[string]DayData `json:"days"`
+		Year       int                        `json:"year"`
+		StartMonth int                        `json:"startMonth"`
+		StartYear  int                        `json:"startYear"`
+		Days       map[string]tracker.DayData `json:"days"`
 	}
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	if err != nil {
@@ -1361,14 +1190,15 @@ func TestHandleCalendar(t *testing.T) {
 
 	// Test case 2: With records
 	testDate := year + "-01-01"
-	dayData := DayData{
+	dayData := tracker.DayData{
 		Date:  testDate,
 		Count: 10,
 		Done:  true,
 	}
 	dayDataJSON, _ := json.Marshal(dayData)
 
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		return b.Put([]byte(testDate), dayDataJSON)
 	})
@@ -1380,7 +1210,7 @@ func TestHandleCalendar(t *testing.T) {
 	req.SetBasicAuth("admin", "admin")
 	w = httptest.NewRecorder()
 
-	handleCalendar(w, req)
+	handleCalendar(w, asTestUser(req))
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", w.Code)
@@ -1408,14 +1238,15 @@ func TestHandleCalendar(t *testing.T) {
 	nextYearDate := nextYear + "-01-01"
 
 	// Add a date for next year
-	nextYearData := DayData{
+	nextYearData := tracker.DayData{
 		Date:  nextYearDate,
 		Count: 15,
 		Done:  true,
 	}
 	nextYearJSON, _ := json.Marshal(nextYearData)
 
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		return b.Put([]byte(nextYearDate), nextYearJSON)
 	})
@@ -1427,7 +1258,7 @@ func TestHandleCalendar(t *testing.T) {
 	req.SetBasicAuth("admin", "admin")
 	w = httptest.NewRecorder()
 
-	handleCalendar(w, req)
+	handleCalendar(w, asTestUser(req))
 
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	if err != nil {
@@ -1446,7 +1277,8 @@ func TestHandleCalendar(t *testing.T) {
 
 	// Test case 4: Invalid date in database
 	invalidDate := year + "-invalid-date"
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		invalidJSON := []byte("{invalid json}")
 		return b.Put([]byte(invalidDate), invalidJSON)
@@ -1460,7 +1292,7 @@ func TestHandleCalendar(t *testing.T) {
 	w = httptest.NewRecorder()
 
 	// Should still succeed despite invalid data
-	handleCalendar(w, req)
+	handleCalendar(w, asTestUser(req))
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200 despite invalid data, got %d", w.Code)
@@ -1468,7 +1300,8 @@ func TestHandleCalendar(t *testing.T) {
 
 	// Test case 5: First record date with invalid format
 	invalidFormatDate := "not-a-date"
-	err = testDB.Update(func(tx *bolt.Tx) error {
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
 		b := tx.Bucket([]byte("Days"))
 		// Clear all data first
 		cursor := b.Cursor()
@@ -1491,7 +1324,7 @@ func TestHandleCalendar(t *testing.T) {
 	w = httptest.NewRecorder()
 
 	// Should fail with 500 due to date parsing error
-	handleCalendar(w, req)
+	handleCalendar(w, asTestUser(req))
 
 	if w.Code != http.StatusInternalServerError {
 		t.Errorf("Expected status 500 for invalid date format, got %d", w.Code)
@@ -1516,7 +1349,7 @@ func TestHandleCalendarErrorCases(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	// Should fail with 500 due to DB error
-	handleCalendar(w, req)
+	handleCalendar(w, asTestUser(req))
 
 	if w.Code != http.StatusInternalServerError {
 		t.Errorf("Expected status 500 for DB error, got %d", w.Code)
@@ -1537,14 +1370,14 @@ func TestHandleStreak(t *testing.T) {
 	req.SetBasicAuth("admin", "admin")
 	w := httptest.NewRecorder()
 
-	handleStreak(w, req)
+	handleStreak(w, asTestUser(req))
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
 
 	// Verify the response structure
-	var streak StreakData
+	var streak tracker.StreakData
 	err := json.Unmarshal(w.Body.Bytes(), &streak)
 	if err != nil {
 		t.Errorf("Failed to unmarshal response: %v", err)
@@ -1560,27 +1393,30 @@ func TestHandleStreak(t *testing.T) {
 		t.Errorf("Expected last date to be empty initially, got %s", streak.LastDate)
 	}
 
-	// Test case 2: With existing streak data
-	streakData := StreakData{
-		Current:  5,
-		Longest:  10,
-		LastDate: time.Now().Format("2006-01-02"),
-	}
-	streakDataJSON, _ := json.Marshal(streakData)
-
-	err = testDB.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Streak"))
-		return b.Put([]byte("current"), streakDataJSON)
+	// Test case 2: Derived from the completed days
+	today := time.Now()
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		for i := 0; i < 10; i++ {
+			date := today.AddDate(0, 0, -20+i).Format("2006-01-02")
+			tx.PutDay(tracker.DayData{Date: date, Count: 10, Done: true})
+		}
+		for i := 1; i <= 5; i++ {
+			date := today.AddDate(0, 0, -i).Format("2006-01-02")
+			tx.PutDay(tracker.DayData{Date: date, Count: 10, Done: true})
+		}
+		// Today is still open, which doesn't break the streak yet
+		return tx.PutDay(tracker.DayData{Date: today.Format("2006-01-02"), Count: 10})
 	})
 	if err != nil {
-		t.Fatalf("Failed to add test streak data: %v", err)
+		t.Fatalf("Failed to add test days: %v", err)
 	}
 
 	req = httptest.NewRequest("GET", "/api/streak", nil)
 	req.SetBasicAuth("admin", "admin")
 	w = httptest.NewRecorder()
 
-	handleStreak(w, req)
+	handleStreak(w, asTestUser(req))
 
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", w.Code)
@@ -1598,24 +1434,195 @@ func TestHandleStreak(t *testing.T) {
 		t.Errorf("Expected longest streak to be 10, got %d", streak.Longest)
 	}
 
-	// Test case 3: Invalid streak data in database
+	// Test case 3: A stale stored streak is ignored once days were missed
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		for i := 1; i <= 2; i++ {
+			date := today.AddDate(0, 0, -i).Format("2006-01-02")
+			tx.PutDay(tracker.DayData{Date: date, Count: 10})
+		}
+		return tx.PutStreak(tracker.StreakData{Current: 5, Longest: 10, LastDate: today.AddDate(0, 0, -1).Format("2006-01-02")})
+	})
+	if err != nil {
+		t.Fatalf("Failed to update test days: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	handleStreak(w, asTestUser(req))
+
+	json.Unmarshal(w.Body.Bytes(), &streak)
+	if streak.Current != 0 || streak.Longest != 10 {
+		t.Errorf("Expected broken streak 0 with longest 10, got %+v", streak)
+	}
+
+	// Test case 4: Invalid day data in database
 	invalidJSON := []byte("{invalid json}")
-	err = testDB.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("Streak"))
-		return b.Put([]byte("current"), invalidJSON)
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		b := tx.Bucket([]byte("Days"))
+		return b.Put([]byte(today.AddDate(0, 0, -30).Format("2006-01-02")), invalidJSON)
 	})
 	if err != nil {
-		t.Fatalf("Failed to add invalid streak data: %v", err)
+		t.Fatalf("Failed to add invalid day data: %v", err)
 	}
 
-	req = httptest.NewRequest("GET", "/api/streak", nil)
-	req.SetBasicAuth("admin", "admin")
 	w = httptest.NewRecorder()
 
 	// This should fail with 500 due to invalid JSON
-	handleStreak(w, req)
+	handleStreak(w, asTestUser(req))
 
 	if w.Code != http.StatusInternalServerError {
 		t.Errorf("Expected status 500 for invalid JSON, got %d", w.Code)
 	}
 }
+
+func TestHandleTodayReps(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	today := time.Now().Format("2006-01-02")
+
+	dayData := tracker.DayData{
+		Date:  today,
+		Count: 20,
+		Done:  false,
+	}
+	jsonData, _ := json.Marshal(dayData)
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		b := tx.Bucket([]byte("Days"))
+		return b.Put([]byte(today), jsonData)
+	})
+	if err != nil {
+		t.Fatalf("Failed to add test data: %v", err)
+	}
+
+	// Test 1: First set doesn't complete the day
+	req := httptest.NewRequest("POST", "/api/today/reps", strings.NewReader(`{"reps": 12}`))
+	w := httptest.NewRecorder()
+
+	handleTodayReps(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response tracker.DayData
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Reps != 12 {
+		t.Errorf("Expected reps 12, got %d", response.Reps)
+	}
+	if response.Done {
+		t.Errorf("Expected day not done after partial set")
+	}
+
+	// Test 2: Second set reaches the target and completes the day
+	req = httptest.NewRequest("POST", "/api/today/reps", strings.NewReader(`{"reps": 10}`))
+	w = httptest.NewRecorder()
+
+	handleTodayReps(w, asTestUser(req))
+
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Reps != 22 {
+		t.Errorf("Expected reps 22, got %d", response.Reps)
+	}
+	if !response.Done {
+		t.Errorf("Expected day done once reps reach target")
+	}
+
+	var streak tracker.StreakData
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		data := tx.Bucket([]byte("Streak")).Get([]byte("current"))
+		return json.Unmarshal(data, &streak)
+	})
+	if streak.Current != 1 {
+		t.Errorf("Expected streak 1 after completion, got %d", streak.Current)
+	}
+
+	// Test 3: Further sets don't bump the streak again
+	req = httptest.NewRequest("POST", "/api/today/reps", strings.NewReader(`{"reps": 5}`))
+	w = httptest.NewRecorder()
+
+	handleTodayReps(w, asTestUser(req))
+
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		data := tx.Bucket([]byte("Streak")).Get([]byte("current"))
+		return json.Unmarshal(data, &streak)
+	})
+	if streak.Current != 1 {
+		t.Errorf("Expected streak to stay 1, got %d", streak.Current)
+	}
+
+	// Test 4: Invalid input
+	for _, body := range []string{`{"reps": 0}`, `{"reps": -5}`, `{"reps": 5000}`, `not json`} {
+		req = httptest.NewRequest("POST", "/api/today/reps", strings.NewReader(body))
+		w = httptest.NewRecorder()
+
+		handleTodayReps(w, asTestUser(req))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for body %q, got %d", body, w.Code)
+		}
+	}
+
+	// Test 5: Wrong method
+	req = httptest.NewRequest("GET", "/api/today/reps", nil)
+	w = httptest.NewRecorder()
+
+	handleTodayReps(w, asTestUser(req))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for GET request, got %d", w.Code)
+	}
+}
+
+func TestHandleTodayTimezone(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	// UTC+14 and UTC-12 are always on different dates
+	for _, timezone := range []string{"Etc/GMT-14", "Etc/GMT+12"} {
+		settings := envSettings
+		settings.Timezone = timezone
+		service().SaveSettings(testUser, settings)
+
+		loc, _ := time.LoadLocation(timezone)
+		expected := time.Now().In(loc).Format("2006-01-02")
+
+		req := httptest.NewRequest("GET", "/api/today", nil)
+		w := httptest.NewRecorder()
+
+		handleToday(w, asTestUser(req))
+
+		var response tracker.Day
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Date != expected {
+			t.Errorf("Expected today to be %s in %s, got %s", expected, timezone, response.Date)
+		}
+
+		req = httptest.NewRequest("GET", "/api/calendar", nil)
+		w = httptest.NewRecorder()
+
+		handleCalendar(w, asTestUser(req))
+
+		var calendar struct {
+			Today string `json:"today"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &calendar)
+		if calendar.Today != expected {
+			t.Errorf("Expected calendar today to be %s in %s, got %s", expected, timezone, calendar.Today)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func handleProgression(w http.ResponseWriter, r *http.Request) {
+	var config tracker.ProgressionConfig
+
+	switch r.Method {
+	case "GET":
+		var err error
+		config, err = service().Progression(requestUser(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case "PUT":
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		settings, err := service().Settings(requestUser(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := config.Validate(settings.MaxTarget); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := service().SaveProgression(requestUser(r), config); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := struct {
+		tracker.ProgressionConfig
+		Available []string `json:"available"`
+	}{
+		ProgressionConfig: config,
+		Available:         tracker.ProgressionStrategies,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestHandleProgression(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	// Test 1: Default strategy
+	req := httptest.NewRequest("GET", "/api/admin/progression", nil)
+	w := httptest.NewRecorder()
+
+	handleProgression(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		tracker.ProgressionConfig
+		Available []string `json:"available"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Strategy != "ladder" {
+		t.Errorf("Expected default strategy ladder, got %s", response.Strategy)
+	}
+	if len(response.Available) != len(tracker.ProgressionStrategies) {
+		t.Errorf("Expected %d available strategies, got %d", len(tracker.ProgressionStrategies), len(response.Available))
+	}
+
+	// Test 2: Switch strategy
+	req = httptest.NewRequest("PUT", "/api/admin/progression", strings.NewReader(`{"strategy": "percentage", "percent": 5}`))
+	w = httptest.NewRecorder()
+
+	handleProgression(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	stored, _ := service().Progression(testUser)
+	if stored.Strategy != "percentage" || stored.Percent != 5 {
+		t.Errorf("Expected stored percentage strategy, got %+v", stored)
+	}
+
+	// Test 3: Invalid strategy is rejected
+	req = httptest.NewRequest("PUT", "/api/admin/progression", strings.NewReader(`{"strategy": "fixed"}`))
+	w = httptest.NewRecorder()
+
+	handleProgression(w, asTestUser(req))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid config, got %d", w.Code)
+	}
+
+	// Test 4: Wrong method
+	req = httptest.NewRequest("DELETE", "/api/admin/progression", nil)
+	w = httptest.NewRecorder()
+
+	handleProgression(w, asTestUser(req))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// remoteCommands can run through the API of the running server
+var remoteCommands = map[string]bool{
+	"today": true, "complete": true, "log": true, "streak": true, "calendar": true, "history": true, "export": true,
+}
+
+// runRemoteCommand runs a command through the API of the server holding the
+// database at dbPath, with the API token in API_TOKEN. API_URL is the address
+// of the server, http://localhost:PORT by default.
+func runRemoteCommand(args []string, defaultUser, port, dbPath string) error {
+	if !remoteCommands[args[0]] {
+		return fmt.Errorf("%s is in use, most likely by the running server: stop it to run %s", dbPath, args[0])
+	}
+	token := os.Getenv("API_TOKEN")
+	if token == "" {
+		return fmt.Errorf("%s is in use, most likely by the running server: stop it, or set API_TOKEN to run %s through its API", dbPath, args[0])
+	}
+
+	url := os.Getenv("API_URL")
+	if url == "" {
+		url = "http://localhost:" + port
+	}
+	remote = apiClient{
+		url:    strings.TrimRight(url, "/"),
+		token:  token,
+		user:   defaultUser,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	return runCommand(args, defaultUser)
+}
+
+// apiClient runs the tracker commands through the API of the running server.
+// The API token decides the account, so -user can't pick another one.
+type apiClient struct {
+	url    string
+	token  string
+	user   string
+	client *http.Client
+}
+
+// do sends a request with body as JSON, and decodes the response into result
+func (c apiClient) do(method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s", method, path, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// account checks that a command works on the account of the API token
+func (c apiClient) account(username string) error {
+	if username != c.user {
+		return errors.New("-user can't be used through the API, the commands work on the account of API_TOKEN")
+	}
+	return nil
+}
+
+func (c apiClient) day(method, path, username string, body interface{}) (tracker.Day, error) {
+	var day tracker.Day
+	if err := c.account(username); err != nil {
+		return day, err
+	}
+	err := c.do(method, path, body, &day)
+	return day, err
+}
+
+func (c apiClient) Today(username string) (tracker.Day, error) {
+	return c.day("GET", "/api/today", username, nil)
+}
+
+func (c apiClient) Complete(username string) (tracker.Day, error) {
+	return c.day("POST", "/api/today/complete", username, nil)
+}
+
+func (c apiClient) Undo(username string) (tracker.Day, error) {
+	return c.day("DELETE", "/api/today/complete", username, nil)
+}
+
+func (c apiClient) Log(username string, reps int, note string) (tracker.Day, error) {
+	body := map[string]interface{}{"reps": reps, "note": note}
+	return c.day("POST", "/api/today/reps", username, body)
+}
+
+// SetTarget has no API, runRemoteCommand refuses set-target before it gets here
+func (c apiClient) SetTarget(username string, count int) (tracker.Day, error) {
+	return tracker.Day{}, errors.New("set-target can't run through the API, stop the server to run it")
+}
+
+func (c apiClient) Streak(username string) (tracker.StreakData, error) {
+	var streak tracker.StreakData
+	if err := c.account(username); err != nil {
+		return streak, err
+	}
+	err := c.do("GET", "/api/streak", nil, &streak)
+	return streak, err
+}
+
+func (c apiClient) Calendar(username string, year int) (tracker.Calendar, error) {
+	var calendar tracker.Calendar
+	if err := c.account(username); err != nil {
+		return calendar, err
+	}
+	path := "/api/calendar"
+	if year != 0 {
+		path += "?year=" + strconv.Itoa(year)
+	}
+	err := c.do("GET", path, nil, &calendar)
+	return calendar, err
+}
+
+// History takes the days from the export, the API has no history of its own
+func (c apiClient) History(username, from, to string) ([]tracker.DayData, error) {
+	export, err := c.Export(username)
+	if err != nil {
+		return nil, err
+	}
+	var days []tracker.DayData
+	for _, day := range export.Days {
+		if (from == "" || day.Date >= from) && (to == "" || day.Date <= to) {
+			days = append(days, day)
+		}
+	}
+	return days, nil
+}
+
+func (c apiClient) Export(username string) (tracker.Export, error) {
+	var export tracker.Export
+	if err := c.account(username); err != nil {
+		return export, err
+	}
+	err := c.do("GET", "/api/export?format=json", nil, &export)
+	return export, err
+}
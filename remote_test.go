@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestRemoteCommands(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db and clock
+	origDB, origClock := db, clock
+	db = testDB
+	defer func() { db, clock, remote = origDB, origClock, nil }()
+	fake := &tracker.OffsetClock{}
+	fake.Set(time.Date(2024, 3, 4, 12, 0, 0, 0, time.Local))
+	clock = fake
+
+	var token string
+	testDB.Update(func(tx *bolt.Tx) error {
+		token, _, _ = createToken(tx, testUser, "cli", "write")
+		return nil
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/today", requireAuth(handleToday))
+	mux.HandleFunc("/api/today/complete", requireAuth(handleTodayComplete))
+	mux.HandleFunc("/api/today/reps", requireAuth(handleTodayReps))
+	mux.HandleFunc("/api/streak", requireAuth(handleStreak))
+	mux.HandleFunc("/api/export", requireAuth(handleExport))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	remote = apiClient{url: server.URL, token: token, user: testUser, client: server.Client()}
+
+	run := func(fn func(w *bytes.Buffer) error) string {
+		t.Helper()
+		var out bytes.Buffer
+		if err := fn(&out); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	// Test 1: The commands go through the API
+	out := run(func(w *bytes.Buffer) error { return runToday(w, nil, testUser) })
+	if out != "2024-03-04: 0/10 reps, 10 to go\n" {
+		t.Errorf("Unexpected today output %q", out)
+	}
+	out = run(func(w *bytes.Buffer) error { return runLog(w, []string{"-note", "morning", "6"}, testUser) })
+	if !strings.HasPrefix(out, "2024-03-04: 6/10 reps, 4 to go\n") || !strings.Contains(out, "6 reps  morning") {
+		t.Errorf("Expected the set to be logged, got %q", out)
+	}
+	out = run(func(w *bytes.Buffer) error { return runComplete(w, nil, testUser) })
+	if !strings.HasPrefix(out, "2024-03-04: 10/10 reps, done\n") {
+		t.Errorf("Expected today to be completed, got %q", out)
+	}
+	out = run(func(w *bytes.Buffer) error { return runStreak(w, nil, testUser) })
+	if !strings.HasPrefix(out, "Current streak: 1 ") {
+		t.Errorf("Unexpected streak output %q", out)
+	}
+	out = run(func(w *bytes.Buffer) error { return runHistory(w, []string{"-to", "2024-03-04"}, testUser) })
+	if out != "2024-03-04    10/10   done\n1 days, 1 completed, 10 reps\n" {
+		t.Errorf("Unexpected history %q", out)
+	}
+
+	// Test 2: The server's errors are passed on
+	if err := runLog(&bytes.Buffer{}, []string{"0"}, testUser); err == nil || !strings.Contains(err.Error(), "reps must be between") {
+		t.Errorf("Expected the server's error for 0 reps, got %v", err)
+	}
+	remote = apiClient{url: server.URL, token: tokenPrefix + "unknown", user: testUser, client: server.Client()}
+	if err := runToday(&bytes.Buffer{}, nil, testUser); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("Expected an unknown token to be refused, got %v", err)
+	}
+
+	// Test 3: The token decides the account, and some commands need the database
+	if err := runToday(&bytes.Buffer{}, []string{"-user", "other"}, testUser); err == nil {
+		t.Errorf("Expected -user to be refused through the API")
+	}
+	t.Setenv("API_TOKEN", token)
+	err := runRemoteCommand([]string{"set-target", "5"}, testUser, "8080", "pushups.db")
+	if err == nil || !strings.Contains(err.Error(), "pushups.db is in use") {
+		t.Errorf("Expected set-target to need the database, got %v", err)
+	}
+	t.Setenv("API_TOKEN", "")
+	err = runRemoteCommand([]string{"today"}, testUser, "8080", "pushups.db")
+	if err == nil || !strings.Contains(err.Error(), "API_TOKEN") {
+		t.Errorf("Expected a hint about API_TOKEN, got %v", err)
+	}
+}
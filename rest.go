@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// handleDayRest marks a day as a rest or sick day with POST, optionally with
+// {"status": "sick"}, and turns it back into a training day with DELETE
+func handleDayRest(w http.ResponseWriter, r *http.Request, date string) {
+	status := ""
+	switch r.Method {
+	case "POST":
+		var req struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		status = req.Status
+		if status == "" {
+			status = tracker.StatusRest
+		}
+		if status != tracker.StatusRest && status != tracker.StatusSick {
+			http.Error(w, fmt.Sprintf("status must be %s or %s", tracker.StatusRest, tracker.StatusSick), http.StatusBadRequest)
+			return
+		}
+	case "DELETE":
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	day, err := service().SetStatus(requestUser(r), date, status)
+	if errors.Is(err, tracker.ErrFutureDate) {
+		http.Error(w, "Date is in the future", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, tracker.ErrOutsideBackfill) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(day)
+}
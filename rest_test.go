@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestSettingsFromEnvRestDays(t *testing.T) {
+	t.Setenv("REST_DAYS", "Saturday, sunday")
+
+	settings, err := settingsFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var weekdays []time.Weekday
+	for _, name := range settings.RestDays {
+		day, _ := tracker.ParseWeekday(name)
+		weekdays = append(weekdays, day)
+	}
+	if len(weekdays) != 2 || weekdays[0] != time.Saturday || weekdays[1] != time.Sunday {
+		t.Errorf("Expected weekend rest days, got %v", settings.RestDays)
+	}
+}
+
+func TestHandleDayRest(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	twoDaysAgo := time.Now().AddDate(0, 0, -2).Format("2006-01-02")
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		tx.PutConfig("firstDay", twoDaysAgo)
+		return tx.PutDay(tracker.DayData{Date: twoDaysAgo, Count: 10, Reps: 10, Done: true})
+	})
+
+	// Test 1: Mark yesterday, which has no record yet, as a sick day
+	req := httptest.NewRequest("POST", "/api/days/"+yesterday.Format("2006-01-02")+"/rest", strings.NewReader(`{"status": "sick"}`))
+	w := httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response tracker.Day
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Status != tracker.StatusSick || response.Count < 10 {
+		t.Errorf("Expected sick day with a target, got %+v", response)
+	}
+
+	// The sick day keeps the streak going
+	testDB.View(func(root *bolt.Tx) error {
+		streak, _ := testTx(root).Streak()
+		if streak.Current != 1 {
+			t.Errorf("Expected streak 1 across the sick day, got %+v", streak)
+		}
+		return nil
+	})
+
+	// Test 2: Without a body the day is a rest day
+	req = httptest.NewRequest("POST", "/api/days/"+yesterday.Format("2006-01-02")+"/rest", nil)
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Status != tracker.StatusRest {
+		t.Errorf("Expected rest day, got %+v", response)
+	}
+
+	// Test 3: DELETE turns it back into a missed training day
+	req = httptest.NewRequest("DELETE", "/api/days/"+yesterday.Format("2006-01-02")+"/rest", nil)
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	response.Status = ""
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.Status != "" {
+		t.Errorf("Expected training day, got %d %+v", w.Code, response)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		streak, _ := testTx(root).Streak()
+		if streak.Current != 0 {
+			t.Errorf("Expected broken streak, got %+v", streak)
+		}
+		return nil
+	})
+
+	// Test 4: Invalid requests
+	for _, tt := range []struct{ method, body string }{
+		{"POST", `{"status": "holiday"}`},
+		{"POST", `not json`},
+		{"PUT", ``},
+	} {
+		req = httptest.NewRequest(tt.method, "/api/days/"+yesterday.Format("2006-01-02")+"/rest", strings.NewReader(tt.body))
+		w = httptest.NewRecorder()
+
+		handleDays(w, asTestUser(req))
+
+		if w.Code == http.StatusOK {
+			t.Errorf("Expected error for %s %s, got %d", tt.method, tt.body, w.Code)
+		}
+	}
+
+	// Test 5: Days before the backfill window can't be changed
+	old := time.Now().AddDate(0, 0, -30).Format("2006-01-02")
+	req = httptest.NewRequest("POST", "/api/days/"+old+"/rest", nil)
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for an old day, got %d", w.Code)
+	}
+}
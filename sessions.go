@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// Session is a browser login. Sessions are stored as JSON in the Sessions bucket
+// keyed by their ID, so logging out or deleting a user revokes them immediately.
+type Session struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	// CSRFToken must accompany every state-changing request made with the session
+	CSRFToken string `json:"csrfToken"`
+	Expires   string `json:"expires"`
+}
+
+// sessionCookieName is the cookie holding the signed session ID
+const sessionCookieName = "session"
+
+// sessionContextKey holds the request's session, if it was authenticated by one
+const sessionContextKey contextKey = "session"
+
+var (
+	// sessionSecret signs session cookies, see loadSessionSecret
+	sessionSecret []byte
+	// sessionTTL is how long a login lasts
+	sessionTTL = 7 * 24 * time.Hour
+	// basicAuthEnabled lets scripts use Basic Auth next to the login form
+	basicAuthEnabled = false
+	// secureCookies marks the session cookie as HTTPS only
+	secureCookies = false
+)
+
+// authFromEnv reads SESSION_TTL, BASIC_AUTH and COOKIE_SECURE
+func authFromEnv() error {
+	if value := os.Getenv("SESSION_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl < time.Minute {
+			return fmt.Errorf("SESSION_TTL must be a duration of at least 1m, e.g. 168h")
+		}
+		sessionTTL = ttl
+	}
+
+	bools := []struct {
+		key   string
+		value *bool
+	}{
+		{"BASIC_AUTH", &basicAuthEnabled},
+		{"COOKIE_SECURE", &secureCookies},
+	}
+	for _, b := range bools {
+		value := os.Getenv(b.key)
+		if value == "" {
+			continue
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", b.key, err)
+		}
+		*b.value = enabled
+	}
+	return nil
+}
+
+// loadSessionSecret uses SESSION_SECRET if set, otherwise a random secret kept in
+// the Server bucket so that sessions survive a restart
+func loadSessionSecret(tx *bolt.Tx) ([]byte, error) {
+	if secret := os.Getenv("SESSION_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+
+	b := tx.Bucket([]byte("Server"))
+	if secret := b.Get([]byte("sessionSecret")); secret != nil {
+		return append([]byte(nil), secret...), nil
+	}
+
+	secret, err := randomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	return secret, b.Put([]byte("sessionSecret"), secret)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+func randomToken() (string, error) {
+	b, err := randomBytes(32)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// signSession returns the cookie value for a session: its ID, expiry and a signature of both
+func signSession(id string, expires time.Time) string {
+	payload := id + "." + strconv.FormatInt(expires.Unix(), 10)
+	mac := hmac.New(sha256.New, sessionSecret)
+	mac.Write([]byte(payload))
+	return payload + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySessionCookie checks the signature and expiry of a cookie value and returns the session ID
+func verifySessionCookie(value string, now time.Time) (string, bool) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return "", false
+	}
+	expected := signSession(parts[0], time.Unix(expires, 0))
+	if !hmac.Equal([]byte(expected), []byte(value)) {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// createSession starts a session for the user, clearing out expired ones on the way
+func createSession(tx *bolt.Tx, username string, now time.Time) (Session, error) {
+	if err := deleteSessions(tx, func(s Session) bool { return sessionExpired(s, now) }); err != nil {
+		return Session{}, err
+	}
+
+	id, err := randomToken()
+	if err != nil {
+		return Session{}, err
+	}
+	csrfToken, err := randomToken()
+	if err != nil {
+		return Session{}, err
+	}
+
+	session := Session{
+		ID:        id,
+		Username:  username,
+		CSRFToken: csrfToken,
+		Expires:   now.Add(sessionTTL).Format(time.RFC3339),
+	}
+	jsonData, err := json.Marshal(session)
+	if err != nil {
+		return Session{}, err
+	}
+	return session, tx.Bucket([]byte("Sessions")).Put([]byte(id), jsonData)
+}
+
+// getSession returns a session that exists and hasn't expired
+func getSession(tx *bolt.Tx, id string, now time.Time) (Session, bool) {
+	var session Session
+	data := tx.Bucket([]byte("Sessions")).Get([]byte(id))
+	if data == nil || json.Unmarshal(data, &session) != nil || sessionExpired(session, now) {
+		return Session{}, false
+	}
+	return session, true
+}
+
+func sessionExpired(session Session, now time.Time) bool {
+	expires, err := time.Parse(time.RFC3339, session.Expires)
+	return err != nil || !now.Before(expires)
+}
+
+// deleteSessions removes every session matching the filter
+func deleteSessions(tx *bolt.Tx, match func(Session) bool) error {
+	b := tx.Bucket([]byte("Sessions"))
+
+	var ids [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		var session Session
+		if json.Unmarshal(v, &session) != nil || match(session) {
+			ids = append(ids, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := b.Delete(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteUserSessions logs the user out everywhere
+func deleteUserSessions(tx *bolt.Tx, username string) error {
+	return deleteSessions(tx, func(s Session) bool { return s.Username == username })
+}
+
+// sessionFromRequest returns the session of a valid session cookie
+func sessionFromRequest(r *http.Request) (Session, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return Session{}, false
+	}
+	now := time.Now()
+	id, ok := verifySessionCookie(cookie.Value, now)
+	if !ok {
+		return Session{}, false
+	}
+
+	var session Session
+	db.View(func(tx *bolt.Tx) error {
+		session, ok = getSession(tx, id, now)
+		return nil
+	})
+	return session, ok
+}
+
+// requestSession returns the session the request was authenticated with, if any
+func requestSession(r *http.Request) (Session, bool) {
+	session, ok := r.Context().Value(sessionContextKey).(Session)
+	return session, ok
+}
+
+// validCSRF checks the X-CSRF-Token header, or the csrf_token form field of a login page form
+func validCSRF(r *http.Request, session Session) bool {
+	token := r.Header.Get("X-CSRF-Token")
+	if token == "" {
+		token = r.PostFormValue("csrf_token")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) == 1
+}
+
+// simpleRequest reports whether a browser could send the request from another site
+// without asking first, i.e. a form post. Browsers resend cached Basic Auth
+// credentials with those, so they can't be trusted to come from a script.
+func simpleRequest(r *http.Request) bool {
+	if r.Method == "GET" || r.Method == "HEAD" {
+		return false
+	}
+	if r.Header.Get("X-Requested-With") != "" {
+		return false
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+	switch mediaType {
+	case "application/x-www-form-urlencoded", "multipart/form-data", "text/plain":
+		return true
+	}
+	return false
+}
+
+// requireAuth accepts an API token, a session cookie or, when enabled, Basic Auth
+// credentials. Requests that change data with a session must carry its CSRF token,
+// and with Basic Auth a header a form can't set, see simpleRequest.
+func requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			tokenAuth(next, token)(w, r)
+			return
+		}
+
+		if session, ok := sessionFromRequest(r); ok {
+			if r.Method != "GET" && r.Method != "HEAD" && !validCSRF(r, session) {
+				http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), sessionContextKey, session))
+			next(w, withUser(r, session.Username))
+			return
+		}
+
+		if _, _, ok := r.BasicAuth(); ok && basicAuthEnabled {
+			if simpleRequest(r) {
+				http.Error(w, "Changes made with Basic Auth need a Content-Type: application/json or X-Requested-With header", http.StatusForbidden)
+				return
+			}
+			basicAuth(next)(w, r)
+			return
+		}
+
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+	}
+}
+
+// handleLogin shows the login form and starts a session for valid credentials
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Error    string
+		Username string
+	}{}
+
+	switch r.Method {
+	case "GET":
+	case "POST":
+		username := r.PostFormValue("username")
+		password := r.PostFormValue("password")
+		data.Username = username
+
+		var session Session
+		var ok bool
+		err := db.Update(func(tx *bolt.Tx) error {
+			if _, ok = authenticate(tx, username, password); !ok {
+				return nil
+			}
+			var err error
+			session, err = createSession(tx, username, time.Now())
+			return err
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if ok {
+			expires, _ := time.Parse(time.RFC3339, session.Expires)
+			http.SetCookie(w, &http.Cookie{
+				Name:     sessionCookieName,
+				Value:    signSession(session.ID, expires),
+				Path:     "/",
+				Expires:  expires,
+				HttpOnly: true,
+				Secure:   secureCookies,
+				SameSite: http.SameSiteLaxMode,
+			})
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		log.Printf("Failed login for %q from %s", username, r.RemoteAddr)
+		data.Error = "Invalid username or password"
+		w.WriteHeader(http.StatusUnauthorized)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := tmpl.ExecuteTemplate(w, "login.html", data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleLogout ends the current session and clears the cookie
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if session, ok := requestSession(r); ok {
+		err := db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte("Sessions")).Delete([]byte(session.ID))
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
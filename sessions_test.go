@@ -0,0 +1,270 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestSignSession(t *testing.T) {
+	origSecret := sessionSecret
+	sessionSecret = []byte("test secret")
+	defer func() { sessionSecret = origSecret }()
+
+	now := time.Now()
+	value := signSession("abc", now.Add(time.Hour))
+
+	if id, ok := verifySessionCookie(value, now); !ok || id != "abc" {
+		t.Errorf("Expected valid cookie for abc, got %q, %v", id, ok)
+	}
+	if _, ok := verifySessionCookie(value, now.Add(2*time.Hour)); ok {
+		t.Errorf("Expected expired cookie to be rejected")
+	}
+
+	// Changing the ID or the expiry breaks the signature
+	parts := strings.Split(value, ".")
+	for _, tampered := range []string{
+		"abd." + parts[1] + "." + parts[2],
+		parts[0] + ".9999999999." + parts[2],
+		parts[0] + "." + parts[1],
+	} {
+		if _, ok := verifySessionCookie(tampered, now); ok {
+			t.Errorf("Expected tampered cookie %q to be rejected", tampered)
+		}
+	}
+
+	sessionSecret = []byte("other secret")
+	if _, ok := verifySessionCookie(value, now); ok {
+		t.Errorf("Expected cookie signed with another secret to be rejected")
+	}
+}
+
+// login posts the login form and returns the session cookie
+func login(t *testing.T, username, password string) *http.Cookie {
+	t.Helper()
+	form := url.Values{"username": {username}, "password": {password}}
+	req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handleLogin(w, req)
+
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == sessionCookieName {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestSessionLogin(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db, template and secret
+	origDB := db
+	origTmpl := tmpl
+	origSecret := sessionSecret
+	db = testDB
+	sessionSecret = []byte("test secret")
+	defer func() {
+		db = origDB
+		tmpl = origTmpl
+		sessionSecret = origSecret
+	}()
+
+	var err error
+	tmpl, err = template.ParseGlob("templates/*.html")
+	if err != nil {
+		t.Skipf("Skipping test as templates not available: %v", err)
+	}
+
+	// Test 1: Wrong password shows the form again
+	if cookie := login(t, testUser, "wrong"); cookie != nil {
+		t.Errorf("Expected no session for wrong password")
+	}
+
+	// Test 2: Valid credentials start a session
+	cookie := login(t, testUser, "admin")
+	if cookie == nil {
+		t.Fatalf("Expected a session cookie")
+	}
+	if !cookie.HttpOnly {
+		t.Errorf("Expected session cookie to be HttpOnly")
+	}
+
+	var session Session
+	testDB.View(func(tx *bolt.Tx) error {
+		id, _ := verifySessionCookie(cookie.Value, time.Now())
+		session, _ = getSession(tx, id, time.Now())
+		return nil
+	})
+	if session.Username != testUser || session.CSRFToken == "" {
+		t.Fatalf("Expected stored session for %s, got %+v", testUser, session)
+	}
+
+	handler := requireAuth(handleTodayComplete)
+
+	// Test 3: Changes with the session need the CSRF token
+	req := httptest.NewRequest("POST", "/api/today/complete", nil)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 without CSRF token, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/today/complete", nil)
+	req.AddCookie(cookie)
+	req.Header.Set("X-CSRF-Token", session.CSRFToken)
+	w = httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with CSRF token, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Test 4: The page gets the CSRF token
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+
+	requireAuth(handleIndex)(w, req)
+
+	if !strings.Contains(w.Body.String(), session.CSRFToken) {
+		t.Errorf("Expected index page to include the CSRF token")
+	}
+
+	// Test 5: Logging out revokes the session
+	form := url.Values{"csrf_token": {session.CSRFToken}}
+	req = httptest.NewRequest("POST", "/logout", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+
+	requireAuth(handleLogout)(w, req)
+
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Expected redirect after logout, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/today", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+
+	requireAuth(handleToday)(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 after logout, got %d", w.Code)
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db and settings
+	origDB := db
+	origBasicAuth := basicAuthEnabled
+	db = testDB
+	defer func() {
+		db = origDB
+		basicAuthEnabled = origBasicAuth
+	}()
+
+	handler := requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Test 1: Unauthenticated page requests go to the login form
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+		t.Errorf("Expected redirect to /login, got %d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	// Test 2: Basic Auth is off by default
+	req = httptest.NewRequest("POST", "/api/today/reps", strings.NewReader(`{"reps": 5}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(testUser, "admin")
+	w = httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 with Basic Auth disabled, got %d", w.Code)
+	}
+
+	// Test 3: Basic Auth works for scripts when enabled
+	basicAuthEnabled = true
+	w = httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with Basic Auth, got %d", w.Code)
+	}
+
+	// Test 4: A form posted from another site with cached credentials is refused
+	req = httptest.NewRequest("POST", "/api/today/complete", strings.NewReader("x=1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(testUser, "admin")
+	w = httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a cross-site form post, got %d", w.Code)
+	}
+
+	// Test 5: A header a form can't set lets it through
+	req.Header.Set("X-Requested-With", "curl")
+	w = httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with X-Requested-With, got %d", w.Code)
+	}
+}
+
+func TestDeleteUserSessions(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(tx *bolt.Tx) error {
+		now := time.Now()
+		session, err := createSession(tx, testUser, now)
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		// Expired sessions are cleared when a new one starts
+		expired, _ := createSession(tx, testUser, now.Add(-sessionTTL-time.Hour))
+		createSession(tx, testUser, now)
+		if tx.Bucket([]byte("Sessions")).Get([]byte(expired.ID)) != nil {
+			t.Errorf("Expected expired session to be removed")
+		}
+
+		// Changing the password logs the user out
+		if err := setPassword(tx, testUser, "changed"); err != nil {
+			t.Fatalf("Failed to set password: %v", err)
+		}
+		if _, ok := getSession(tx, session.ID, now); ok {
+			t.Errorf("Expected session to be removed after a password change")
+		}
+		return nil
+	})
+}
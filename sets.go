@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// handleDays routes the /api/days/{date}/... endpoints
+func handleDays(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/days/"), "/"), "/")
+
+	date := parts[0]
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if date > requestToday(r) {
+		http.Error(w, "Date is in the future", http.StatusBadRequest)
+		return
+	}
+
+	if len(parts) == 1 {
+		handleDay(w, r, date)
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "audit" {
+		handleDayAudit(w, r, date)
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "rest" {
+		handleDayRest(w, r, date)
+		return
+	}
+
+	if len(parts) >= 2 && parts[1] == "sets" {
+		switch len(parts) {
+		case 2:
+			handleDaySets(w, r, date)
+			return
+		case 3:
+			id, err := strconv.ParseUint(parts[2], 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid set id", http.StatusBadRequest)
+				return
+			}
+			handleDaySet(w, r, date, id)
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+// handleDaySets lists the sets of a day or logs a new one
+func handleDaySets(w http.ResponseWriter, r *http.Request, date string) {
+	var req struct {
+		Reps int    `json:"reps"`
+		Note string `json:"note"`
+	}
+
+	var day tracker.Day
+	var found bool
+	var err error
+	switch r.Method {
+	case "GET":
+		day, found, err = service().Day(requestUser(r), date)
+	case "POST":
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Reps <= 0 || req.Reps > tracker.MaxSetReps {
+			http.Error(w, fmt.Sprintf("reps must be between 1 and %d", tracker.MaxSetReps), http.StatusBadRequest)
+			return
+		}
+		req.Note = strings.TrimSpace(req.Note)
+		if len(req.Note) > tracker.MaxNoteLength {
+			http.Error(w, fmt.Sprintf("note must be at most %d characters", tracker.MaxNoteLength), http.StatusBadRequest)
+			return
+		}
+		day, found, err = service().AddSet(requestUser(r), date, req.Reps, req.Note)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if errors.Is(err, tracker.ErrFutureDate) {
+		http.Error(w, "Date is in the future", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, tracker.ErrOutsideBackfill) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Day not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(day)
+}
+
+// handleDaySet deletes a single set
+func handleDaySet(w http.ResponseWriter, r *http.Request, date string, id uint64) {
+	if r.Method != "DELETE" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	day, found, err := service().DeleteSet(requestUser(r), date, id)
+	if errors.Is(err, tracker.ErrSetNotFound) || (err == nil && !found) {
+		http.Error(w, "Set not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, tracker.ErrFutureDate) {
+		http.Error(w, "Date is in the future", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, tracker.ErrOutsideBackfill) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(day)
+}
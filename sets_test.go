@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestHandleDaySets(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	today := time.Now().Format("2006-01-02")
+
+	dayData := tracker.DayData{
+		Date:  today,
+		Count: 30,
+		Done:  false,
+	}
+	jsonData, _ := json.Marshal(dayData)
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		b := tx.Bucket([]byte("Days"))
+		return b.Put([]byte(today), jsonData)
+	})
+	if err != nil {
+		t.Fatalf("Failed to add test data: %v", err)
+	}
+
+	// Test 1: Log two sets
+	for _, body := range []string{`{"reps": 10, "note": "morning"}`, `{"reps": 8}`} {
+		req := httptest.NewRequest("POST", "/api/days/"+today+"/sets", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handleDays(w, asTestUser(req))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// Test 2: List sets
+	req := httptest.NewRequest("GET", "/api/days/"+today+"/sets", nil)
+	w := httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	var response tracker.Day
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Sets) != 2 {
+		t.Fatalf("Expected 2 sets, got %d", len(response.Sets))
+	}
+	if response.Sets[0].Reps != 10 || response.Sets[0].Note != "morning" {
+		t.Errorf("Unexpected first set: %+v", response.Sets[0])
+	}
+	if response.Sets[0].Time == "" {
+		t.Errorf("Expected set timestamp to be recorded")
+	}
+	if response.Reps != 18 {
+		t.Errorf("Expected reps 18, got %d", response.Reps)
+	}
+	if response.Remaining != 12 {
+		t.Errorf("Expected remaining 12, got %d", response.Remaining)
+	}
+
+	// Test 3: Delete the first set
+	req = httptest.NewRequest("DELETE", "/api/days/"+today+"/sets/"+"1", nil)
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Sets) != 1 || response.Reps != 8 {
+		t.Errorf("Expected 1 set with 8 reps after delete, got %d sets with %d reps", len(response.Sets), response.Reps)
+	}
+
+	// Test 4: Deleting a missing set
+	req = httptest.NewRequest("DELETE", "/api/days/"+today+"/sets/"+"1", nil)
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for missing set, got %d", w.Code)
+	}
+
+	// Test 5: Past day without a record
+	req = httptest.NewRequest("GET", "/api/days/2000-01-01/sets", nil)
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown day, got %d", w.Code)
+	}
+
+	// Test 6: Invalid and future dates
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	for _, path := range []string{"/api/days/not-a-date/sets", "/api/days/" + tomorrow + "/sets"} {
+		req = httptest.NewRequest("GET", path, nil)
+		w = httptest.NewRecorder()
+
+		handleDays(w, asTestUser(req))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", path, w.Code)
+		}
+	}
+
+	// Test 7: Invalid note
+	body := `{"reps": 5, "note": "` + strings.Repeat("x", tracker.MaxNoteLength+1) + `"}`
+	req = httptest.NewRequest("POST", "/api/days/"+today+"/sets", strings.NewReader(body))
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for long note, got %d", w.Code)
+	}
+
+	// Test 8: Days before the backfill window can't be changed
+	old := time.Now().AddDate(0, 0, -30).Format("2006-01-02")
+	testDB.Update(func(root *bolt.Tx) error {
+		return testTx(root).PutDay(tracker.DayData{Date: old, Count: 10})
+	})
+	req = httptest.NewRequest("POST", "/api/days/"+old+"/sets", strings.NewReader(`{"reps": 10}`))
+	w = httptest.NewRecorder()
+
+	handleDays(w, asTestUser(req))
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for an old day, got %d", w.Code)
+	}
+}
+
+func TestHandleTodayIncludesSets(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	today := time.Now().Format("2006-01-02")
+
+	dayData := tracker.DayData{
+		Date:  today,
+		Count: 20,
+		Done:  false,
+	}
+	err := testDB.Update(func(root *bolt.Tx) error {
+		return testTx(root).PutDay(dayData)
+	})
+	if err == nil {
+		_, _, err = service().AddSet(testUser, today, 5, "")
+	}
+	if err != nil {
+		t.Fatalf("Failed to record set: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/today", nil)
+	w := httptest.NewRecorder()
+
+	handleToday(w, asTestUser(req))
+
+	var response tracker.Day
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Sets) != 1 {
+		t.Errorf("Expected 1 set, got %d", len(response.Sets))
+	}
+	if response.Remaining != 15 {
+		t.Errorf("Expected remaining 15, got %d", response.Remaining)
+	}
+
+	// Completing the day logs the remaining reps as a final set
+	req = httptest.NewRequest("POST", "/api/today/complete", nil)
+	w = httptest.NewRecorder()
+
+	handleTodayComplete(w, asTestUser(req))
+
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if !response.Done {
+		t.Errorf("Expected day to be done")
+	}
+	if len(response.Sets) != 2 || response.Sets[1].Reps != 15 {
+		t.Errorf("Expected a final set of 15 reps, got %+v", response.Sets)
+	}
+	if response.Reps != 20 || response.Remaining != 0 {
+		t.Errorf("Expected 20 reps and nothing remaining, got %d and %d", response.Reps, response.Remaining)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// envSettings are the defaults used for users who haven't saved their own settings.
+// Values saved with PUT /api/settings are stored per user and take precedence.
+var envSettings = tracker.DefaultSettings
+
+// settingsFromEnv reads START_TARGET, MAX_TARGET, TARGET_TIERS, DELOAD_AFTER,
+// DELOAD_PERCENT, REST_DAYS, FREEZE_EVERY, FREEZE_CAP, FREEZE_AUTO, BACKFILL_DAYS,
+// TIMEZONE and DAY_START_HOUR on top of the defaults. TARGET_TIERS is a comma-separated
+// list of below:increment:every triples, e.g. "50:2:1,100:1:1,200:1:2", and REST_DAYS
+// a comma-separated list of weekdays, e.g. "saturday,sunday".
+func settingsFromEnv() (tracker.Settings, error) {
+	settings := envSettings
+
+	ints := []struct {
+		key   string
+		value *int
+	}{
+		{"START_TARGET", &settings.StartTarget},
+		{"MAX_TARGET", &settings.MaxTarget},
+		{"DELOAD_AFTER", &settings.DeloadAfter},
+		{"DELOAD_PERCENT", &settings.DeloadPercent},
+		{"FREEZE_EVERY", &settings.FreezeEvery},
+		{"FREEZE_CAP", &settings.FreezeCap},
+		{"BACKFILL_DAYS", &settings.BackfillDays},
+		{"DAY_START_HOUR", &settings.DayStartHour},
+	}
+	for _, i := range ints {
+		value := os.Getenv(i.key)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return settings, fmt.Errorf("%s: %v", i.key, err)
+		}
+		*i.value = n
+	}
+
+	if value := os.Getenv("TARGET_TIERS"); value != "" {
+		tiers, err := tracker.ParseTiers(value)
+		if err != nil {
+			return settings, fmt.Errorf("TARGET_TIERS: %v", err)
+		}
+		settings.Tiers = tiers
+	}
+
+	if value := os.Getenv("FREEZE_AUTO"); value != "" {
+		auto, err := strconv.ParseBool(value)
+		if err != nil {
+			return settings, fmt.Errorf("FREEZE_AUTO: %v", err)
+		}
+		settings.FreezeAuto = auto
+	}
+
+	if value := os.Getenv("TIMEZONE"); value != "" {
+		settings.Timezone = value
+	}
+
+	if value := os.Getenv("REST_DAYS"); value != "" {
+		settings.RestDays = nil
+		for _, day := range strings.Split(value, ",") {
+			settings.RestDays = append(settings.RestDays, strings.ToLower(strings.TrimSpace(day)))
+		}
+	}
+
+	return settings, settings.Validate()
+}
+
+func handleSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "PUT" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	settings, err := service().Settings(requestUser(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == "PUT" {
+		// Fields missing from the body keep their current values
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := settings.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := service().SaveSettings(requestUser(r), settings); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestSettingsFromEnv(t *testing.T) {
+	for _, key := range []string{"START_TARGET", "MAX_TARGET", "TARGET_TIERS"} {
+		orig, ok := os.LookupEnv(key)
+		defer func(key, orig string, ok bool) {
+			if ok {
+				os.Setenv(key, orig)
+			} else {
+				os.Unsetenv(key)
+			}
+		}(key, orig, ok)
+	}
+
+	// Test 1: Defaults when nothing is set
+	os.Unsetenv("START_TARGET")
+	os.Unsetenv("MAX_TARGET")
+	os.Unsetenv("TARGET_TIERS")
+
+	settings, err := settingsFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if settings.StartTarget != 10 || settings.MaxTarget != 200 || len(settings.Tiers) != 3 {
+		t.Errorf("Expected default settings, got %+v", settings)
+	}
+
+	// Test 2: Overrides
+	os.Setenv("START_TARGET", "30")
+	os.Setenv("MAX_TARGET", "300")
+	os.Setenv("TARGET_TIERS", "100:3:1, 300:1:2")
+
+	settings, err = settingsFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if settings.StartTarget != 30 || settings.MaxTarget != 300 {
+		t.Errorf("Expected start 30 and max 300, got %+v", settings)
+	}
+	if len(settings.Tiers) != 2 || settings.Tiers[1] != (tracker.ProgressionStep{Below: 300, Increment: 1, Every: 2}) {
+		t.Errorf("Unexpected tiers: %+v", settings.Tiers)
+	}
+
+	// Test 3: Invalid values
+	for key, value := range map[string]string{"START_TARGET": "abc", "MAX_TARGET": "-1", "TARGET_TIERS": "50:2"} {
+		orig := os.Getenv(key)
+		os.Setenv(key, value)
+		if _, err := settingsFromEnv(); err == nil {
+			t.Errorf("Expected error for %s=%s", key, value)
+		}
+		os.Setenv(key, orig)
+	}
+}
+
+func TestHandleSettings(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	// Test 1: Defaults
+	req := httptest.NewRequest("GET", "/api/settings", nil)
+	w := httptest.NewRecorder()
+
+	handleSettings(w, asTestUser(req))
+
+	var settings tracker.Settings
+	json.Unmarshal(w.Body.Bytes(), &settings)
+	if settings.StartTarget != 10 || settings.MaxTarget != 200 {
+		t.Errorf("Expected default settings, got %+v", settings)
+	}
+
+	// Test 2: Partial update keeps other fields
+	req = httptest.NewRequest("PUT", "/api/settings", strings.NewReader(`{"startTarget": 30}`))
+	w = httptest.NewRecorder()
+
+	handleSettings(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	json.Unmarshal(w.Body.Bytes(), &settings)
+	if settings.StartTarget != 30 || settings.MaxTarget != 200 || len(settings.Tiers) != 3 {
+		t.Errorf("Unexpected settings after update: %+v", settings)
+	}
+
+	// Test 3: Invalid settings are rejected
+	req = httptest.NewRequest("PUT", "/api/settings", strings.NewReader(`{"maxTarget": 20}`))
+	w = httptest.NewRecorder()
+
+	handleSettings(w, asTestUser(req))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	// Test 4: First day starts at the configured target
+	req = httptest.NewRequest("GET", "/api/today", nil)
+	w = httptest.NewRecorder()
+
+	handleToday(w, asTestUser(req))
+
+	var today tracker.DayData
+	json.Unmarshal(w.Body.Bytes(), &today)
+	if today.Count != 30 {
+		t.Errorf("Expected first day target 30, got %d", today.Count)
+	}
+
+	// Test 5: Wrong method
+	req = httptest.NewRequest("POST", "/api/settings", nil)
+	w = httptest.NewRecorder()
+
+	handleSettings(w, asTestUser(req))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
+
+func TestSettingsFromEnvTimezone(t *testing.T) {
+	t.Setenv("TIMEZONE", "Asia/Tashkent")
+	t.Setenv("DAY_START_HOUR", "3")
+
+	settings, err := settingsFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if settings.Timezone != "Asia/Tashkent" || settings.DayStartHour != 3 {
+		t.Errorf("Expected time zone settings from the environment, got %+v", settings)
+	}
+}
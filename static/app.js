@@ -2,15 +2,27 @@ document.addEventListener('DOMContentLoaded', function() {
     let todayData = null;
     let streakData = null;
     let calendarData = null;
+    let leaderboardData = null;
+    let statsData = null;
     let showPreviousMonths = false;
 
+    // Changes made through a login session must carry its CSRF token
+    const csrfToken = document.querySelector('meta[name="csrf-token"]').content;
+
     // Load initial data
     loadTodayData();
     loadStreakData();
     loadCalendarData();
+    loadLeaderboardData();
+    loadStatsData();
 
     // Set up event listeners
     document.getElementById('completeBtn').addEventListener('click', completeToday);
+    document.getElementById('repsForm').addEventListener('submit', addReps);
+    document.getElementById('leaderboardDays').addEventListener('change', loadLeaderboardData);
+    document.getElementById('leaderboardSort').addEventListener('change', loadLeaderboardData);
+    document.getElementById('leaderboardOptOut').addEventListener('click', toggleLeaderboardOptOut);
+    document.getElementById('statsDays').addEventListener('change', loadStatsData);
     
     // Add toggle button event listener after calendar is loaded
     setTimeout(() => {
@@ -22,7 +34,7 @@ document.addEventListener('DOMContentLoaded', function() {
 
     async function loadTodayData() {
         try {
-            const response = await fetch('/api/today');
+            const response = await apiFetch('/api/today');
             todayData = await response.json();
             updateTodayUI();
         } catch (error) {
@@ -32,7 +44,7 @@ document.addEventListener('DOMContentLoaded', function() {
 
     async function loadStreakData() {
         try {
-            const response = await fetch('/api/streak');
+            const response = await apiFetch('/api/streak');
             streakData = await response.json();
             updateStreakUI();
         } catch (error) {
@@ -42,8 +54,8 @@ document.addEventListener('DOMContentLoaded', function() {
 
     async function loadCalendarData() {
         try {
-            const currentYear = new Date().getFullYear();
-            const response = await fetch(`/api/calendar?year=${currentYear}`);
+            // The server picks the current year in the user's time zone
+            const response = await apiFetch('/api/calendar');
             calendarData = await response.json();
             updateCalendarUI();
         } catch (error) {
@@ -51,6 +63,156 @@ document.addEventListener('DOMContentLoaded', function() {
         }
     }
 
+    async function apiFetch(url, options = {}) {
+        const headers = Object.assign({}, options.headers);
+        if (options.method && options.method !== 'GET' && csrfToken) {
+            headers['X-CSRF-Token'] = csrfToken;
+        }
+
+        const response = await fetch(url, Object.assign({}, options, { headers }));
+        if (response.status === 401) {
+            // The session has expired
+            window.location.href = '/login';
+        }
+        return response;
+    }
+
+    function leaderboardQuery() {
+        const days = document.getElementById('leaderboardDays').value;
+        const sort = document.getElementById('leaderboardSort').value;
+        return `days=${days}&sort=${sort}`;
+    }
+
+    async function loadLeaderboardData() {
+        try {
+            const response = await apiFetch(`/api/leaderboard?${leaderboardQuery()}`);
+            leaderboardData = await response.json();
+            updateLeaderboardUI();
+        } catch (error) {
+            console.error('Error loading leaderboard:', error);
+        }
+    }
+
+    async function toggleLeaderboardOptOut() {
+        if (!leaderboardData) return;
+
+        try {
+            const response = await apiFetch(`/api/leaderboard?${leaderboardQuery()}`, {
+                method: 'PUT',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ optOut: !leaderboardData.optOut })
+            });
+
+            if (response.ok) {
+                leaderboardData = await response.json();
+                updateLeaderboardUI();
+            } else {
+                console.error('Error updating leaderboard visibility:', await response.text());
+            }
+        } catch (error) {
+            console.error('Error updating leaderboard visibility:', error);
+        }
+    }
+
+    function updateLeaderboardUI() {
+        if (!leaderboardData) return;
+
+        const body = document.getElementById('leaderboardBody');
+        body.innerHTML = '';
+
+        for (const entry of leaderboardData.entries || []) {
+            const row = document.createElement('tr');
+            const cells = [entry.rank, entry.username, entry.currentStreak, entry.longestStreak, entry.totalReps, `${entry.completionRate}%`];
+            for (const value of cells) {
+                const cell = document.createElement('td');
+                cell.textContent = value;
+                row.appendChild(cell);
+            }
+            body.appendChild(row);
+        }
+
+        document.getElementById('leaderboardOptOut').textContent = leaderboardData.optOut ? 'SHOW ME' : 'HIDE ME';
+    }
+
+    function statsQuery() {
+        const days = parseInt(document.getElementById('statsDays').value, 10);
+        if (!days || !todayData) return '';
+
+        // The range ends on the user's current date, which today's data carries
+        const from = new Date(`${todayData.date}T00:00:00Z`);
+        from.setUTCDate(from.getUTCDate() - (days - 1));
+        return `?from=${from.toISOString().slice(0, 10)}`;
+    }
+
+    async function loadStatsData() {
+        try {
+            const response = await apiFetch(`/api/stats${statsQuery()}`);
+            statsData = await response.json();
+            updateStatsUI();
+        } catch (error) {
+            console.error('Error loading statistics:', error);
+        }
+    }
+
+    function updateStatsUI() {
+        if (!statsData) return;
+
+        document.getElementById('statsTotalReps').textContent = statsData.totalReps;
+        document.getElementById('statsCompletedDays').textContent = `${statsData.completedDays} / ${statsData.trackedDays}`;
+        document.getElementById('statsCompletionRate').textContent = `${statsData.completionRate}%`;
+        document.getElementById('statsAverageReps').textContent = statsData.averageReps;
+
+        const best = [['statsBestWeek', statsData.bestWeek], ['statsBestMonth', statsData.bestMonth]];
+        for (const [id, period] of best) {
+            document.getElementById(id).textContent = period ? `${period.reps} REPS` : '-';
+            document.getElementById(`${id}Period`).textContent = period ? `${period.start} – ${period.end}` : '';
+        }
+
+        const weekdays = document.getElementById('statsWeekdays');
+        weekdays.innerHTML = '';
+        for (const weekday of statsData.weekdays) {
+            const row = document.createElement('div');
+            row.className = 'statistics-weekday';
+            row.innerHTML = `<span>${weekday.weekday.slice(0, 3).toUpperCase()}</span>` +
+                `<div class="statistics-bar"><div class="statistics-bar-fill" style="width: ${weekday.completionRate}%"></div></div>` +
+                `<span>${weekday.completionRate}%</span>`;
+            weekdays.appendChild(row);
+        }
+
+        updateTargetChart(statsData.targets, statsData.from, statsData.to);
+    }
+
+    // updateTargetChart draws the target as steps from the first date to the last
+    function updateTargetChart(targets, from, to) {
+        const chart = document.getElementById('statsTargets');
+        const range = document.getElementById('statsTargetRange');
+        chart.innerHTML = '';
+        range.textContent = '';
+        if (targets.length === 0) return;
+
+        const width = 300, height = 120, margin = 10;
+        const start = Date.parse(from);
+        const span = Math.max(Date.parse(to) - start, 1);
+        const values = targets.map(change => change.target);
+        const min = Math.min(...values), max = Math.max(...values);
+        const x = date => (Date.parse(date) - start) / span * width;
+        const y = target => height - margin - (max === min ? 0.5 : (target - min) / (max - min)) * (height - 2 * margin);
+
+        const points = [];
+        targets.forEach((change, i) => {
+            if (i > 0) points.push(`${x(change.date)},${y(targets[i - 1].target)}`);
+            points.push(`${x(change.date)},${y(change.target)}`);
+        });
+        points.push(`${width},${y(values[values.length - 1])}`);
+
+        const line = document.createElementNS('http://www.w3.org/2000/svg', 'polyline');
+        line.setAttribute('points', points.join(' '));
+        chart.appendChild(line);
+        range.textContent = `${values[0]} → ${values[values.length - 1]} REPS, ${min}–${max}`;
+    }
+
     function updateTodayUI() {
         if (!todayData) return;
 
@@ -62,26 +224,86 @@ document.addEventListener('DOMContentLoaded', function() {
         todayCount.textContent = todayData.count;
         headerToday.textContent = todayData.count;
 
+        const reps = todayData.reps || 0;
+        const percent = todayData.count > 0 ? Math.min(100, Math.round(reps / todayData.count * 100)) : 0;
+        document.getElementById('repsProgress').style.width = `${percent}%`;
+        document.getElementById('repsText').textContent = `${reps} / ${todayData.count} REPS`;
+
+        updateSetsUI();
+
         if (todayData.done) {
             todayStatus.innerHTML = '<span class="status-dot"></span><span class="status-text">Completed!</span>';
             todayStatus.classList.add('completed');
-            completeBtn.disabled = true;
             completeBtn.classList.add('completed');
+            completeBtn.title = 'Undo today\'s completion';
             completeBtn.innerHTML = '<span class="btn-text">COMPLETED</span><span class="btn-icon"><svg width="20" height="20" viewBox="0 0 20 20" fill="none" xmlns="http://www.w3.org/2000/svg"><path d="M3 10L8 15L17 6" stroke="currentColor" stroke-width="2.5" stroke-linecap="round" stroke-linejoin="round"/></svg></span>';
         } else {
-            todayStatus.innerHTML = '<span class="status-dot"></span><span class="status-text">Not completed</span>';
+            const statusText = {rest: 'Rest day', sick: 'Sick day'}[todayData.status] || 'Not completed';
+            todayStatus.innerHTML = `<span class="status-dot"></span><span class="status-text">${statusText}</span>`;
             todayStatus.classList.remove('completed');
-            completeBtn.disabled = false;
             completeBtn.classList.remove('completed');
+            completeBtn.title = '';
             completeBtn.innerHTML = '<span class="btn-text">COMPLETE WORKOUT</span><span class="btn-icon"><svg width="20" height="20" viewBox="0 0 20 20" fill="none" xmlns="http://www.w3.org/2000/svg"><path d="M3 10L8 15L17 6" stroke="currentColor" stroke-width="2.5" stroke-linecap="round" stroke-linejoin="round"/></svg></span>';
         }
     }
 
+    function updateSetsUI() {
+        const setsList = document.getElementById('setsList');
+        setsList.innerHTML = '';
+
+        for (const set of todayData.sets || []) {
+            const time = new Date(set.time).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+
+            const item = document.createElement('li');
+            item.className = 'set-item';
+            item.title = set.note || '';
+            item.innerHTML = `<span class="set-reps">${set.reps}</span><span class="set-time">${time}</span>`;
+
+            const deleteBtn = document.createElement('button');
+            deleteBtn.className = 'set-delete';
+            deleteBtn.textContent = '×';
+            deleteBtn.title = 'Delete set';
+            deleteBtn.addEventListener('click', () => deleteSet(set.id));
+            item.appendChild(deleteBtn);
+
+            setsList.appendChild(item);
+        }
+    }
+
+    async function deleteSet(id) {
+        const wasDone = todayData.done;
+
+        try {
+            const response = await apiFetch(`/api/days/${todayData.date}/sets/${id}`, {
+                method: 'DELETE'
+            });
+
+            if (response.ok) {
+                todayData = await response.json();
+                updateTodayUI();
+                if (wasDone !== todayData.done) {
+                    loadStreakData();
+                    loadCalendarData();
+                    loadLeaderboardData();
+                }
+                loadStatsData();
+            } else {
+                console.error('Error deleting set:', await response.text());
+            }
+        } catch (error) {
+            console.error('Error deleting set:', error);
+        }
+    }
+
     function updateStreakUI() {
         if (!streakData) return;
 
         document.getElementById('currentStreak').textContent = streakData.current || 0;
         document.getElementById('longestStreak').textContent = streakData.longest || 0;
+
+        const freezes = streakData.freezes || 0;
+        document.getElementById('currentStreakUnit').textContent =
+            freezes > 0 ? `DAYS · ${freezes} FREEZE${freezes === 1 ? '' : 'S'}` : 'DAYS';
     }
 
     function updateCalendarUI() {
@@ -134,10 +356,9 @@ document.addEventListener('DOMContentLoaded', function() {
         const dayHeaders = ['Sun', 'Mon', 'Tue', 'Wed', 'Thu', 'Fri', 'Sat'];
         
         let html = '<div class="calendar-grid-container">';
-        const today = new Date();
-        // Get today's date string in local timezone to match backend
-        const todayStr = `${today.getFullYear()}-${String(today.getMonth() + 1).padStart(2, '0')}-${String(today.getDate()).padStart(2, '0')}`;
-        const currentMonth = today.getMonth();
+        // Today comes from the server, which counts days in the user's time zone
+        const todayStr = data.today;
+        const currentMonth = Number(todayStr.slice(5, 7)) - 1;
         
         // Start from the first record month
         const startMonth = data.startMonth || 0;
@@ -180,12 +401,17 @@ document.addEventListener('DOMContentLoaded', function() {
                 
                 const dayData = data.days[dateStr];
                 const isCompleted = dayData && dayData.done;
+                // Days off are marked on the day, or come from the weekly schedule
+                const restStatus = dayData ? dayData.status :
+                    ((data.restDays || []).includes(date.getDay()) ? 'rest' : '');
                 
                 let classes = ['calendar-day'];
                 if (isToday) classes.push('today');
                 if (isCompleted) classes.push('completed');
+                else if (restStatus) classes.push(restStatus);
                 
-                html += `<div class="${classes.join(' ')}">`;
+                const title = restStatus && !isCompleted ? ` title="${restStatus === 'sick' ? 'Sick day' : 'Rest day'}"` : '';
+                html += `<div class="${classes.join(' ')}"${title}>`;
                 html += `<span class="day-number">${day}</span>`;
                 html += '</div>';
             }
@@ -198,12 +424,52 @@ document.addEventListener('DOMContentLoaded', function() {
         return html;
     }
 
-    async function completeToday() {
-        if (!todayData || todayData.done) return;
+    async function addReps(event) {
+        event.preventDefault();
+
+        const repsInput = document.getElementById('repsInput');
+        const reps = parseInt(repsInput.value, 10);
+        if (!reps || reps <= 0) return;
+
+        const wasDone = todayData && todayData.done;
 
         try {
-            const response = await fetch('/api/today/complete', {
+            const response = await apiFetch('/api/today/reps', {
                 method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ reps: reps })
+            });
+
+            if (response.ok) {
+                todayData = await response.json();
+                repsInput.value = '';
+                updateTodayUI();
+                if (!wasDone && todayData.done) {
+                    // Reaching the target completes the day
+                    loadStreakData();
+                    loadCalendarData();
+                }
+                loadLeaderboardData();
+                loadStatsData();
+            } else {
+                console.error('Error adding reps:', await response.text());
+            }
+        } catch (error) {
+            console.error('Error adding reps:', error);
+        }
+    }
+
+    async function completeToday() {
+        if (!todayData) return;
+        // A second tap on a completed day undoes it, e.g. after an accidental tap
+        const undo = todayData.done;
+        if (undo && !confirm('Undo today\'s completion?')) return;
+
+        try {
+            const response = await apiFetch('/api/today/complete', {
+                method: undo ? 'DELETE' : 'POST',
                 headers: {
                     'Content-Type': 'application/json',
                 }
@@ -214,8 +480,13 @@ document.addEventListener('DOMContentLoaded', function() {
                 updateTodayUI();
                 // Reload streak data as it may have changed
                 loadStreakData();
-                // Reload calendar to show today as completed
+                // Reload calendar to show today as completed, or open again
                 loadCalendarData();
+                loadLeaderboardData();
+                loadStatsData();
+            } else if (response.status === 409) {
+                // The logged sets reach the target on their own
+                alert(await response.text());
             } else {
                 console.error('Error completing today\'s push-ups');
             }
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// handleStats sums up the user's days from the from date, by default the first
+// record, to the to date, by default today
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	for _, date := range []string{from, to} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			http.Error(w, fmt.Sprintf("Invalid date %q, expected YYYY-MM-DD", date), http.StatusBadRequest)
+			return
+		}
+	}
+	if from != "" && to != "" && from > to {
+		http.Error(w, "from must not be after to", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := service().Stats(requestUser(r), from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
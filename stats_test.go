@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestHandleStats(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db and clock
+	origDB, origClock := db, clock
+	db = testDB
+	defer func() { db, clock = origDB, origClock }()
+	fake := &tracker.OffsetClock{}
+	fake.Set(time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local))
+	clock = fake
+
+	seedExportData(t, testDB)
+
+	// Test 1: Everything up to today
+	req := httptest.NewRequest("GET", "/api/stats", nil)
+	w := httptest.NewRecorder()
+
+	handleStats(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var stats tracker.Stats
+	json.NewDecoder(w.Body).Decode(&stats)
+	if stats.From != "2024-03-08" || stats.To != "2024-03-10" || stats.TotalReps != 14 || stats.CompletedDays != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if stats.BestWeek == nil || stats.BestWeek.Start != "2024-03-04" || len(stats.Targets) != 2 {
+		t.Errorf("Expected the best week and two targets, got %+v and %v", stats.BestWeek, stats.Targets)
+	}
+
+	// Test 2: A range of one day
+	req = httptest.NewRequest("GET", "/api/stats?from=2024-03-09&to=2024-03-09", nil)
+	w = httptest.NewRecorder()
+
+	handleStats(w, asTestUser(req))
+
+	stats = tracker.Stats{}
+	json.NewDecoder(w.Body).Decode(&stats)
+	if stats.TotalReps != 4 || stats.TrackedDays != 1 || stats.CompletionRate != 0 {
+		t.Errorf("Expected one missed day, got %+v", stats)
+	}
+
+	// Test 3: Invalid ranges and methods
+	for _, query := range []string{"?from=March", "?to=2024-3-1", "?from=2024-03-09&to=2024-03-08"} {
+		req = httptest.NewRequest("GET", "/api/stats"+query, nil)
+		w = httptest.NewRecorder()
+
+		handleStats(w, asTestUser(req))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+
+	req = httptest.NewRequest("POST", "/api/stats", nil)
+	w = httptest.NewRecorder()
+
+	handleStats(w, asTestUser(req))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
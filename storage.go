@@ -0,0 +1,252 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+var (
+	// dataStore keeps the tracker data, nil for the accounts database itself
+	dataStore tracker.Store
+	// closeDataStore closes dataStore
+	closeDataStore = func() error { return nil }
+)
+
+// dataFiles are the default files of each storage driver, next to pushups.db
+var dataFiles = map[string]string{
+	"bolt":   "pushups.db",
+	"sqlite": "pushups.sqlite",
+}
+
+// trackerStore returns the store of the tracker data
+func trackerStore() tracker.Store {
+	if dataStore != nil {
+		return dataStore
+	}
+	return tracker.NewBoltStore(db)
+}
+
+// dataPath returns the file of a storage driver, the default one for an empty path.
+// A relative path is taken from the directory of pushups.db.
+func dataPath(driver, path string) string {
+	if path == "" {
+		path = dataFiles[driver]
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(db.Path()), path)
+	}
+	return filepath.Clean(path)
+}
+
+// storageFromEnv returns the driver and file of the tracker data from DB_DRIVER
+// and DB_PATH
+func storageFromEnv() (driver, path string, err error) {
+	driver = os.Getenv("DB_DRIVER")
+	if driver == "" {
+		driver = "bolt"
+	}
+	if _, ok := dataFiles[driver]; !ok {
+		return "", "", fmt.Errorf("DB_DRIVER must be bolt or sqlite, got %q", driver)
+	}
+	return driver, dataPath(driver, os.Getenv("DB_PATH")), nil
+}
+
+// openStore opens the tracker data of a driver at path. A BoltDB store in
+// pushups.db shares the open accounts database, close does nothing for it. A
+// separate BoltDB file is opened read-only when pushups.db is.
+func openStore(driver, path string) (store tracker.Store, close func() error, err error) {
+	switch driver {
+	case "bolt":
+		if path == filepath.Clean(db.Path()) {
+			return tracker.NewBoltStore(db), func() error { return nil }, nil
+		}
+		boltDB, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second, ReadOnly: db.IsReadOnly()})
+		if err != nil {
+			return nil, nil, err
+		}
+		return tracker.NewBoltStore(boltDB), boltDB.Close, nil
+	case "sqlite":
+		sqliteStore, err := tracker.OpenSQLite(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sqliteStore, sqliteStore.DB.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+// syncUserData makes sure every account has its tracker data, e.g. after
+// switching to a new, empty store
+func syncUserData() error {
+	var users []User
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		users, err = listUsers(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := trackerStore().CreateUser(user.Username); err != nil {
+			return fmt.Errorf("%s: %v", user.Username, err)
+		}
+	}
+	return nil
+}
+
+// userMigration is the result of migrating one user's data
+type userMigration struct {
+	Username string
+	tracker.MigrationResult
+}
+
+// migrateSchema brings the data of every user up to the current schema version.
+// Unless it is a dry run, the store is first backed up when a migration is
+// pending, backup is the file written. New users without data are only given
+// the current version, which needs no backup.
+func migrateSchema(dryRun bool) (migrations []userMigration, backup string, err error) {
+	usernames, err := trackerStore().Users()
+	if err != nil {
+		return nil, "", err
+	}
+
+	pending := false
+	for _, username := range usernames {
+		result, err := service().Migrate(username, true)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %v", username, err)
+		}
+		pending = pending || result.From < result.To
+		migrations = append(migrations, userMigration{username, result})
+	}
+	if dryRun {
+		return migrations, "", nil
+	}
+
+	if pending {
+		backup, err = backupStore()
+		if err != nil {
+			return nil, "", fmt.Errorf("backup: %v", err)
+		}
+	}
+	for i := range migrations {
+		result, err := service().Migrate(migrations[i].Username, false)
+		if err != nil {
+			return nil, backup, fmt.Errorf("%s: %v", migrations[i].Username, err)
+		}
+		migrations[i].MigrationResult = result
+	}
+	return migrations, backup, nil
+}
+
+// backupStore writes a copy of the tracker data next to its file, named after the
+// file and the current time
+func backupStore() (string, error) {
+	_, path, err := storageFromEnv()
+	if err != nil {
+		return "", err
+	}
+	backup := fmt.Sprintf("%s.%s.bak", path, time.Now().Format("20060102-150405"))
+
+	f, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	if err := trackerStore().Backup(f); err != nil {
+		f.Close()
+		os.Remove(backup)
+		return "", err
+	}
+	return backup, f.Close()
+}
+
+// printMigrations writes what the migrations of each user changed
+func printMigrations(w io.Writer, migrations []userMigration) {
+	for _, m := range migrations {
+		if len(m.Applied) == 0 {
+			fmt.Fprintf(w, "%s: up to date at schema version %d\n", m.Username, m.To)
+			continue
+		}
+		fmt.Fprintf(w, "%s: schema version %d -> %d\n", m.Username, m.From, m.To)
+		for _, applied := range m.Applied {
+			fmt.Fprintf(w, "  %d. %s: %d changes\n", applied.Version, applied.Name, len(applied.Changes))
+			for _, change := range applied.Changes {
+				fmt.Fprintf(w, "     %s\n", change)
+			}
+		}
+	}
+}
+
+// runMigrate implements `push_up_tracker migrate [-dry-run]`, which brings the data
+// up to the current schema version, and `push_up_tracker migrate -to bolt|sqlite
+// [-path file]`, which copies the tracker data from the store in use to another one
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report the schema migrations without applying them")
+	to := flags.String("to", "", "storage driver to copy the data to, bolt or sqlite")
+	path := flags.String("path", "", "file to copy the data to, the driver's default if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *to == "" {
+		if *path != "" {
+			return fmt.Errorf("-path needs -to")
+		}
+		migrations, backup, err := migrateSchema(*dryRun)
+		if backup != "" {
+			fmt.Printf("Backed up the data to %s\n", backup)
+		}
+		printMigrations(os.Stdout, migrations)
+		if err == nil && *dryRun {
+			fmt.Println("Dry run, nothing was changed")
+		}
+		return err
+	}
+	if *dryRun {
+		return fmt.Errorf("-dry-run only applies to schema migrations, not to -to")
+	}
+	if _, ok := dataFiles[*to]; !ok {
+		return fmt.Errorf("-to must be bolt or sqlite")
+	}
+
+	fromDriver, fromPath, err := storageFromEnv()
+	if err != nil {
+		return err
+	}
+	toPath := dataPath(*to, *path)
+	if toPath == fromPath {
+		return fmt.Errorf("the data is already stored in %s", toPath)
+	}
+
+	dst, closeDst, err := openStore(*to, toPath)
+	if err != nil {
+		return fmt.Errorf("open %s: %v", toPath, err)
+	}
+	usernames, err := tracker.CopyStore(dst, trackerStore())
+	closeErr := closeDst()
+	for _, username := range usernames {
+		log.Printf("Copied the data of %s", username)
+	}
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	fmt.Printf("Copied %d users from %s (%s) to %s (%s), set DB_DRIVER=%s and DB_PATH=%s to use it\n",
+		len(usernames), fromPath, fromDriver, toPath, *to, *to, toPath)
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestStorageFromEnv(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	dir, _ := filepath.Abs(".")
+	tests := []struct {
+		driver, path string
+		expected     string
+		wantErr      bool
+	}{
+		{"", "", filepath.Join(dir, "pushups.db"), false},
+		{"sqlite", "", filepath.Join(dir, "pushups.sqlite"), false},
+		{"sqlite", "data/tracker.sqlite", filepath.Join(dir, "data/tracker.sqlite"), false},
+		{"bolt", "/var/lib/pushups/data.db", "/var/lib/pushups/data.db", false},
+		{"mysql", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Setenv("DB_DRIVER", tt.driver)
+		t.Setenv("DB_PATH", tt.path)
+		_, path, err := storageFromEnv()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expected error for driver %q", tt.driver)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for driver %q: %v", tt.driver, err)
+			continue
+		}
+		// The test database is opened by a relative name
+		if !filepath.IsAbs(path) {
+			path, _ = filepath.Abs(path)
+		}
+		if path != tt.expected {
+			t.Errorf("Expected %s for %q %q, got %s", tt.expected, tt.driver, tt.path, path)
+		}
+	}
+}
+
+func TestRunMigrate(t *testing.T) {
+	if !tracker.SQLiteSupported {
+		t.Skip("SQLite needs cgo")
+	}
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db and store
+	origDB := db
+	db = testDB
+	origStore := dataStore
+	defer func() {
+		db = origDB
+		dataStore = origStore
+	}()
+	t.Setenv("DB_DRIVER", "")
+	t.Setenv("DB_PATH", "")
+
+	testDB.Update(func(root *bolt.Tx) error {
+		return testTx(root).PutDay(tracker.DayData{Date: "2024-03-01", Count: 10, Done: true})
+	})
+
+	// Test 1: Copy the data to SQLite
+	output := filepath.Join(t.TempDir(), "data.sqlite")
+	if err := runCommand([]string{"migrate", "-to", "sqlite", "-path", output}, testUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	store, closeStore, err := openStore("sqlite", output)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", output, err)
+	}
+	defer closeStore()
+	dataStore = store
+
+	day, found, err := service().Day(testUser, "2024-03-01")
+	if err != nil || !found || !day.Done {
+		t.Errorf("Expected the completed day to be copied, got %+v (found %v, error %v)", day, found, err)
+	}
+
+	// Test 2: New accounts get their data in the store in use
+	if _, err := addUser("alice", "secret", false); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if users, _ := store.Users(); len(users) != 2 {
+		t.Errorf("Expected admin and alice in SQLite, got %v", users)
+	}
+	if err := removeUser("alice"); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	if users, _ := store.Users(); len(users) != 1 {
+		t.Errorf("Expected alice to be removed from SQLite, got %v", users)
+	}
+	dataStore = origStore
+
+	// Test 3: Invalid targets
+	for _, args := range [][]string{
+		{"migrate", "-path", "data.db"},
+		{"migrate", "-to", "sqlite", "-dry-run"},
+		{"migrate", "-to", "mysql"},
+		{"migrate", "-to", "bolt", "-path", testDB.Path()},
+	} {
+		if err := runCommand(args, testUser); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+func TestRunMigrateSchema(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+	t.Setenv("DB_DRIVER", "")
+	t.Setenv("DB_PATH", testDB.Path())
+
+	// A day completed before reps were logged
+	testDB.Update(func(root *bolt.Tx) error {
+		return testTx(root).PutDay(tracker.DayData{Date: "2024-03-01", Count: 10, Done: true})
+	})
+	reps := func() int {
+		day, _, _ := service().Day(testUser, "2024-03-01")
+		return day.Reps
+	}
+	backups := func() []string {
+		files, _ := filepath.Glob(testDB.Path() + ".*.bak")
+		return files
+	}
+
+	// Test 1: A dry run changes nothing
+	if err := runCommand([]string{"migrate", "--dry-run"}, testUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reps() != 0 || len(backups()) != 0 {
+		t.Errorf("Expected no changes and no backup, got %d reps and %v", reps(), backups())
+	}
+
+	// Test 2: Migrating backs up the data first
+	if err := runCommand([]string{"migrate"}, testUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	files := backups()
+	for _, file := range files {
+		defer os.Remove(file)
+	}
+	if reps() != 10 {
+		t.Errorf("Expected 10 reps after migrating, got %d", reps())
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected one backup, got %v", files)
+	}
+
+	backupDB, err := bolt.Open(files[0], 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open the backup: %v", err)
+	}
+	defer backupDB.Close()
+	backupDB.View(func(root *bolt.Tx) error {
+		day, _, _ := testTx(root).Day("2024-03-01")
+		if day.Reps != 0 {
+			t.Errorf("Expected the backup to hold the data from before, got %+v", day)
+		}
+		return nil
+	})
+
+	// Test 3: Nothing is left to migrate, so there is no second backup
+	if err := runCommand([]string{"migrate"}, testUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(backups()) != 1 {
+		t.Errorf("Expected no new backup, got %v", backups())
+	}
+}
+
+func TestMigrateSchemaNewUsers(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+	t.Setenv("DB_DRIVER", "")
+	t.Setenv("DB_PATH", testDB.Path())
+
+	backups := func() []string {
+		files, _ := filepath.Glob(testDB.Path() + ".*.bak")
+		return files
+	}
+	defer func() {
+		for _, file := range backups() {
+			os.Remove(file)
+		}
+	}()
+
+	// Test 1: Users without data, e.g. on a fresh install, need no backup
+	migrations, backup, err := migrateSchema(false)
+	if err != nil || backup != "" || len(backups()) != 0 {
+		t.Fatalf("Expected no backup, got %q %v (%v)", backup, backups(), err)
+	}
+	for _, m := range migrations {
+		if len(m.Applied) > 0 {
+			t.Errorf("Expected nothing to migrate, got %+v", m)
+		}
+	}
+
+	// Test 2: A user added later starts at the current version, even with data
+	if _, err := addUser("bob", "bob-password", false); err != nil {
+		t.Fatalf("Failed to add a user: %v", err)
+	}
+	if _, err := service().Complete("bob"); err != nil {
+		t.Fatalf("Failed to complete a day: %v", err)
+	}
+	if _, backup, err := migrateSchema(false); err != nil || backup != "" {
+		t.Errorf("Expected no backup, got %q (%v)", backup, err)
+	}
+}
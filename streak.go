@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+// runRebuildStreaks implements `push_up_tracker rebuild-streaks [-user name]`, which
+// recomputes the stored streak of every user, or of one user, from their history
+func runRebuildStreaks(args []string) error {
+	flags := flag.NewFlagSet("rebuild-streaks", flag.ContinueOnError)
+	username := flags.String("user", "", "only rebuild this user's streak")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	usernames := []string{*username}
+	if *username == "" {
+		var users []User
+		err := db.View(func(tx *bolt.Tx) error {
+			var err error
+			users, err = listUsers(tx)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		usernames = usernames[:0]
+		for _, user := range users {
+			usernames = append(usernames, user.Username)
+		}
+	}
+
+	for _, name := range usernames {
+		before, streak, err := service().RebuildStreak(name)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		fmt.Printf("%s: current %d (was %d), longest %d (was %d)\n",
+			name, streak.Current, before.Current, streak.Longest, before.Longest)
+	}
+	return nil
+}
+
+// handleStreakFreeze uses a streak freeze on a missed day, e.g. {"date": "2024-03-05"}.
+// With freezeAuto on, freezes are used up without asking.
+func handleStreakFreeze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Date string `json:"date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	today := requestToday(r)
+	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if req.Date >= today {
+		http.Error(w, "Only a past day can be frozen", http.StatusBadRequest)
+		return
+	}
+
+	streak, err := service().Freeze(requestUser(r), req.Date)
+	if errors.Is(err, tracker.ErrNoFreeze) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(streak)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestHandleStreakFreeze(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	day := func(offset int) string {
+		return time.Now().AddDate(0, 0, offset).Format("2006-01-02")
+	}
+	settings := envSettings
+	settings.FreezeEvery = 2
+	settings.FreezeAuto = false
+	service().SaveSettings(testUser, settings)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		tx.PutDay(tracker.DayData{Date: day(-4), Count: 10, Done: true})
+		tx.PutDay(tracker.DayData{Date: day(-3), Count: 10, Done: true})
+		return tx.PutDay(tracker.DayData{Date: day(-1), Count: 10, Done: true})
+	})
+
+	// Test 1: Freeze the missed day
+	req := httptest.NewRequest("POST", "/api/streak/freeze", strings.NewReader(`{"date": "`+day(-2)+`"}`))
+	w := httptest.NewRecorder()
+
+	handleStreakFreeze(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var streak tracker.StreakData
+	json.Unmarshal(w.Body.Bytes(), &streak)
+	if streak.Current != 3 || streak.Freezes != 0 || streak.FreezesUsed != 1 {
+		t.Errorf("Expected the freeze to bridge the streak, got %+v", streak)
+	}
+
+	// Test 2: A completed day can't be frozen, and isn't remembered
+	req = httptest.NewRequest("POST", "/api/streak/freeze", strings.NewReader(`{"date": "`+day(-1)+`"}`))
+	w = httptest.NewRecorder()
+
+	handleStreakFreeze(w, asTestUser(req))
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		picked, _ := testTx(root).FreezeDays()
+		if len(picked) != 1 || picked[0] != day(-2) {
+			t.Errorf("Expected only the missed day to be picked, got %v", picked)
+		}
+		return nil
+	})
+
+	// Test 3: Invalid requests
+	for _, tt := range []struct{ method, body string }{
+		{"POST", `{"date": "` + day(0) + `"}`},
+		{"POST", `{"date": "yesterday"}`},
+		{"POST", `not json`},
+		{"GET", ``},
+	} {
+		req = httptest.NewRequest(tt.method, "/api/streak/freeze", strings.NewReader(tt.body))
+		w = httptest.NewRecorder()
+
+		handleStreakFreeze(w, asTestUser(req))
+
+		if w.Code == http.StatusOK {
+			t.Errorf("Expected error for %s %s, got %d", tt.method, tt.body, w.Code)
+		}
+	}
+}
+
+func TestRunRebuildStreaks(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	// A stored streak that doesn't match the history
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testTx(root)
+		tx.PutDay(tracker.DayData{Date: "2024-03-08", Count: 10, Done: true})
+		tx.PutDay(tracker.DayData{Date: "2024-03-09", Count: 10, Done: true})
+		return tx.PutStreak(tracker.StreakData{Current: 40, Longest: 40, LastDate: "2024-03-09"})
+	})
+
+	if err := runCommand([]string{"rebuild-streaks"}, testUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testDB.View(func(root *bolt.Tx) error {
+		streak, _ := testTx(root).Streak()
+		if streak.Longest != 2 || streak.Current != 0 || streak.LastDate != "2024-03-09" {
+			t.Errorf("Expected rebuilt streak, got %+v", streak)
+		}
+		return nil
+	})
+
+	if err := runCommand([]string{"rebuild-streaks", "-user", "nobody"}, testUser); err == nil {
+		t.Errorf("Expected error for unknown user")
+	}
+}
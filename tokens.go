@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// APIToken is a personal token for scripts. Tokens are stored as JSON in the
+// Tokens bucket keyed by the SHA-256 hash of the token, the token itself is only
+// shown once when it is created.
+type APIToken struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	// Scope is "read" for GET requests only, or "write" for everything
+	Scope    string `json:"scope"`
+	Created  string `json:"created"`
+	LastUsed string `json:"lastUsed,omitempty"`
+}
+
+const (
+	// tokenPrefix makes tokens easy to recognise, e.g. in leaked logs
+	tokenPrefix = "put_"
+	// maxTokenNameLength bounds the name given to a token
+	maxTokenNameLength = 100
+	// tokenUseInterval limits how often the last-used time is written
+	tokenUseInterval = time.Minute
+)
+
+// tokenScopes lists the accepted values of APIToken.Scope
+var tokenScopes = []string{"read", "write"}
+
+// tokenContextKey holds the token the request was authenticated with, if any
+const tokenContextKey contextKey = "token"
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return []byte(hex.EncodeToString(sum[:]))
+}
+
+// createToken generates a token for the user and returns it along with its record
+func createToken(tx *bolt.Tx, username, name, scope string) (string, APIToken, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return "", APIToken{}, err
+	}
+	id, err := randomBytes(8)
+	if err != nil {
+		return "", APIToken{}, err
+	}
+
+	token := tokenPrefix + secret
+	record := APIToken{
+		ID:       hex.EncodeToString(id),
+		Username: username,
+		Name:     name,
+		Scope:    scope,
+		Created:  time.Now().Format(time.RFC3339),
+	}
+	return token, record, putToken(tx, hashToken(token), record)
+}
+
+func putToken(tx *bolt.Tx, key []byte, record APIToken) error {
+	jsonData, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket([]byte("Tokens")).Put(key, jsonData)
+}
+
+// getToken returns the record of a token
+func getToken(tx *bolt.Tx, token string) (APIToken, bool) {
+	var record APIToken
+	data := tx.Bucket([]byte("Tokens")).Get(hashToken(token))
+	if data == nil || json.Unmarshal(data, &record) != nil {
+		return APIToken{}, false
+	}
+	return record, true
+}
+
+// listTokens returns the user's tokens
+func listTokens(tx *bolt.Tx, username string) ([]APIToken, error) {
+	tokens := []APIToken{}
+	err := tx.Bucket([]byte("Tokens")).ForEach(func(k, v []byte) error {
+		var record APIToken
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+		if record.Username == username {
+			tokens = append(tokens, record)
+		}
+		return nil
+	})
+	return tokens, err
+}
+
+// deleteTokens removes every token matching the filter and reports whether any were found
+func deleteTokens(tx *bolt.Tx, match func(APIToken) bool) (bool, error) {
+	b := tx.Bucket([]byte("Tokens"))
+
+	var keys [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		var record APIToken
+		if json.Unmarshal(v, &record) != nil || match(record) {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return false, err
+		}
+	}
+	return len(keys) > 0, nil
+}
+
+// authenticateToken checks a bearer token and records when it was last used
+func authenticateToken(token string) (APIToken, bool) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return APIToken{}, false
+	}
+
+	var record APIToken
+	var ok bool
+	db.View(func(tx *bolt.Tx) error {
+		record, ok = getToken(tx, token)
+		return nil
+	})
+	if !ok {
+		return APIToken{}, false
+	}
+
+	now := time.Now()
+	lastUsed, err := time.Parse(time.RFC3339, record.LastUsed)
+	if err != nil || now.Sub(lastUsed) >= tokenUseInterval {
+		// The token is read again, as it may have been revoked since the lookup
+		db.Update(func(tx *bolt.Tx) error {
+			record, ok = getToken(tx, token)
+			if !ok {
+				return nil
+			}
+			record.LastUsed = now.Format(time.RFC3339)
+			return putToken(tx, hashToken(token), record)
+		})
+	}
+	return record, ok
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(header[7:]), true
+}
+
+// tokenAuth serves requests made with an API token, refusing changes for read-only tokens
+func tokenAuth(next http.HandlerFunc, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		record, ok := authenticateToken(token)
+		if !ok {
+			http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+			return
+		}
+		if record.Scope != "write" && r.Method != "GET" && r.Method != "HEAD" {
+			http.Error(w, "Token is read-only", http.StatusForbidden)
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), tokenContextKey, record))
+		next(w, withUser(r, record.Username))
+	}
+}
+
+// handleTokens routes /api/tokens and /api/tokens/{id}. Tokens can only be managed
+// after logging in with a password, not with another token.
+func handleTokens(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.Context().Value(tokenContextKey).(APIToken); ok {
+		http.Error(w, "Tokens cannot be managed with a token", http.StatusForbidden)
+		return
+	}
+
+	username := requestUser(r)
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/tokens"), "/")
+
+	if id != "" {
+		if r.Method != "DELETE" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var found bool
+		err := db.Update(func(tx *bolt.Tx) error {
+			var err error
+			found, err = deleteTokens(tx, func(t APIToken) bool {
+				return t.ID == id && t.Username == username
+			})
+			return err
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, "Token not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		var tokens []APIToken
+		err := db.View(func(tx *bolt.Tx) error {
+			var err error
+			tokens, err = listTokens(tx, username)
+			return err
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+	case "POST":
+		var req struct {
+			Name  string `json:"name"`
+			Scope string `json:"scope"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" || len(req.Name) > maxTokenNameLength {
+			http.Error(w, fmt.Sprintf("name must be between 1 and %d characters", maxTokenNameLength), http.StatusBadRequest)
+			return
+		}
+		if req.Scope == "" {
+			req.Scope = "read"
+		}
+		if req.Scope != "read" && req.Scope != "write" {
+			http.Error(w, fmt.Sprintf("scope must be one of %v", tokenScopes), http.StatusBadRequest)
+			return
+		}
+
+		var token string
+		var record APIToken
+		err := db.Update(func(tx *bolt.Tx) error {
+			var err error
+			token, record, err = createToken(tx, username, req.Name, req.Scope)
+			return err
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := struct {
+			APIToken
+			Token string `json:"token"`
+		}{
+			APIToken: record,
+			Token:    token,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
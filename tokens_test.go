@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+		ok       bool
+	}{
+		{"Bearer put_abc", "put_abc", true},
+		{"bearer put_abc", "put_abc", true},
+		{"Basic YWRtaW46YWRtaW4=", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/today", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		token, ok := bearerToken(req)
+		if token != tt.expected || ok != tt.ok {
+			t.Errorf("%q: expected %q, %v, got %q, %v", tt.header, tt.expected, tt.ok, token, ok)
+		}
+	}
+}
+
+func TestHandleTokens(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	// create creates a token through the API and returns the response
+	create := func(body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/api/tokens", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handleTokens(w, asTestUser(req))
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	// Test 1: Create a read-only and a write token
+	code, readToken := create(`{"name": "dashboard"}`)
+	if code != http.StatusCreated || readToken["scope"] != "read" {
+		t.Fatalf("Expected read token, got %d %v", code, readToken)
+	}
+	code, writeToken := create(`{"name": "home assistant", "scope": "write"}`)
+	if code != http.StatusCreated || !strings.HasPrefix(writeToken["token"].(string), tokenPrefix) {
+		t.Fatalf("Expected write token, got %d %v", code, writeToken)
+	}
+
+	// Test 2: Invalid requests
+	for _, body := range []string{`{"name": ""}`, `{"name": "x", "scope": "admin"}`, `not json`} {
+		if code, _ := create(body); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, code)
+		}
+	}
+
+	// Test 3: Only the hash of the token is stored
+	testDB.View(func(tx *bolt.Tx) error {
+		token := writeToken["token"].(string)
+		if tx.Bucket([]byte("Tokens")).Get([]byte(token)) != nil {
+			t.Errorf("Expected the token not to be stored in plain text")
+		}
+		if _, ok := getToken(tx, token); !ok {
+			t.Errorf("Expected the token to be found by its hash")
+		}
+		return nil
+	})
+
+	// Test 4: Listing doesn't reveal the tokens
+	req := httptest.NewRequest("GET", "/api/tokens", nil)
+	w := httptest.NewRecorder()
+
+	handleTokens(w, asTestUser(req))
+
+	if strings.Contains(w.Body.String(), writeToken["token"].(string)) {
+		t.Errorf("Expected list not to include the token")
+	}
+	var tokens []APIToken
+	json.Unmarshal(w.Body.Bytes(), &tokens)
+	if len(tokens) != 2 {
+		t.Errorf("Expected 2 tokens, got %d", len(tokens))
+	}
+
+	// Test 5: Tokens can't manage tokens
+	handler := requireAuth(handleTokens)
+	req = httptest.NewRequest("GET", "/api/tokens", nil)
+	req.Header.Set("Authorization", "Bearer "+writeToken["token"].(string))
+	w = httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 when using a token, got %d", w.Code)
+	}
+
+	// Test 6: Revoke a token
+	req = httptest.NewRequest("DELETE", "/api/tokens/"+readToken["id"].(string), nil)
+	w = httptest.NewRecorder()
+
+	handleTokens(w, asTestUser(req))
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handleTokens(w, asTestUser(req))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a revoked token, got %d", w.Code)
+	}
+}
+
+func TestTokenAuth(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	var readToken, writeToken string
+	testDB.Update(func(tx *bolt.Tx) error {
+		readToken, _, _ = createToken(tx, testUser, "dashboard", "read")
+		writeToken, _, _ = createToken(tx, testUser, "home assistant", "write")
+		return nil
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		expected int
+	}{
+		{"Read token can read", "GET", readToken, http.StatusOK},
+		{"Read token can't write", "POST", readToken, http.StatusForbidden},
+		{"Write token can write without CSRF token", "POST", writeToken, http.StatusOK},
+		{"Unknown token", "GET", tokenPrefix + "unknown", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var user string
+			handler := requireAuth(func(w http.ResponseWriter, r *http.Request) {
+				user = requestUser(r)
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(tt.method, "/api/today/reps", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+			if w.Code == http.StatusOK && user != testUser {
+				t.Errorf("Expected request as %s, got %q", testUser, user)
+			}
+		})
+	}
+
+	// Using a token records when it was last used
+	testDB.View(func(tx *bolt.Tx) error {
+		record, _ := getToken(tx, readToken)
+		if record.LastUsed == "" {
+			t.Errorf("Expected last used time to be recorded")
+		}
+		return nil
+	})
+
+	// Deleting the user revokes their tokens
+	testDB.Update(func(tx *bolt.Tx) error {
+		if err := deleteUser(tx, testUser); err != nil {
+			t.Fatalf("Failed to delete user: %v", err)
+		}
+		if _, ok := getToken(tx, writeToken); ok {
+			t.Errorf("Expected tokens to be removed with the user")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,503 @@
+package tracker
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// BoltBuckets are the buckets of a user's data, nested under their name in the
+// UserData bucket. Days holds a JSON record per date, Sets a nested bucket per
+// date, Streak the "current" streak and picked "freezes", Config plain values
+// and Audit the changes made to past days.
+var BoltBuckets = []string{"Days", "Streak", "Config", "Sets", "Audit"}
+
+// BoltStore keeps the tracker data in a BoltDB database
+type BoltStore struct {
+	DB *bolt.DB
+}
+
+// NewBoltStore returns a store on an open database
+func NewBoltStore(db *bolt.DB) BoltStore {
+	return BoltStore{DB: db}
+}
+
+func (s BoltStore) View(username string, fn func(tx Tx) error) error {
+	return s.DB.View(func(tx *bolt.Tx) error {
+		b, err := boltUser(tx, username)
+		if err != nil {
+			return err
+		}
+		return fn(boltTx{b})
+	})
+}
+
+func (s BoltStore) Update(username string, fn func(tx Tx) error) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		b, err := boltUser(tx, username)
+		if err != nil {
+			return err
+		}
+		return fn(boltTx{b})
+	})
+}
+
+func (s BoltStore) CreateUser(username string) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		return CreateBoltUser(tx, username)
+	})
+}
+
+func (s BoltStore) DeleteUser(username string) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		return DeleteBoltUser(tx, username)
+	})
+}
+
+func (s BoltStore) Users() ([]string, error) {
+	usernames := []string{}
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte("UserData"))
+		if root == nil {
+			return nil
+		}
+		return root.ForEach(func(k, v []byte) error {
+			if v == nil {
+				usernames = append(usernames, string(k))
+			}
+			return nil
+		})
+	})
+	return usernames, err
+}
+
+func (s BoltStore) Backup(w io.Writer) error {
+	return s.DB.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+func boltUser(tx *bolt.Tx, username string) (*bolt.Bucket, error) {
+	root := tx.Bucket([]byte("UserData"))
+	if root == nil {
+		return nil, ErrUserNotFound
+	}
+	b := root.Bucket([]byte(username))
+	if b == nil {
+		return nil, ErrUserNotFound
+	}
+	return b, nil
+}
+
+// BoltTx returns a user's data within a transaction opened by the caller, for
+// work that has to happen together with changes to other buckets
+func BoltTx(tx *bolt.Tx, username string) (Tx, error) {
+	b, err := boltUser(tx, username)
+	if err != nil {
+		return nil, err
+	}
+	return boltTx{b}, nil
+}
+
+// CreateBoltUser creates the buckets of a new user, in the same transaction as
+// the account itself
+func CreateBoltUser(tx *bolt.Tx, username string) error {
+	root, err := tx.CreateBucketIfNotExists([]byte("UserData"))
+	if err != nil {
+		return err
+	}
+	b, err := root.CreateBucketIfNotExists([]byte(username))
+	if err != nil {
+		return err
+	}
+	for _, name := range BoltBuckets {
+		if _, err := b.CreateBucketIfNotExists([]byte(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteBoltUser removes all of a user's data
+func DeleteBoltUser(tx *bolt.Tx, username string) error {
+	root := tx.Bucket([]byte("UserData"))
+	if root == nil {
+		return nil
+	}
+	err := root.DeleteBucket([]byte(username))
+	if err == bolt.ErrBucketNotFound {
+		return nil
+	}
+	return err
+}
+
+// boltTx is a transaction on the buckets of one user
+type boltTx struct {
+	b *bolt.Bucket
+}
+
+// setKey is the key of a set or audit entry, a big-endian sequence number so that
+// a cursor walks them in the order they were added
+func setKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+// nextID returns id, or the next sequence number of b for 0. The sequence is moved
+// past a given id so that it isn't handed out again.
+func nextID(b *bolt.Bucket, id uint64) (uint64, error) {
+	if id == 0 {
+		return b.NextSequence()
+	}
+	if id > b.Sequence() {
+		return id, b.SetSequence(id)
+	}
+	return id, nil
+}
+
+func (t boltTx) Day(date string) (DayData, bool, error) {
+	var day DayData
+	data := t.b.Bucket([]byte("Days")).Get([]byte(date))
+	if data == nil {
+		return day, false, nil
+	}
+	err := json.Unmarshal(data, &day)
+	return day, err == nil, err
+}
+
+func (t boltTx) PutDay(day DayData) error {
+	jsonData, err := json.Marshal(day)
+	if err != nil {
+		return err
+	}
+	return t.b.Bucket([]byte("Days")).Put([]byte(day.Date), jsonData)
+}
+
+func (t boltTx) DeleteDay(date string) error {
+	return t.b.Bucket([]byte("Days")).Delete([]byte(date))
+}
+
+// Days skips keys that aren't dates
+func (t boltTx) Days(from, to string) ([]DayData, error) {
+	days := []DayData{}
+
+	c := t.b.Bucket([]byte("Days")).Cursor()
+	k, v := c.First()
+	if from != "" {
+		k, v = c.Seek([]byte(from))
+	}
+	for ; k != nil && (to == "" || string(k) <= to); k, v = c.Next() {
+		if _, err := time.Parse("2006-01-02", string(k)); err != nil {
+			continue
+		}
+		var day DayData
+		if err := json.Unmarshal(v, &day); err != nil {
+			return days, fmt.Errorf("day %s: %v", k, err)
+		}
+		day.Date = string(k)
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func (t boltTx) FirstDate() (string, error) {
+	k, _ := t.b.Bucket([]byte("Days")).Cursor().First()
+	return string(k), nil
+}
+
+func (t boltTx) Sets(date string) ([]SetData, error) {
+	sets := []SetData{}
+
+	root := t.b.Bucket([]byte("Sets"))
+	if root == nil {
+		return sets, nil
+	}
+	b := root.Bucket([]byte(date))
+	if b == nil {
+		return sets, nil
+	}
+
+	err := b.ForEach(func(k, v []byte) error {
+		var set SetData
+		if err := json.Unmarshal(v, &set); err != nil {
+			return err
+		}
+		sets = append(sets, set)
+		return nil
+	})
+	return sets, err
+}
+
+func (t boltTx) Set(date string, id uint64) (SetData, bool, error) {
+	var set SetData
+
+	root := t.b.Bucket([]byte("Sets"))
+	if root == nil {
+		return set, false, nil
+	}
+	b := root.Bucket([]byte(date))
+	if b == nil {
+		return set, false, nil
+	}
+	data := b.Get(setKey(id))
+	if data == nil {
+		return set, false, nil
+	}
+	err := json.Unmarshal(data, &set)
+	return set, err == nil, err
+}
+
+func (t boltTx) AddSet(date string, set SetData) (SetData, error) {
+	root, err := t.b.CreateBucketIfNotExists([]byte("Sets"))
+	if err != nil {
+		return set, err
+	}
+	b, err := root.CreateBucketIfNotExists([]byte(date))
+	if err != nil {
+		return set, err
+	}
+
+	set.ID, err = nextID(b, set.ID)
+	if err != nil {
+		return set, err
+	}
+	jsonData, err := json.Marshal(set)
+	if err != nil {
+		return set, err
+	}
+	return set, b.Put(setKey(set.ID), jsonData)
+}
+
+func (t boltTx) DeleteSet(date string, id uint64) error {
+	root := t.b.Bucket([]byte("Sets"))
+	if root == nil {
+		return nil
+	}
+	b := root.Bucket([]byte(date))
+	if b == nil {
+		return nil
+	}
+	return b.Delete(setKey(id))
+}
+
+func (t boltTx) DeleteSets(date string) error {
+	root := t.b.Bucket([]byte("Sets"))
+	if root == nil || root.Bucket([]byte(date)) == nil {
+		return nil
+	}
+	return root.DeleteBucket([]byte(date))
+}
+
+func (t boltTx) Streak() (StreakData, error) {
+	var streak StreakData
+	data := t.b.Bucket([]byte("Streak")).Get([]byte("current"))
+	if data == nil {
+		return streak, nil
+	}
+	err := json.Unmarshal(data, &streak)
+	return streak, err
+}
+
+func (t boltTx) PutStreak(streak StreakData) error {
+	jsonData, err := json.Marshal(streak)
+	if err != nil {
+		return err
+	}
+	return t.b.Bucket([]byte("Streak")).Put([]byte("current"), jsonData)
+}
+
+func (t boltTx) FreezeDays() ([]string, error) {
+	var days []string
+	data := t.b.Bucket([]byte("Streak")).Get([]byte("freezes"))
+	if data == nil {
+		return days, nil
+	}
+	err := json.Unmarshal(data, &days)
+	return days, err
+}
+
+func (t boltTx) PutFreezeDays(days []string) error {
+	jsonData, err := json.Marshal(days)
+	if err != nil {
+		return err
+	}
+	return t.b.Bucket([]byte("Streak")).Put([]byte("freezes"), jsonData)
+}
+
+func (t boltTx) Config(key string) (string, error) {
+	return string(t.b.Bucket([]byte("Config")).Get([]byte(key))), nil
+}
+
+func (t boltTx) PutConfig(key, value string) error {
+	return t.b.Bucket([]byte("Config")).Put([]byte(key), []byte(value))
+}
+
+func (t boltTx) ConfigKeys() ([]string, error) {
+	keys := []string{}
+	err := t.b.Bucket([]byte("Config")).ForEach(func(k, v []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	})
+	return keys, err
+}
+
+func (t boltTx) AddAudit(entry AuditEntry) (AuditEntry, error) {
+	b, err := t.b.CreateBucketIfNotExists([]byte("Audit"))
+	if err != nil {
+		return entry, err
+	}
+
+	entry.ID, err = nextID(b, entry.ID)
+	if err != nil {
+		return entry, err
+	}
+	jsonData, err := json.Marshal(entry)
+	if err != nil {
+		return entry, err
+	}
+	return entry, b.Put(setKey(entry.ID), jsonData)
+}
+
+func (t boltTx) Audit(date string) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+
+	b := t.b.Bucket([]byte("Audit"))
+	if b == nil {
+		return entries, nil
+	}
+
+	err := b.ForEach(func(k, v []byte) error {
+		var entry AuditEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return err
+		}
+		if date == "" || entry.Date == date {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Damaged walks the buckets of the user. A value in Sets that isn't a bucket of
+// sets, or one under a key that isn't a date, is listed as all sets of the date.
+// A set or audit key that isn't an ID is listed in hex with a 0x prefix.
+func (t boltTx) Damaged() ([]DamagedRecord, error) {
+	damaged := []DamagedRecord{}
+	add := func(kind, key string, err error) {
+		damaged = append(damaged, DamagedRecord{Kind: kind, Key: key, Err: err.Error()})
+	}
+
+	t.b.Bucket([]byte("Days")).ForEach(func(k, v []byte) error {
+		var day DayData
+		if _, err := time.Parse("2006-01-02", string(k)); err != nil {
+			add("day", string(k), fmt.Errorf("key is not a date"))
+		} else if err := json.Unmarshal(v, &day); err != nil {
+			add("day", string(k), err)
+		}
+		return nil
+	})
+
+	if root := t.b.Bucket([]byte("Sets")); root != nil {
+		root.ForEach(func(k, v []byte) error {
+			date := string(k)
+			b := root.Bucket(k)
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				add("set", date, fmt.Errorf("key is not a date"))
+				return nil
+			}
+			if b == nil {
+				add("set", date, fmt.Errorf("not a bucket of sets"))
+				return nil
+			}
+			return b.ForEach(func(k, v []byte) error {
+				var set SetData
+				if len(k) != 8 {
+					add("set", date+"/0x"+hex.EncodeToString(k), fmt.Errorf("key is not a set ID"))
+				} else if err := json.Unmarshal(v, &set); err != nil {
+					add("set", fmt.Sprintf("%s/%d", date, binary.BigEndian.Uint64(k)), err)
+				}
+				return nil
+			})
+		})
+	}
+
+	if _, err := t.Streak(); err != nil {
+		add("streak", "current", err)
+	}
+	if _, err := t.FreezeDays(); err != nil {
+		add("streak", "freezes", err)
+	}
+
+	if b := t.b.Bucket([]byte("Audit")); b != nil {
+		b.ForEach(func(k, v []byte) error {
+			var entry AuditEntry
+			if len(k) != 8 {
+				add("audit", "0x"+hex.EncodeToString(k), fmt.Errorf("key is not an ID"))
+			} else if err := json.Unmarshal(v, &entry); err != nil {
+				add("audit", strconv.FormatUint(binary.BigEndian.Uint64(k), 10), err)
+			}
+			return nil
+		})
+	}
+	return damaged, nil
+}
+
+func (t boltTx) DeleteDamaged(record DamagedRecord) error {
+	switch record.Kind {
+	case "day":
+		return deleteKey(t.b.Bucket([]byte("Days")), []byte(record.Key))
+	case "set":
+		root := t.b.Bucket([]byte("Sets"))
+		if root == nil {
+			return nil
+		}
+		date, id, found := strings.Cut(record.Key, "/")
+		if !found {
+			return deleteKey(root, []byte(date))
+		}
+		return t.deleteID(root.Bucket([]byte(date)), id)
+	case "streak":
+		return deleteKey(t.b.Bucket([]byte("Streak")), []byte(record.Key))
+	case "audit":
+		return t.deleteID(t.b.Bucket([]byte("Audit")), record.Key)
+	}
+	return fmt.Errorf("unknown record kind %q", record.Kind)
+}
+
+// deleteID deletes the set or audit entry with the given ID from b, which may be
+// nil. A key that isn't an ID is given in hex with a 0x prefix.
+func (t boltTx) deleteID(b *bolt.Bucket, id string) error {
+	if b == nil {
+		return nil
+	}
+	if raw, ok := strings.CutPrefix(id, "0x"); ok {
+		key, err := hex.DecodeString(raw)
+		if err != nil {
+			return err
+		}
+		return b.Delete(key)
+	}
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return b.Delete([]byte(id))
+	}
+	return b.Delete(setKey(n))
+}
+
+// deleteKey deletes a value or a nested bucket
+func deleteKey(b *bolt.Bucket, key []byte) error {
+	if b.Bucket(key) != nil {
+		return b.DeleteBucket(key)
+	}
+	return b.Delete(key)
+}
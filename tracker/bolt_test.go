@@ -0,0 +1,217 @@
+package tracker
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestDayDataOperations(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Test storing and retrieving day data
+	dayData := DayData{
+		Date:  "2024-01-01",
+		Count: 5,
+		Done:  true,
+	}
+
+	// Store data
+	err := testDB.Update(func(root *bolt.Tx) error {
+		return testScope(root).PutDay(dayData)
+	})
+	if err != nil {
+		t.Fatalf("Failed to store day data: %v", err)
+	}
+
+	// The record is JSON keyed by date
+	var stored DayData
+	err = testDB.View(func(root *bolt.Tx) error {
+		data := testBucket(root, "Days").Get([]byte(dayData.Date))
+		return json.Unmarshal(data, &stored)
+	})
+	if err != nil {
+		t.Fatalf("Failed to read stored day data: %v", err)
+	}
+	if stored != dayData {
+		t.Errorf("Expected stored %+v, got %+v", dayData, stored)
+	}
+
+	// Retrieve data
+	var retrieved DayData
+	var found bool
+	err = testDB.View(func(root *bolt.Tx) error {
+		var err error
+		retrieved, found, err = testScope(root).Day(dayData.Date)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to retrieve day data: %v", err)
+	}
+
+	// Verify data
+	if !found || retrieved != dayData {
+		t.Errorf("Expected %+v, got %+v (found %v)", dayData, retrieved, found)
+	}
+}
+
+func TestDaysRange(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		for _, date := range []string{"2024-03-03", "2024-03-01", "2024-03-02", "2024-03-05"} {
+			tx.PutDay(DayData{Date: date, Count: 10})
+		}
+		// Keys that aren't dates are skipped
+		testBucket(root, "Days").Put([]byte("notes"), []byte("{invalid json}"))
+
+		tests := []struct {
+			from, to string
+			expected []string
+		}{
+			{"", "", []string{"2024-03-01", "2024-03-02", "2024-03-03", "2024-03-05"}},
+			{"2024-03-02", "2024-03-03", []string{"2024-03-02", "2024-03-03"}},
+			{"2024-03-04", "", []string{"2024-03-05"}},
+			{"", "2024-02-28", nil},
+		}
+		for _, tt := range tests {
+			days, err := tx.Days(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var dates []string
+			for _, day := range days {
+				dates = append(dates, day.Date)
+			}
+			if len(dates) != len(tt.expected) {
+				t.Errorf("Expected %v from %q to %q, got %v", tt.expected, tt.from, tt.to, dates)
+				continue
+			}
+			for i := range dates {
+				if dates[i] != tt.expected[i] {
+					t.Errorf("Expected %v from %q to %q, got %v", tt.expected, tt.from, tt.to, dates)
+					break
+				}
+			}
+		}
+
+		if first, _ := tx.FirstDate(); first != "2024-03-01" {
+			t.Errorf("Expected first date 2024-03-01, got %s", first)
+		}
+		return nil
+	})
+}
+
+func TestSetOperations(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		first, _ := tx.AddSet("2024-03-01", SetData{Reps: 10})
+		second, _ := tx.AddSet("2024-03-01", SetData{Reps: 5})
+		if first.ID != 1 || second.ID != 2 {
+			t.Errorf("Expected IDs 1 and 2, got %d and %d", first.ID, second.ID)
+		}
+
+		tx.DeleteSet("2024-03-01", first.ID)
+		if _, found, _ := tx.Set("2024-03-01", first.ID); found {
+			t.Errorf("Expected the deleted set to be gone")
+		}
+		if sets, _ := tx.Sets("2024-03-01"); len(sets) != 1 || sets[0] != second {
+			t.Errorf("Expected only the second set, got %+v", sets)
+		}
+
+		// IDs aren't reused after a deletion
+		if third, _ := tx.AddSet("2024-03-01", SetData{Reps: 1}); third.ID != 3 {
+			t.Errorf("Expected ID 3, got %d", third.ID)
+		}
+
+		tx.DeleteSets("2024-03-01")
+		if sets, _ := tx.Sets("2024-03-01"); len(sets) != 0 {
+			t.Errorf("Expected no sets, got %+v", sets)
+		}
+		return nil
+	})
+}
+
+func TestStreakDataOperations(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Test storing and retrieving streak data
+	streakData := StreakData{
+		Current:  5,
+		Longest:  10,
+		LastDate: "2024-01-05",
+	}
+
+	// Store data
+	err := testDB.Update(func(root *bolt.Tx) error {
+		return testScope(root).PutStreak(streakData)
+	})
+	if err != nil {
+		t.Fatalf("Failed to store streak data: %v", err)
+	}
+
+	// The streak is stored under "current"
+	var stored StreakData
+	err = testDB.View(func(root *bolt.Tx) error {
+		data := testBucket(root, "Streak").Get([]byte("current"))
+		return json.Unmarshal(data, &stored)
+	})
+	if err != nil {
+		t.Fatalf("Failed to read stored streak data: %v", err)
+	}
+
+	// Retrieve data
+	var retrieved StreakData
+	err = testDB.View(func(root *bolt.Tx) error {
+		var err error
+		retrieved, err = testScope(root).Streak()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to retrieve streak data: %v", err)
+	}
+
+	// Verify data
+	if stored != streakData || retrieved != streakData {
+		t.Errorf("Expected %+v, got stored %+v and retrieved %+v", streakData, stored, retrieved)
+	}
+}
+
+func TestBoltBackup(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	store := NewBoltStore(testDB)
+	store.Update(testUser, func(tx Tx) error {
+		return tx.PutDay(DayData{Date: "2024-03-01", Count: 10})
+	})
+
+	file := filepath.Join(t.TempDir(), "backup.db")
+	f, _ := os.Create(file)
+	if err := store.Backup(f); err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+	f.Close()
+
+	backupDB, err := bolt.Open(file, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to open the backup: %v", err)
+	}
+	defer backupDB.Close()
+	NewBoltStore(backupDB).View(testUser, func(tx Tx) error {
+		if _, found, _ := tx.Day("2024-03-01"); !found {
+			t.Errorf("Expected the day to be in the backup")
+		}
+		return nil
+	})
+}
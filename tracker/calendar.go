@@ -0,0 +1,66 @@
+package tracker
+
+import (
+	"fmt"
+	"time"
+)
+
+// Calendar is a year of a user's days
+type Calendar struct {
+	// Year is the current year, which may differ from the year of Days
+	Year int `json:"year"`
+	// StartMonth and StartYear are the month of the first record, or the current
+	// one without records. StartMonth is 0-based for JavaScript.
+	StartMonth int                `json:"startMonth"`
+	StartYear  int                `json:"startYear"`
+	Days       map[string]DayData `json:"days"`
+	// RestDays are the weekdays off in the weekly schedule, 0 being Sunday, for
+	// the days without a record
+	RestDays []int  `json:"restDays"`
+	Today    string `json:"today"`
+}
+
+// Calendar returns the days of the given year, or of the current year for 0
+func (s *Service) Calendar(username string, year int) (Calendar, error) {
+	var calendar Calendar
+	err := s.view(username, func(tx *scope) error {
+		calendar.Today = userToday(tx)
+		todayTime, _ := time.Parse("2006-01-02", calendar.Today)
+		calendar.Year = todayTime.Year()
+		if year == 0 {
+			year = todayTime.Year()
+		}
+
+		firstRecordDate, err := tx.FirstDate()
+		if err != nil {
+			return err
+		}
+		start := todayTime
+		if firstRecordDate != "" {
+			start, err = time.Parse("2006-01-02", firstRecordDate)
+			if err != nil {
+				return err
+			}
+		}
+		calendar.StartMonth = int(start.Month() - 1) // Go months are 1-based, JS is 0-based
+		calendar.StartYear = start.Year()
+
+		calendar.RestDays = []int{}
+		for _, name := range loadSettings(tx).RestDays {
+			if day, ok := ParseWeekday(name); ok {
+				calendar.RestDays = append(calendar.RestDays, int(day))
+			}
+		}
+
+		days, err := tx.Days(fmt.Sprintf("%04d-01-01", year), fmt.Sprintf("%04d-12-31", year))
+		if err != nil {
+			return err
+		}
+		calendar.Days = make(map[string]DayData, len(days))
+		for _, dayData := range days {
+			calendar.Days[dayData.Date] = dayData
+		}
+		return nil
+	})
+	return calendar, err
+}
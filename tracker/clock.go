@@ -0,0 +1,56 @@
+package tracker
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the real time
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// OffsetClock runs at the real pace, shifted by an offset that can be changed
+// while the tracker is in use
+type OffsetClock struct {
+	mu     sync.Mutex
+	offset time.Duration
+}
+
+func (c *OffsetClock) Now() time.Time {
+	return time.Now().Add(c.Offset())
+}
+
+func (c *OffsetClock) Offset() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.offset
+}
+
+// Set moves the clock to the given moment
+func (c *OffsetClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = time.Until(t)
+}
+
+// Shift moves the clock forward, or back for a negative duration
+func (c *OffsetClock) Shift(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset += d
+}
+
+// Reset puts the clock back to the real time
+func (c *OffsetClock) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = 0
+}
@@ -0,0 +1,26 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOffsetClock(t *testing.T) {
+	fake := &OffsetClock{}
+	start, _ := time.Parse(time.RFC3339, "2024-03-10T23:59:00Z")
+
+	fake.Set(start)
+	if d := fake.Now().Sub(start); d < 0 || d > time.Second {
+		t.Errorf("Expected the clock at %s, got %s", start, fake.Now())
+	}
+
+	fake.Shift(2 * time.Minute)
+	if got := fake.Now().UTC().Format("2006-01-02 15:04"); got != "2024-03-11 00:01" {
+		t.Errorf("Expected the clock past midnight, got %s", got)
+	}
+
+	fake.Reset()
+	if fake.Offset() != 0 {
+		t.Errorf("Expected no offset after a reset, got %s", fake.Offset())
+	}
+}
@@ -0,0 +1,245 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AuditEntry records a change made to a past day. Before is nil for a day that
+// had no record, After is nil for a deleted day.
+type AuditEntry struct {
+	ID     uint64   `json:"id"`
+	Time   string   `json:"time"`
+	Date   string   `json:"date"`
+	Action string   `json:"action"`
+	Before *DayData `json:"before"`
+	After  *DayData `json:"after"`
+}
+
+// ErrOutsideBackfill is returned when a day is too far back to be edited
+var ErrOutsideBackfill = errors.New("day is outside the backfill window")
+
+// ErrFutureDate is returned when a day after today is to be changed
+var ErrFutureDate = errors.New("date is in the future")
+
+// addAudit appends an entry to the user's audit trail
+func addAudit(tx *scope, entry AuditEntry) error {
+	entry.Time = tx.clock.Now().Format(time.RFC3339)
+	_, err := tx.AddAudit(entry)
+	return err
+}
+
+// inBackfillWindow checks that the date can be edited: ErrFutureDate is returned
+// for a day after today and ErrOutsideBackfill for one too far back
+func inBackfillWindow(settings Settings, date, today string) error {
+	todayTime, err := time.Parse("2006-01-02", today)
+	if err != nil {
+		return ErrOutsideBackfill
+	}
+	if date > today {
+		return ErrFutureDate
+	}
+	if date < todayTime.AddDate(0, 0, -settings.BackfillDays).Format("2006-01-02") {
+		return ErrOutsideBackfill
+	}
+	return nil
+}
+
+// recalculateAfter works out the targets of the days recorded after the given date
+// again, in order, so that a change to a past day carries through the progression.
+// A completed day stays completed. The history is replayed once from the first day,
+// counter of tiers that increase every few days included, so working out the
+// targets again without a change leaves them as they were.
+func recalculateAfter(tx *scope, date, today string) error {
+	days, err := tx.Days("", today)
+	if err != nil {
+		return err
+	}
+	if err := setDaysAtCurrentLevel(tx, 0); err != nil {
+		return err
+	}
+
+	r := newReplay(tx)
+	for _, dayData := range days {
+		count, err := r.target(dayData.Date)
+		if err != nil {
+			return err
+		}
+		// Only the counter is replayed up to the changed day
+		if dayData.Date > date {
+			dayData.Count = count
+			dayData.Done = dayData.Done || dayData.Reps >= dayData.Count
+			if err := tx.PutDay(dayData); err != nil {
+				return err
+			}
+		}
+		if err := r.add(dayData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateFirstDay sets the first day to the first record, which a change to a past
+// day can move
+func updateFirstDay(tx *scope) error {
+	firstDay, err := tx.FirstDate()
+	if err != nil {
+		return err
+	}
+	return setFirstDay(tx, firstDay)
+}
+
+// afterPastChange records a change to a day before today in the audit trail and
+// carries it through the first day, the targets after it and the streak, like an
+// edit does. Changes to today follow the usual flow and aren't audited.
+func afterPastChange(tx *scope, today, action string, before *DayData, after DayData) error {
+	if after.Date >= today {
+		return nil
+	}
+	if err := addAudit(tx, AuditEntry{Date: after.Date, Action: action, Before: before, After: &after}); err != nil {
+		return err
+	}
+	if err := updateFirstDay(tx); err != nil {
+		return err
+	}
+	if err := recalculateAfter(tx, after.Date, today); err != nil {
+		return err
+	}
+	_, err := updateStreak(tx, today)
+	return err
+}
+
+// editDay marks a day done or not done and/or corrects its reps, then recalculates
+// the first day, the targets after it and the streak. A day without a record gets the target it
+// would have had. Sets logged on the day are kept as they are.
+func editDay(tx *scope, date, today string, done *bool, reps *int) (DayData, error) {
+	if err := inBackfillWindow(loadSettings(tx), date, today); err != nil {
+		return DayData{}, err
+	}
+
+	dayData, found, err := loadDay(tx, date)
+	if err != nil {
+		return dayData, err
+	}
+	var before *DayData
+	if found {
+		previous := dayData
+		before = &previous
+	} else {
+		count, err := calculateTodayTarget(tx, date)
+		if err != nil {
+			return dayData, err
+		}
+		dayData = DayData{Date: date, Count: count}
+	}
+
+	if reps != nil {
+		dayData.Reps = *reps
+		dayData.Done = dayData.Reps >= dayData.Count
+	}
+	if done != nil {
+		dayData.Done = *done
+	}
+
+	if err := tx.PutDay(dayData); err != nil {
+		return dayData, err
+	}
+	if err := addAudit(tx, AuditEntry{Date: date, Action: "edit", Before: before, After: &dayData}); err != nil {
+		return dayData, err
+	}
+	if err := updateFirstDay(tx); err != nil {
+		return dayData, err
+	}
+	if err := recalculateAfter(tx, date, today); err != nil {
+		return dayData, err
+	}
+	_, err = updateStreak(tx, today)
+	return dayData, err
+}
+
+// deleteDay removes a day's record and sets, then recalculates the targets after it,
+// the first day and the streak
+func deleteDay(tx *scope, date, today string) (bool, error) {
+	if err := inBackfillWindow(loadSettings(tx), date, today); err != nil {
+		return false, err
+	}
+
+	dayData, found, err := tx.Day(date)
+	if err != nil || !found {
+		return false, err
+	}
+
+	if err := tx.DeleteDay(date); err != nil {
+		return false, err
+	}
+	if err := tx.DeleteSets(date); err != nil {
+		return false, err
+	}
+	if err := addAudit(tx, AuditEntry{Date: date, Action: "delete", Before: &dayData}); err != nil {
+		return false, err
+	}
+
+	if err := updateFirstDay(tx); err != nil {
+		return false, err
+	}
+
+	if err := recalculateAfter(tx, date, today); err != nil {
+		return false, err
+	}
+	_, err = updateStreak(tx, today)
+	return true, err
+}
+
+// EditDay marks a day done or not done, e.g. with done set to true, and/or
+// corrects its reps. Only days within the backfill window can be changed,
+// ErrOutsideBackfill is returned for older ones and ErrFutureDate for days after today.
+func (s *Service) EditDay(username, date string, done *bool, reps *int) (Day, error) {
+	if reps != nil && (*reps < 0 || *reps > MaxImportCount) {
+		return Day{}, fmt.Errorf("reps must be between 0 and %d", MaxImportCount)
+	}
+
+	var response Day
+	err := s.update(username, func(tx *scope) error {
+		dayData, err := editDay(tx, date, userToday(tx), done, reps)
+		if err != nil {
+			return err
+		}
+		response, err = newDay(tx, dayData)
+		return err
+	})
+	return response, err
+}
+
+// DeleteDay removes a day and its sets, found is false if it had no record
+func (s *Service) DeleteDay(username, date string) (found bool, err error) {
+	err = s.update(username, func(tx *scope) error {
+		found, err = deleteDay(tx, date, userToday(tx))
+		return err
+	})
+	return found, err
+}
+
+// History returns the user's records from from to to, both included, in date
+// order. An empty bound leaves that end open.
+func (s *Service) History(username, from, to string) ([]DayData, error) {
+	var days []DayData
+	err := s.view(username, func(tx *scope) error {
+		var err error
+		days, err = tx.Days(from, to)
+		return err
+	})
+	return days, err
+}
+
+// Audit lists the changes made to a day in the order they were made
+func (s *Service) Audit(username, date string) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	err := s.view(username, func(tx *scope) error {
+		var err error
+		entries, err = tx.Audit(date)
+		return err
+	})
+	return entries, err
+}
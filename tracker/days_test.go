@@ -0,0 +1,321 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestInBackfillWindow(t *testing.T) {
+	settings := DefaultSettings
+	settings.BackfillDays = 7
+
+	tests := []struct {
+		date     string
+		expected error
+	}{
+		{"2024-03-11", ErrFutureDate},
+		{"2024-03-10", nil},
+		{"2024-03-03", nil},
+		{"2024-03-02", ErrOutsideBackfill},
+	}
+
+	for _, tt := range tests {
+		if got := inBackfillWindow(settings, tt.date, "2024-03-10"); got != tt.expected {
+			t.Errorf("Expected %v for %s, got %v", tt.expected, tt.date, got)
+		}
+	}
+
+	settings.BackfillDays = 0
+	if inBackfillWindow(settings, "2024-03-09", "2024-03-10") == nil {
+		t.Errorf("Expected only today to be editable without a backfill window")
+	}
+}
+
+func TestFutureDays(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	clock := &OffsetClock{}
+	clock.Set(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC))
+	svc := New(NewBoltStore(testDB))
+	svc.Clock = clock
+	svc.Defaults.Timezone = "UTC"
+	svc.Today(testUser)
+
+	done := true
+	if _, err := svc.EditDay(testUser, "2024-03-20", &done, nil); err != ErrFutureDate {
+		t.Errorf("Expected ErrFutureDate for an edit, got %v", err)
+	}
+	if _, err := svc.SetStatus(testUser, "2030-01-01", StatusRest); err != ErrFutureDate {
+		t.Errorf("Expected ErrFutureDate for a status, got %v", err)
+	}
+	if _, err := svc.DeleteDay(testUser, "2024-03-11"); err != ErrFutureDate {
+		t.Errorf("Expected ErrFutureDate for a delete, got %v", err)
+	}
+	if days, _ := svc.History(testUser, "2024-03-11", ""); len(days) != 0 {
+		t.Errorf("Expected no days after today, got %+v", days)
+	}
+}
+
+func TestEditDay(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		setFirstDay(tx, "2024-03-01")
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 10})
+		tx.PutDay(DayData{Date: "2024-03-02", Count: 10})
+		tx.PutDay(DayData{Date: "2024-03-03", Count: 10, Reps: 11, Done: true})
+
+		// Completing the forgotten first day raises the targets after it
+		done := true
+		dayData, err := editDay(tx, "2024-03-01", "2024-03-03", &done, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !dayData.Done {
+			t.Errorf("Expected the day to be done, got %+v", dayData)
+		}
+		next, _, _ := loadDay(tx, "2024-03-02")
+		last, _, _ := loadDay(tx, "2024-03-03")
+		if next.Count != 12 || last.Count != 12 || !last.Done {
+			t.Errorf("Expected recalculated targets of 12 with the last day still done, got %+v and %+v", next, last)
+		}
+		if streak, _ := tx.Streak(); streak.Current != 1 || streak.Longest != 1 {
+			t.Errorf("Expected streak to be updated, got %+v", streak)
+		}
+
+		// Correcting the reps decides whether the day is done
+		reps := 5
+		dayData, _ = editDay(tx, "2024-03-03", "2024-03-03", nil, &reps)
+		if dayData.Reps != 5 || dayData.Done {
+			t.Errorf("Expected 5 reps and not done, got %+v", dayData)
+		}
+
+		entries, _ := tx.Audit("2024-03-01")
+		if len(entries) != 1 || entries[0].Action != "edit" || entries[0].Before.Done || !entries[0].After.Done {
+			t.Errorf("Expected one audit entry for the edit, got %+v", entries)
+		}
+
+		if _, err := editDay(tx, "2024-02-20", "2024-03-03", &done, nil); err != ErrOutsideBackfill {
+			t.Errorf("Expected ErrOutsideBackfill, got %v", err)
+		}
+		return nil
+	})
+}
+
+func TestEditDayKeepsTargets(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	clock := &OffsetClock{}
+	clock.Set(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc := New(NewBoltStore(testDB))
+	svc.Clock = clock
+	svc.Defaults.Timezone = "UTC"
+	svc.Defaults.StartTarget = 100
+
+	// Targets of 100 and up increase every two days, with a counter in between
+	for i := 0; i < 8; i++ {
+		if _, err := svc.Complete(testUser); err != nil {
+			t.Fatalf("Failed to complete a day: %v", err)
+		}
+		clock.Shift(24 * time.Hour)
+	}
+	svc.Today(testUser)
+	before, _ := svc.History(testUser, "", "")
+
+	// Completing a day that is already completed changes nothing
+	done := true
+	if _, err := svc.EditDay(testUser, "2024-03-04", &done, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	after, _ := svc.History(testUser, "", "")
+	if len(after) != len(before) {
+		t.Fatalf("Expected %d days, got %d", len(before), len(after))
+	}
+	for i := range before {
+		if after[i] != before[i] {
+			t.Errorf("Expected %+v to be unchanged, got %+v", before[i], after[i])
+		}
+	}
+	if result, _ := svc.Fsck(testUser, false); len(result.Problems) != 0 {
+		t.Errorf("Expected no problems, got %+v", result.Problems)
+	}
+}
+
+func TestPastSetsAndStatus(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	clock := &OffsetClock{}
+	clock.Set(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC))
+	svc := New(NewBoltStore(testDB))
+	svc.Clock = clock
+	svc.Defaults.Timezone = "UTC"
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		setFirstDay(tx, "2024-01-01")
+		tx.PutDay(DayData{Date: "2024-01-01", Count: 10})
+		tx.PutDay(DayData{Date: "2024-03-07", Count: 10, Reps: 10, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-08", Count: 12})
+		return tx.PutDay(DayData{Date: "2024-03-09", Count: 12})
+	})
+
+	// Test 1: Days outside the backfill window can't be changed
+	if _, _, err := svc.AddSet(testUser, "2024-01-01", 10, ""); err != ErrOutsideBackfill {
+		t.Errorf("Expected ErrOutsideBackfill for a set, got %v", err)
+	}
+	if _, _, err := svc.DeleteSet(testUser, "2024-01-01", 1); err != ErrOutsideBackfill {
+		t.Errorf("Expected ErrOutsideBackfill for deleting a set, got %v", err)
+	}
+	if _, err := svc.SetStatus(testUser, "2023-06-01", StatusRest); err != ErrOutsideBackfill {
+		t.Errorf("Expected ErrOutsideBackfill for a status, got %v", err)
+	}
+	if _, found, _ := svc.Day(testUser, "2023-06-01"); found {
+		t.Errorf("Expected no record outside the window")
+	}
+
+	// Test 2: A set that completes a past day is audited and carries through
+	day, _, err := svc.AddSet(testUser, "2024-03-08", 12, "")
+	if err != nil || !day.Done {
+		t.Fatalf("Expected the day to be completed, got %+v (%v)", day, err)
+	}
+	if next, _, _ := svc.Day(testUser, "2024-03-09"); next.Count != 14 {
+		t.Errorf("Expected the next target to be recalculated to 14, got %+v", next.DayData)
+	}
+	if streak, _ := svc.Streak(testUser); streak.Longest != 2 {
+		t.Errorf("Expected a streak of 2, got %+v", streak)
+	}
+	entries, _ := svc.Audit(testUser, "2024-03-08")
+	if len(entries) != 1 || entries[0].Action != "set" || entries[0].Before.Done || !entries[0].After.Done {
+		t.Errorf("Expected the set to be audited, got %+v", entries)
+	}
+
+	// Test 3: Deleting it again reopens the day
+	day, _, err = svc.DeleteSet(testUser, "2024-03-08", day.Sets[0].ID)
+	if err != nil || day.Done {
+		t.Errorf("Expected the day to be reopened, got %+v (%v)", day, err)
+	}
+	if entries, _ := svc.Audit(testUser, "2024-03-08"); len(entries) != 2 || entries[1].Action != "delete-set" {
+		t.Errorf("Expected the deletion to be audited, got %+v", entries)
+	}
+
+	// Test 4: A past day off is audited, today isn't
+	if _, err := svc.SetStatus(testUser, "2024-03-09", StatusSick); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entries, _ := svc.Audit(testUser, "2024-03-09"); len(entries) != 1 || entries[0].Action != "status" || entries[0].After.Status != StatusSick {
+		t.Errorf("Expected the status to be audited, got %+v", entries)
+	}
+	if _, err := svc.SetStatus(testUser, "2024-03-10", StatusRest); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entries, _ := svc.Audit(testUser, "2024-03-10"); len(entries) != 0 {
+		t.Errorf("Expected no audit entry for today, got %+v", entries)
+	}
+}
+
+func TestBackfillMovesFirstDay(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	clock := &OffsetClock{}
+	clock.Set(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC))
+	svc := New(NewBoltStore(testDB))
+	svc.Clock = clock
+	svc.Defaults.Timezone = "UTC"
+	svc.Today(testUser)
+
+	firstDay := func() string {
+		var first string
+		svc.view(testUser, func(tx *scope) error {
+			first, _ = getFirstDay(tx)
+			return nil
+		})
+		return first
+	}
+
+	done := true
+	if _, err := svc.EditDay(testUser, "2024-03-08", &done, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first := firstDay(); first != "2024-03-08" {
+		t.Errorf("Expected the first day to move to 2024-03-08, got %q", first)
+	}
+
+	if _, err := svc.SetStatus(testUser, "2024-03-05", StatusSick); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first := firstDay(); first != "2024-03-05" {
+		t.Errorf("Expected the first day to move to 2024-03-05, got %q", first)
+	}
+	if result, _ := svc.Fsck(testUser, false); len(result.Problems) != 0 {
+		t.Errorf("Expected no problems, got %+v", result.Problems)
+	}
+}
+
+func TestDeleteDay(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		setFirstDay(tx, "2024-03-01")
+		dayData := DayData{Date: "2024-03-01", Count: 10}
+		recordSet(tx, &dayData, 10, "")
+		tx.PutDay(DayData{Date: "2024-03-02", Count: 12})
+
+		found, err := deleteDay(tx, "2024-03-01", "2024-03-02")
+		if err != nil || !found {
+			t.Fatalf("Expected the day to be deleted, got %v %v", found, err)
+		}
+
+		if _, found, _ := loadDay(tx, "2024-03-01"); found {
+			t.Errorf("Expected the day record to be gone")
+		}
+		if sets, _ := tx.Sets("2024-03-01"); len(sets) != 0 {
+			t.Errorf("Expected the sets to be gone, got %d", len(sets))
+		}
+		if firstDay, _ := getFirstDay(tx); firstDay != "2024-03-02" {
+			t.Errorf("Expected first day 2024-03-02, got %s", firstDay)
+		}
+		if next, _, _ := loadDay(tx, "2024-03-02"); next.Count != DefaultSettings.StartTarget {
+			t.Errorf("Expected the next day to start over, got %+v", next)
+		}
+		if entries, _ := tx.Audit("2024-03-01"); len(entries) != 1 || entries[0].After != nil {
+			t.Errorf("Expected one audit entry for the deletion, got %+v", entries)
+		}
+
+		if found, _ := deleteDay(tx, "2024-03-01", "2024-03-02"); found {
+			t.Errorf("Expected a missing day not to be found")
+		}
+		return nil
+	})
+}
+
+func TestHistory(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		for _, date := range []string{"2024-03-01", "2024-03-02", "2024-03-04"} {
+			tx.PutDay(DayData{Date: date, Count: 10})
+		}
+		return nil
+	})
+
+	svc := New(NewBoltStore(testDB))
+	days, err := svc.History(testUser, "2024-03-02", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(days) != 2 || days[0].Date != "2024-03-02" || days[1].Date != "2024-03-04" {
+		t.Errorf("Expected the days from 2024-03-02, got %+v", days)
+	}
+}
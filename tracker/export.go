@@ -0,0 +1,75 @@
+package tracker
+
+import "time"
+
+// ExportVersion is bumped whenever the export format changes incompatibly
+const ExportVersion = 1
+
+// Export is everything stored about a user's history
+type Export struct {
+	Version  int       `json:"version"`
+	Username string    `json:"username"`
+	Exported string    `json:"exported"`
+	Days     []DayData `json:"days"`
+	// Sets holds the sets logged on each day, keyed by date
+	Sets   map[string][]SetData `json:"sets,omitempty"`
+	Streak StreakData           `json:"streak"`
+	Config ExportConfig         `json:"config"`
+}
+
+// ExportConfig holds the config values needed to continue the history elsewhere
+type ExportConfig struct {
+	FirstDay    string `json:"firstDay"`
+	DaysAtLevel int    `json:"daysAtLevel"`
+}
+
+// buildExport collects the user's days with their sets, streak and config
+func buildExport(tx *scope, username string, now time.Time) (Export, error) {
+	export := Export{
+		Version:  ExportVersion,
+		Username: username,
+		Exported: now.Format(time.RFC3339),
+	}
+
+	var err error
+	export.Days, err = tx.Days("", "")
+	if err != nil {
+		return export, err
+	}
+	for _, day := range export.Days {
+		sets, err := tx.Sets(day.Date)
+		if err != nil {
+			return export, err
+		}
+		if len(sets) == 0 {
+			continue
+		}
+		if export.Sets == nil {
+			export.Sets = map[string][]SetData{}
+		}
+		export.Sets[day.Date] = sets
+	}
+
+	export.Streak, err = computeStreak(tx, loadSettings(tx).DateAt(now))
+	if err != nil {
+		return export, err
+	}
+
+	export.Config.FirstDay, err = getFirstDay(tx)
+	if err != nil {
+		return export, err
+	}
+	export.Config.DaysAtLevel = getDaysAtCurrentLevel(tx)
+	return export, nil
+}
+
+// Export collects the user's days with their sets, streak and config
+func (s *Service) Export(username string) (Export, error) {
+	var export Export
+	err := s.view(username, func(tx *scope) error {
+		var err error
+		export, err = buildExport(tx, username, s.Clock.Now())
+		return err
+	})
+	return export, err
+}
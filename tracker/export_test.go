@@ -0,0 +1,56 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// seedExportData stores a short history for the test user
+func seedExportData(t *testing.T, testDB *bolt.DB) {
+	t.Helper()
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		setFirstDay(tx, "2024-03-08")
+		setDaysAtCurrentLevel(tx, 2)
+		tx.PutDay(DayData{Date: "2024-03-08", Count: 10, Reps: 10, Done: true})
+		tx.AddSet("2024-03-08", SetData{Reps: 10, Time: "2024-03-08T09:00:00Z", Note: "morning"})
+		tx.PutDay(DayData{Date: "2024-03-09", Count: 11, Reps: 4})
+		return tx.PutStreak(StreakData{Current: 1, Longest: 1, LastDate: "2024-03-08"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add test data: %v", err)
+	}
+}
+
+func TestBuildExport(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	seedExportData(t, testDB)
+
+	testDB.View(func(root *bolt.Tx) error {
+		export, err := buildExport(testScope(root), testUser, time.Now())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if export.Version != ExportVersion || export.Username != testUser {
+			t.Errorf("Unexpected header %+v", export)
+		}
+		if len(export.Days) != 2 || export.Days[0].Date != "2024-03-08" || export.Days[1].Reps != 4 {
+			t.Errorf("Expected both days in order, got %+v", export.Days)
+		}
+		if sets := export.Sets["2024-03-08"]; len(export.Sets) != 1 || len(sets) != 1 || sets[0].Note != "morning" {
+			t.Errorf("Expected the sets of the first day, got %+v", export.Sets)
+		}
+		if export.Streak.Longest != 1 || export.Streak.LastDate != "2024-03-08" {
+			t.Errorf("Expected stored streak, got %+v", export.Streak)
+		}
+		if export.Config.FirstDay != "2024-03-08" || export.Config.DaysAtLevel != 2 {
+			t.Errorf("Expected stored config, got %+v", export.Config)
+		}
+		return nil
+	})
+}
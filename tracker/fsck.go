@@ -0,0 +1,225 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Problem is something wrong in a user's data found by Fsck. Check is the check
+// that found it: record, date, reps, target, daysAtLevel, firstDay or streak. Fix is
+// what fixing it does. A warning is only reported, fixing leaves it as it is.
+type Problem struct {
+	Check   string `json:"check"`
+	Key     string `json:"key,omitempty"`
+	Detail  string `json:"detail"`
+	Fix     string `json:"fix"`
+	Warning bool   `json:"warning,omitempty"`
+}
+
+// FsckResult is what Fsck found, Fixed is true if the problems were fixed
+type FsckResult struct {
+	Problems []Problem `json:"problems"`
+	Fixed    bool      `json:"fixed"`
+}
+
+func describeStreak(streak StreakData) string {
+	return fmt.Sprintf("current %d, longest %d, last %q, %d freezes, %d used",
+		streak.Current, streak.Longest, streak.LastDate, streak.Freezes, streak.FreezesUsed)
+}
+
+// fsck checks the user's data and fixes every problem as it is found, so that
+// later checks work on the fixed data
+func fsck(tx *scope, today string) ([]Problem, error) {
+	problems := []Problem{}
+	report := func(check, key, detail, fix string) {
+		problems = append(problems, Problem{Check: check, Key: key, Detail: detail, Fix: fix})
+	}
+	warn := func(check, key, detail string) {
+		problems = append(problems, Problem{Check: check, Key: key, Detail: detail, Fix: "left as it is", Warning: true})
+	}
+
+	// Records that can't be read break everything that reads them
+	damaged, err := tx.Damaged()
+	if err != nil {
+		return problems, err
+	}
+	for _, record := range damaged {
+		report("record", record.Kind+" "+record.Key, record.Err, "delete it")
+		if err := tx.DeleteDamaged(record); err != nil {
+			return problems, err
+		}
+	}
+
+	days, err := tx.Days("", "")
+	if err != nil {
+		return problems, err
+	}
+	for _, dayData := range days {
+		stored, _, err := tx.Day(dayData.Date)
+		if err != nil {
+			return problems, err
+		}
+		if stored.Date != dayData.Date {
+			report("date", dayData.Date, fmt.Sprintf("the record is dated %q", stored.Date), "date it "+dayData.Date)
+			if err := tx.PutDay(dayData); err != nil {
+				return problems, err
+			}
+		}
+	}
+
+	// Targets and reps set by hand are kept
+	entries, err := tx.Audit("")
+	if err != nil {
+		return problems, err
+	}
+	setByHand := map[string]bool{}
+	repsByHand := map[string]bool{}
+	for _, entry := range entries {
+		switch {
+		case entry.Action == "target":
+			setByHand[entry.Date] = true
+		case entry.Action == "edit" && entry.After != nil && (entry.Before == nil || entry.Before.Reps != entry.After.Reps):
+			repsByHand[entry.Date] = true
+		}
+	}
+
+	// The reps of a day are its sets added up. Days without sets, e.g. imported or
+	// from before sets were logged, only have their reps.
+	for i, dayData := range days {
+		if repsByHand[dayData.Date] {
+			continue
+		}
+		sets, err := tx.Sets(dayData.Date)
+		if err != nil {
+			return problems, err
+		}
+		if len(sets) == 0 {
+			continue
+		}
+		sum := 0
+		for _, set := range sets {
+			sum += set.Reps
+		}
+		if sum == dayData.Reps {
+			continue
+		}
+
+		report("reps", dayData.Date, fmt.Sprintf("reps are %d, the sets add up to %d", dayData.Reps, sum),
+			fmt.Sprintf("set them to %d", sum))
+		before := dayData
+		dayData.Reps = sum
+		dayData.Done = dayData.Done || dayData.Reps >= dayData.Count
+		if err := tx.PutDay(dayData); err != nil {
+			return problems, err
+		}
+		if err := addAudit(tx, AuditEntry{Date: dayData.Date, Action: "fsck", Before: &before, After: &dayData}); err != nil {
+			return problems, err
+		}
+		days[i] = dayData
+	}
+
+	// Work out every target again from the first day, like recalculateAfter does,
+	// with the counter of tiers that increase every few days starting at 0. Only
+	// targets below 1 are damaged. Others may come from an import, an older release
+	// or other settings, so they are kept and the days after them follow on.
+	daysAtLevel, err := tx.Config("daysAtLevel")
+	if err != nil {
+		return problems, err
+	}
+	if err := setDaysAtCurrentLevel(tx, 0); err != nil {
+		return problems, err
+	}
+	r := newReplay(tx)
+	for i, dayData := range days {
+		expected, err := r.target(dayData.Date)
+		if err != nil {
+			return problems, err
+		}
+
+		switch {
+		case dayData.Count == expected || setByHand[dayData.Date]:
+		case i == 0 && dayData.Count >= 1:
+			// Any target is fine to start with, e.g. from an import
+		case dayData.Count >= 1:
+			warn("target", dayData.Date, fmt.Sprintf("target is %d, the progression gives %d", dayData.Count, expected))
+		default:
+			report("target", dayData.Date, fmt.Sprintf("target is %d, the progression gives %d", dayData.Count, expected),
+				fmt.Sprintf("set it to %d", expected))
+			before := dayData
+			dayData.Count = expected
+			dayData.Done = dayData.Done || dayData.Reps >= dayData.Count
+			if err := tx.PutDay(dayData); err != nil {
+				return problems, err
+			}
+			if err := addAudit(tx, AuditEntry{Date: dayData.Date, Action: "fsck", Before: &before, After: &dayData}); err != nil {
+				return problems, err
+			}
+		}
+
+		if err := r.add(dayData); err != nil {
+			return problems, err
+		}
+	}
+	replayed := strconv.Itoa(getDaysAtCurrentLevel(tx))
+	if daysAtLevel == "" {
+		daysAtLevel = "0"
+	}
+	if daysAtLevel != replayed {
+		report("daysAtLevel", "", fmt.Sprintf("counter is %q, the history gives %s", daysAtLevel, replayed), "set it to "+replayed)
+	}
+
+	first, err := tx.FirstDate()
+	if err != nil {
+		return problems, err
+	}
+	firstDay, err := getFirstDay(tx)
+	if err != nil {
+		return problems, err
+	}
+	if firstDay != first {
+		report("firstDay", "", fmt.Sprintf("firstDay is %q, the first record is %q", firstDay, first), fmt.Sprintf("set it to %q", first))
+		if err := setFirstDay(tx, first); err != nil {
+			return problems, err
+		}
+	}
+
+	stored, err := tx.Streak()
+	if err != nil {
+		return problems, err
+	}
+	streak, err := computeStreak(tx, today)
+	if err != nil {
+		return problems, err
+	}
+	if stored != streak {
+		report("streak", "", fmt.Sprintf("stored %s, the history gives %s", describeStreak(stored), describeStreak(streak)),
+			"store the one from the history")
+		if err := tx.PutStreak(streak); err != nil {
+			return problems, err
+		}
+	}
+	return problems, nil
+}
+
+// Fsck checks that every record of a user can be read, the reps of each day add
+// up its sets, the targets follow the progression unless they were set by hand, daysAtLevel and firstDay match the
+// history and so does the stored streak. With fix the problems are fixed,
+// otherwise nothing is changed. Targets are worked out with the current settings,
+// so a target that differs is only a warning and is never rewritten, unless it
+// is below 1.
+func (s *Service) Fsck(username string, fix bool) (FsckResult, error) {
+	result := FsckResult{Fixed: fix}
+	err := s.update(username, func(tx *scope) error {
+		var err error
+		result.Problems, err = fsck(tx, userToday(tx))
+		if err == nil && !fix {
+			return errDryRun
+		}
+		return err
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	return result, err
+}
@@ -0,0 +1,249 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// fsckChecks returns the checks of the problems found, in order
+func fsckChecks(problems []Problem) []string {
+	checks := []string{}
+	for _, problem := range problems {
+		checks = append(checks, problem.Check)
+	}
+	return checks
+}
+
+func TestFsck(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	clock := &OffsetClock{}
+	clock.Set(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc := New(NewBoltStore(testDB))
+	svc.Clock = clock
+	svc.Defaults.Timezone = "UTC"
+
+	// Three days completed in a row
+	for i := 0; i < 3; i++ {
+		if _, err := svc.Complete(testUser); err != nil {
+			t.Fatalf("Failed to complete a day: %v", err)
+		}
+		if i < 2 {
+			clock.Shift(24 * time.Hour)
+		}
+	}
+
+	// Test 1: Data written by the tracker has no problems
+	result, err := svc.Fsck(testUser, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Problems) != 0 {
+		t.Fatalf("Expected no problems, got %+v", result.Problems)
+	}
+
+	var second DayData
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		second, _, _ = tx.Day("2024-03-02")
+		tx.PutDay(DayData{Date: "2024-03-02", Count: second.Count + 5, Reps: second.Reps, Done: true})
+		testBucket(root, "Days").Put([]byte("notes"), []byte(`{}`))
+		testBucket(root, "Days").Put([]byte("2024-02-28"), []byte(`{"count":`))
+		setFirstDay(tx, "2024-02-01")
+		return tx.PutStreak(StreakData{Current: 9, Longest: 9})
+	})
+
+	// Test 2: Checking reports the problems without changing anything
+	result, err = svc.Fsck(testUser, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The day after the changed target follows on from the one before the change
+	expected := []string{"record", "record", "target", "target", "firstDay", "streak"}
+	checks := fsckChecks(result.Problems)
+	if len(checks) != len(expected) {
+		t.Fatalf("Expected %v, got %+v", expected, result.Problems)
+	}
+	for i := range expected {
+		if checks[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, checks)
+			break
+		}
+	}
+	if result.Fixed || result.Problems[2].Key != "2024-03-02" || !result.Problems[2].Warning || result.Problems[0].Warning {
+		t.Errorf("Expected an unfixed target warning on 2024-03-02, got %+v", result)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		if testBucket(root, "Days").Get([]byte("notes")) == nil {
+			t.Errorf("Expected the check to keep the damaged records")
+		}
+		if day, _, _ := tx.Day("2024-03-02"); day.Count != second.Count+5 {
+			t.Errorf("Expected the check to keep the target, got %+v", day)
+		}
+		return nil
+	})
+
+	// Test 3: Fixing repairs the data
+	result, err = svc.Fsck(testUser, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.Fixed || len(result.Problems) != len(expected) {
+		t.Errorf("Expected the problems to be fixed, got %+v", result)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		if testBucket(root, "Days").Get([]byte("notes")) != nil || testBucket(root, "Days").Get([]byte("2024-02-28")) != nil {
+			t.Errorf("Expected the damaged records to be deleted")
+		}
+		if day, _, _ := tx.Day("2024-03-02"); day.Count != second.Count+5 {
+			t.Errorf("Expected the target to be kept, got %+v", day)
+		}
+		if firstDay, _ := getFirstDay(tx); firstDay != "2024-03-01" {
+			t.Errorf("Expected firstDay 2024-03-01, got %q", firstDay)
+		}
+		if streak, _ := tx.Streak(); streak.Current != 3 {
+			t.Errorf("Expected a streak of 3, got %+v", streak)
+		}
+		if entries, _ := tx.Audit("2024-03-02"); len(entries) != 0 {
+			t.Errorf("Expected no audit entry for a kept target, got %+v", entries)
+		}
+		return nil
+	})
+
+	result, _ = svc.Fsck(testUser, false)
+	if checks := fsckChecks(result.Problems); len(checks) != 2 || checks[0] != "target" || checks[1] != "target" {
+		t.Errorf("Expected only the target warnings after fixing, got %+v", result.Problems)
+	}
+}
+
+func TestFsckKeepsTargets(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// A history imported from a spreadsheet, with a target damaged to 0
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 30, Reps: 30, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-02", Count: 40, Reps: 40, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-03", Count: 50, Reps: 50, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-04", Count: 0, Reps: 20})
+		setFirstDay(tx, "2024-03-01")
+		return tx.PutStreak(StreakData{Current: 3, Longest: 3, LastDate: "2024-03-03"})
+	})
+
+	svc := New(NewBoltStore(testDB))
+	svc.Clock = &OffsetClock{}
+	svc.Clock.(*OffsetClock).Set(time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC))
+	svc.Defaults.Timezone = "UTC"
+	result, err := svc.Fsck(testUser, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	warnings := 0
+	for _, problem := range result.Problems {
+		if problem.Warning {
+			warnings++
+		}
+	}
+	if len(result.Problems) != 3 || warnings != 2 || result.Problems[2].Key != "2024-03-04" || result.Problems[2].Warning {
+		t.Errorf("Expected two warnings and the damaged target, got %+v", result.Problems)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		for date, count := range map[string]int{"2024-03-01": 30, "2024-03-02": 40, "2024-03-03": 50, "2024-03-04": 51} {
+			if day, _, _ := tx.Day(date); day.Count != count {
+				t.Errorf("Expected target %d on %s, got %+v", count, date, day)
+			}
+		}
+		return nil
+	})
+}
+
+func TestFsckDaysAtLevel(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// A tier that increases every other day
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		setProgressionConfig(tx, ProgressionConfig{Strategy: "steps", Steps: []ProgressionStep{{Below: 100, Increment: 1, Every: 2}}})
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 5, Reps: 5, Done: true})
+		setFirstDay(tx, "2024-03-01")
+		tx.PutStreak(StreakData{Longest: 1, LastDate: "2024-03-01"})
+		return tx.PutConfig("daysAtLevel", "abc")
+	})
+
+	result, err := New(NewBoltStore(testDB)).Fsck(testUser, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	checks := fsckChecks(result.Problems)
+	if len(checks) != 1 || checks[0] != "daysAtLevel" {
+		t.Errorf("Expected a daysAtLevel problem, got %+v", result.Problems)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		if value, _ := testScope(root).Config("daysAtLevel"); value != "0" {
+			t.Errorf("Expected the counter to be reset, got %q", value)
+		}
+		return nil
+	})
+}
+
+func TestFsckSets(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		setFirstDay(tx, "2024-03-01")
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 10, Reps: 7})
+		tx.AddSet("2024-03-01", SetData{Reps: 3, Time: "2024-03-01T08:00:00Z"})
+		tx.AddSet("2024-03-01", SetData{Reps: 4, Time: "2024-03-01T09:00:00Z"})
+		// A key that isn't a set ID next to the valid sets
+		testBucket(root, "Sets").Bucket([]byte("2024-03-01")).Put([]byte("junk"), []byte(`{"reps": 5}`))
+
+		// Reps that drifted from the sets
+		tx.PutDay(DayData{Date: "2024-03-02", Count: 10, Reps: 8})
+		tx.AddSet("2024-03-02", SetData{Reps: 5, Time: "2024-03-02T08:00:00Z"})
+
+		// Reps corrected by hand and a day without sets are left alone
+		tx.PutDay(DayData{Date: "2024-03-03", Count: 10, Reps: 9})
+		tx.AddSet("2024-03-03", SetData{Reps: 2, Time: "2024-03-03T08:00:00Z"})
+		addAudit(tx, AuditEntry{Date: "2024-03-03", Action: "edit", Before: &DayData{Date: "2024-03-03", Count: 10, Reps: 2}, After: &DayData{Date: "2024-03-03", Count: 10, Reps: 9}})
+		tx.PutDay(DayData{Date: "2024-03-04", Count: 10, Reps: 6})
+		return nil
+	})
+
+	svc := New(NewBoltStore(testDB))
+	svc.Clock = &OffsetClock{}
+	svc.Clock.(*OffsetClock).Set(time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC))
+	svc.Defaults.Timezone = "UTC"
+	result, err := svc.Fsck(testUser, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result.Problems) != 2 || result.Problems[0].Key != "set 2024-03-01/0x6a756e6b" || result.Problems[1].Check != "reps" || result.Problems[1].Key != "2024-03-02" {
+		t.Fatalf("Expected the junk key and the reps of 2024-03-02, got %+v", result.Problems)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		if sets, _ := tx.Sets("2024-03-01"); len(sets) != 2 {
+			t.Errorf("Expected the valid sets to be kept, got %+v", sets)
+		}
+		if day, _, _ := tx.Day("2024-03-02"); day.Reps != 5 {
+			t.Errorf("Expected the reps of the sets, got %+v", day)
+		}
+		return nil
+	})
+
+	if result, _ := svc.Fsck(testUser, false); len(result.Problems) != 0 {
+		t.Errorf("Expected no problems after fixing, got %+v", result.Problems)
+	}
+}
@@ -0,0 +1,212 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ImportResult summarises what an import changed, or would change for a dry run
+type ImportResult struct {
+	DryRun      bool       `json:"dryRun"`
+	Conflict    string     `json:"conflict"`
+	Added       int        `json:"added"`
+	Overwritten int        `json:"overwritten"`
+	Merged      int        `json:"merged"`
+	Skipped     int        `json:"skipped"`
+	FirstDay    string     `json:"firstDay"`
+	Streak      StreakData `json:"streak"`
+}
+
+const (
+	// MaxImportCount bounds the target and reps of an imported or edited day to catch typos
+	MaxImportCount = 10000
+	// maxImportErrors is how many invalid days are reported at once
+	maxImportErrors = 10
+)
+
+// ConflictModes lists how a day that already exists can be handled:
+// keep the existing day, replace it, or merge both
+var ConflictModes = []string{"skip", "overwrite", "merge"}
+
+// errDryRun rolls back the transaction of a dry run
+var errDryRun = errors.New("dry run")
+
+// ValidConflictMode reports whether mode is one of ConflictModes
+func ValidConflictMode(mode string) bool {
+	for _, m := range ConflictModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateImport checks every day and the sets logged on them, keyed by date,
+// reporting up to maxImportErrors problems
+func ValidateImport(days []DayData, sets map[string][]SetData, today string) error {
+	var errs []error
+	seen := map[string]bool{}
+
+	for i, day := range days {
+		if len(errs) == maxImportErrors {
+			errs = append(errs, errors.New("too many errors"))
+			break
+		}
+
+		if _, err := time.Parse("2006-01-02", day.Date); err != nil {
+			errs = append(errs, fmt.Errorf("day %d: invalid date %q, expected YYYY-MM-DD", i+1, day.Date))
+			continue
+		}
+		switch {
+		case day.Date > today:
+			errs = append(errs, fmt.Errorf("%s: date is in the future", day.Date))
+		case seen[day.Date]:
+			errs = append(errs, fmt.Errorf("%s: date appears more than once", day.Date))
+		case day.Count < 1 || day.Count > MaxImportCount:
+			errs = append(errs, fmt.Errorf("%s: count must be between 1 and %d", day.Date, MaxImportCount))
+		case day.Reps < 0 || day.Reps > MaxImportCount:
+			errs = append(errs, fmt.Errorf("%s: reps must be between 0 and %d", day.Date, MaxImportCount))
+		case day.Status != "" && !IsDayOff(day):
+			errs = append(errs, fmt.Errorf("%s: status must be empty, %s or %s", day.Date, StatusRest, StatusSick))
+		}
+		seen[day.Date] = true
+	}
+
+	for date, daySets := range sets {
+		if len(errs) >= maxImportErrors {
+			break
+		}
+		if !seen[date] {
+			errs = append(errs, fmt.Errorf("%s: sets for a day that isn't imported", date))
+			continue
+		}
+		for i, set := range daySets {
+			if err := validateImportSet(set); err != nil {
+				errs = append(errs, fmt.Errorf("%s: set %d: %v", date, i+1, err))
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateImportSet checks a set the way AddSet would when it was logged
+func validateImportSet(set SetData) error {
+	if set.Reps <= 0 || set.Reps > MaxSetReps {
+		return fmt.Errorf("reps must be between 1 and %d", MaxSetReps)
+	}
+	if len(set.Note) > MaxNoteLength {
+		return fmt.Errorf("note must be at most %d characters", MaxNoteLength)
+	}
+	if _, err := time.Parse(time.RFC3339, set.Time); err != nil {
+		return fmt.Errorf("invalid time %q", set.Time)
+	}
+	return nil
+}
+
+// mergeDays combines an imported day with an existing one: the existing target is
+// kept, with the higher reps, and the day is done if either of them is
+func mergeDays(existing, imported DayData) DayData {
+	merged := existing
+	if imported.Reps > merged.Reps {
+		merged.Reps = imported.Reps
+	}
+	merged.Done = existing.Done || imported.Done || merged.Reps >= merged.Count
+	return merged
+}
+
+// importSets replaces the sets of a day with imported ones, which get new IDs
+func importSets(tx *scope, date string, sets []SetData) error {
+	if err := tx.DeleteSets(date); err != nil {
+		return err
+	}
+	for _, set := range sets {
+		set.ID = 0
+		if _, err := tx.AddSet(date, set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importDays stores validated days and their sets, then recomputes firstDay and the
+// streak so they match the new history. The sets of a merged day are replaced when
+// its reps are taken from the import.
+func importDays(tx *scope, days []DayData, sets map[string][]SetData, conflict string) (ImportResult, error) {
+	result := ImportResult{Conflict: conflict}
+
+	for _, day := range days {
+		existing, found, err := tx.Day(day.Date)
+		if err != nil {
+			return result, fmt.Errorf("day %s: %v", day.Date, err)
+		}
+		// The logged sets belong to the day whose reps are kept
+		replaceSets := true
+		if found {
+			switch conflict {
+			case "skip":
+				result.Skipped++
+				continue
+			case "overwrite":
+				result.Overwritten++
+			case "merge":
+				replaceSets = day.Reps > existing.Reps
+				day = mergeDays(existing, day)
+				result.Merged++
+			}
+		} else {
+			result.Added++
+		}
+
+		if err := tx.PutDay(day); err != nil {
+			return result, err
+		}
+		if replaceSets {
+			if err := importSets(tx, day.Date, sets[day.Date]); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	firstDay, err := tx.FirstDate()
+	if err != nil {
+		return result, err
+	}
+	if firstDay != "" {
+		result.FirstDay = firstDay
+		if err := setFirstDay(tx, result.FirstDay); err != nil {
+			return result, err
+		}
+	}
+
+	result.Streak, err = updateStreak(tx, userToday(tx))
+	return result, err
+}
+
+// Import validates and stores days in the export format, with the sets logged on
+// them keyed by date. conflict is one of ConflictModes, and a dry run reports the
+// changes without saving them.
+func (s *Service) Import(username string, days []DayData, sets map[string][]SetData, conflict string, dryRun bool) (ImportResult, error) {
+	if !ValidConflictMode(conflict) {
+		return ImportResult{}, fmt.Errorf("conflict must be one of %v", ConflictModes)
+	}
+	if err := ValidateImport(days, sets, s.Date(username)); err != nil {
+		return ImportResult{}, err
+	}
+
+	var result ImportResult
+	err := s.update(username, func(tx *scope) error {
+		var err error
+		result, err = importDays(tx, days, sets, conflict)
+		if err == nil && dryRun {
+			return errDryRun
+		}
+		return err
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	result.DryRun = dryRun
+	return result, err
+}
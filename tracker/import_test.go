@@ -0,0 +1,145 @@
+package tracker
+
+import (
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestValidateImportDays(t *testing.T) {
+	sets := func(sets ...SetData) map[string][]SetData {
+		return map[string][]SetData{"2024-03-08": sets}
+	}
+	day := []DayData{{Date: "2024-03-08", Count: 10, Reps: 12}}
+
+	tests := []struct {
+		name  string
+		days  []DayData
+		sets  map[string][]SetData
+		valid bool
+	}{
+		{"Valid", []DayData{{Date: "2024-03-08", Count: 10, Reps: 12, Done: true}}, nil, true},
+		{"Invalid date", []DayData{{Date: "2024-02-30", Count: 10}}, nil, false},
+		{"Future date", []DayData{{Date: "2024-03-11", Count: 10}}, nil, false},
+		{"Duplicate date", []DayData{{Date: "2024-03-08", Count: 10}, {Date: "2024-03-08", Count: 11}}, nil, false},
+		{"Zero count", []DayData{{Date: "2024-03-08", Count: 0}}, nil, false},
+		{"Negative reps", []DayData{{Date: "2024-03-08", Count: 10, Reps: -1}}, nil, false},
+		{"Rest day", []DayData{{Date: "2024-03-08", Count: 10, Status: StatusRest}}, nil, true},
+		{"Unknown status", []DayData{{Date: "2024-03-08", Count: 10, Status: "holiday"}}, nil, false},
+		{"Sets", day, sets(SetData{Reps: 12, Time: "2024-03-08T09:00:00Z", Note: "morning"}), true},
+		{"Sets of another day", day, map[string][]SetData{"2024-03-09": {{Reps: 12, Time: "2024-03-09T09:00:00Z"}}}, false},
+		{"Set without reps", day, sets(SetData{Time: "2024-03-08T09:00:00Z"}), false},
+		{"Set without a time", day, sets(SetData{Reps: 12}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateImport(tt.days, tt.sets, "2024-03-10")
+			if (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestImportDays(t *testing.T) {
+	imported := []DayData{
+		{Date: "2024-03-05", Count: 8, Reps: 8, Done: true},
+		{Date: "2024-03-06", Count: 9, Reps: 9, Done: true},
+		{Date: "2024-03-07", Count: 10, Reps: 12, Done: true},
+	}
+
+	tests := []struct {
+		conflict string
+		expected DayData
+		longest  int
+	}{
+		{"skip", DayData{Date: "2024-03-07", Count: 10, Reps: 4}, 2},
+		{"overwrite", DayData{Date: "2024-03-07", Count: 10, Reps: 12, Done: true}, 4},
+		{"merge", DayData{Date: "2024-03-07", Count: 10, Reps: 12, Done: true}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.conflict, func(t *testing.T) {
+			testDB := setupTestDB(t)
+			defer cleanupTestDB(t, testDB)
+
+			testDB.Update(func(root *bolt.Tx) error {
+				tx := testScope(root)
+				setFirstDay(tx, "2024-03-07")
+				dayData := DayData{Date: "2024-03-07", Count: 10}
+				recordSet(tx, &dayData, 4, "")
+				tx.PutDay(DayData{Date: "2024-03-08", Count: 11, Reps: 11, Done: true})
+
+				result, err := importDays(tx, imported, nil, tt.conflict)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if result.Added != 2 || result.Skipped+result.Overwritten+result.Merged != 1 {
+					t.Errorf("Expected 2 added and 1 conflict, got %+v", result)
+				}
+				day, _, _ := loadDay(tx, "2024-03-07")
+				if day != tt.expected {
+					t.Errorf("Expected %+v, got %+v", tt.expected, day)
+				}
+
+				// firstDay and the streak follow the imported history
+				if firstDay, _ := getFirstDay(tx); firstDay != "2024-03-05" || result.FirstDay != firstDay {
+					t.Errorf("Expected first day 2024-03-05, got %s", firstDay)
+				}
+				streak, _ := tx.Streak()
+				if streak.Longest != tt.longest || streak.LastDate != "2024-03-08" || streak != result.Streak {
+					t.Errorf("Expected longest streak %d, got %+v", tt.longest, streak)
+				}
+
+				sets, _ := tx.Sets("2024-03-07")
+				if tt.conflict == "overwrite" && len(sets) != 0 {
+					t.Errorf("Expected sets of the overwritten day to be removed, got %d", len(sets))
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestImportSets(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	imported := []DayData{
+		{Date: "2024-03-06", Count: 9, Reps: 9, Done: true},
+		{Date: "2024-03-07", Count: 10, Reps: 12, Done: true},
+		{Date: "2024-03-08", Count: 11, Reps: 2},
+	}
+	sets := map[string][]SetData{
+		"2024-03-06": {{ID: 4, Reps: 5, Time: "2024-03-06T08:00:00Z", Note: "morning"}, {ID: 9, Reps: 4, Time: "2024-03-06T20:00:00Z"}},
+		"2024-03-07": {{Reps: 12, Time: "2024-03-07T08:00:00Z"}},
+		"2024-03-08": {{Reps: 2, Time: "2024-03-08T08:00:00Z"}},
+	}
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		// The import has more reps on the 7th and fewer on the 8th
+		seventh := DayData{Date: "2024-03-07", Count: 10}
+		recordSet(tx, &seventh, 4, "")
+		eighth := DayData{Date: "2024-03-08", Count: 11}
+		recordSet(tx, &eighth, 6, "kept")
+
+		if _, err := importDays(tx, imported, sets, "merge"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		added, _ := tx.Sets("2024-03-06")
+		if len(added) != 2 || added[0].ID != 1 || added[0].Note != "morning" || added[1].Time != "2024-03-06T20:00:00Z" {
+			t.Errorf("Expected the sets of the added day, got %+v", added)
+		}
+		if merged, _ := tx.Sets("2024-03-07"); len(merged) != 1 || merged[0].Reps != 12 {
+			t.Errorf("Expected the imported sets where the imported reps are kept, got %+v", merged)
+		}
+		if kept, _ := tx.Sets("2024-03-08"); len(kept) != 1 || kept[0].Note != "kept" {
+			t.Errorf("Expected the existing sets where the existing reps are kept, got %+v", kept)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,114 @@
+package tracker
+
+import "fmt"
+
+// CopyStore copies the data of every user of src to dst, e.g. from BoltDB to
+// SQLite, and returns the users copied. A user who already has data in dst is
+// an error rather than being merged.
+func CopyStore(dst, src Store) ([]string, error) {
+	usernames, err := src.Users()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, username := range usernames {
+		if err := CopyUser(dst, src, username); err != nil {
+			return usernames[:i], fmt.Errorf("%s: %v", username, err)
+		}
+	}
+	return usernames, nil
+}
+
+// CopyUser copies a user's days, sets, streak, config and audit trail from src to
+// dst, keeping the IDs of sets and audit entries
+func CopyUser(dst, src Store, username string) error {
+	if err := dst.CreateUser(username); err != nil {
+		return err
+	}
+
+	return src.View(username, func(from Tx) error {
+		return dst.Update(username, func(to Tx) error {
+			return copyTx(to, from)
+		})
+	})
+}
+
+func copyTx(to, from Tx) error {
+	if empty, err := isEmpty(to); err != nil || !empty {
+		if err == nil {
+			err = fmt.Errorf("destination already has data")
+		}
+		return err
+	}
+
+	days, err := from.Days("", "")
+	if err != nil {
+		return err
+	}
+	for _, day := range days {
+		if err := to.PutDay(day); err != nil {
+			return err
+		}
+		sets, err := from.Sets(day.Date)
+		if err != nil {
+			return fmt.Errorf("sets of %s: %v", day.Date, err)
+		}
+		for _, set := range sets {
+			if _, err := to.AddSet(day.Date, set); err != nil {
+				return err
+			}
+		}
+	}
+
+	streak, err := from.Streak()
+	if err != nil {
+		return err
+	}
+	if err := to.PutStreak(streak); err != nil {
+		return err
+	}
+	freezeDays, err := from.FreezeDays()
+	if err != nil {
+		return err
+	}
+	if err := to.PutFreezeDays(freezeDays); err != nil {
+		return err
+	}
+
+	keys, err := from.ConfigKeys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		value, err := from.Config(key)
+		if err != nil {
+			return err
+		}
+		if err := to.PutConfig(key, value); err != nil {
+			return err
+		}
+	}
+
+	entries, err := from.Audit("")
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, err := to.AddAudit(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isEmpty reports whether a user has no days, config or audit trail yet
+func isEmpty(tx Tx) (bool, error) {
+	if first, err := tx.FirstDate(); err != nil || first != "" {
+		return false, err
+	}
+	if keys, err := tx.ConfigKeys(); err != nil || len(keys) > 0 {
+		return false, err
+	}
+	entries, err := tx.Audit("")
+	return len(entries) == 0, err
+}
@@ -0,0 +1,135 @@
+package tracker
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+// storeDump is everything a Tx holds for one user
+type storeDump struct {
+	Days       []DayData
+	Sets       map[string][]SetData
+	Streak     StreakData
+	FreezeDays []string
+	Config     map[string]string
+	Audit      []AuditEntry
+}
+
+func dumpStore(t *testing.T, store Store, username string) storeDump {
+	t.Helper()
+	dump := storeDump{Sets: map[string][]SetData{}, Config: map[string]string{}}
+	err := store.View(username, func(tx Tx) error {
+		var err error
+		if dump.Days, err = tx.Days("", ""); err != nil {
+			return err
+		}
+		for _, day := range dump.Days {
+			if dump.Sets[day.Date], err = tx.Sets(day.Date); err != nil {
+				return err
+			}
+		}
+		if dump.Streak, err = tx.Streak(); err != nil {
+			return err
+		}
+		if dump.FreezeDays, err = tx.FreezeDays(); err != nil {
+			return err
+		}
+		keys, err := tx.ConfigKeys()
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			dump.Config[key], _ = tx.Config(key)
+		}
+		dump.Audit, err = tx.Audit("")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", username, err)
+	}
+	return dump
+}
+
+func TestCopyStore(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+	sqliteStore := setupTestSQLite(t)
+	defer cleanupTestSQLite(t, sqliteStore)
+
+	boltStore := NewBoltStore(testDB)
+	boltStore.CreateUser("alice")
+	before := DayData{Date: "2024-03-02", Count: 10}
+	err := boltStore.Update(testUser, func(tx Tx) error {
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 10, Reps: 10, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-02", Count: 11, Reps: 4})
+		tx.PutDay(DayData{Date: "2024-03-03", Count: 11, Status: StatusSick})
+		tx.AddSet("2024-03-01", SetData{Reps: 6, Time: "2024-03-01T08:00:00Z"})
+		tx.AddSet("2024-03-01", SetData{Reps: 4, Time: "2024-03-01T09:00:00Z", Final: true})
+		deleted, _ := tx.AddSet("2024-03-02", SetData{Reps: 1})
+		tx.AddSet("2024-03-02", SetData{Reps: 4, Note: "evening"})
+		tx.DeleteSet("2024-03-02", deleted.ID)
+		tx.PutStreak(StreakData{Current: 1, Longest: 3, LastDate: "2024-03-01", Freezes: 1})
+		tx.PutFreezeDays([]string{"2024-02-28"})
+		tx.PutConfig("firstDay", "2024-02-20")
+		tx.PutConfig("timezone", "Europe/Moscow")
+		tx.AddAudit(AuditEntry{Date: "2024-03-02", Action: "edit", Before: &before, After: &DayData{Date: "2024-03-02", Count: 11, Reps: 4}})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to add test data: %v", err)
+	}
+	boltStore.Update("alice", func(tx Tx) error {
+		return tx.PutDay(DayData{Date: "2024-03-01", Count: 20})
+	})
+
+	// Test 1: BoltDB to SQLite
+	usernames, err := CopyStore(sqliteStore, boltStore)
+	if err != nil {
+		t.Fatalf("Failed to copy to SQLite: %v", err)
+	}
+	if !reflect.DeepEqual(usernames, []string{testUser, "alice"}) {
+		t.Errorf("Expected admin and alice to be copied, got %v", usernames)
+	}
+	for _, username := range usernames {
+		expected := dumpStore(t, boltStore, username)
+		if got := dumpStore(t, sqliteStore, username); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %s's data to be copied as\n%+v\ngot\n%+v", username, expected, got)
+		}
+	}
+
+	// Copied set IDs aren't handed out again
+	sqliteStore.Update(testUser, func(tx Tx) error {
+		if set, _ := tx.AddSet("2024-03-02", SetData{Reps: 1}); set.ID != 3 {
+			t.Errorf("Expected the next set ID to be 3, got %d", set.ID)
+		}
+		return tx.DeleteSet("2024-03-02", 3)
+	})
+
+	// Test 2: Copying again doesn't merge into existing data
+	if _, err := CopyStore(sqliteStore, boltStore); err == nil || !strings.Contains(err.Error(), "already has data") {
+		t.Errorf("Expected an error for a store that already has data, got %v", err)
+	}
+
+	// Test 3: And back from SQLite to a new BoltDB file
+	backDB, err := bolt.Open("test_back.db", 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to create test DB: %v", err)
+	}
+	defer os.Remove("test_back.db")
+	defer backDB.Close()
+
+	back := NewBoltStore(backDB)
+	if _, err := CopyStore(back, sqliteStore); err != nil {
+		t.Fatalf("Failed to copy to BoltDB: %v", err)
+	}
+	for _, username := range usernames {
+		expected := dumpStore(t, boltStore, username)
+		if got := dumpStore(t, back, username); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %s's data to survive the round trip as\n%+v\ngot\n%+v", username, expected, got)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package tracker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultStartTarget is the target of the very first day
+	defaultStartTarget = 10
+	// defaultMaxTarget is the highest daily target any strategy will set
+	defaultMaxTarget = 200
+)
+
+// ProgressionStrategy decides the target for the day after a completed one
+type ProgressionStrategy interface {
+	NextTarget(currentCount int, tx Tx) int
+}
+
+// ProgressionStep is one tier of a step table: targets below Below increase by
+// Increment once every Every completed days
+type ProgressionStep struct {
+	Below     int `json:"below"`
+	Increment int `json:"increment"`
+	Every     int `json:"every"`
+}
+
+// defaultLadder is the original progression: +2 under 50, +1 under 100,
+// +1 every two days under 200
+var defaultLadder = []ProgressionStep{
+	{Below: 50, Increment: 2, Every: 1},
+	{Below: 100, Increment: 1, Every: 1},
+	{Below: 200, Increment: 1, Every: 2},
+}
+
+// StepStrategy applies the tier of a step table that matches the current target.
+// Tiers that only increase every few days keep their counter in the Config bucket.
+type StepStrategy struct {
+	Steps []ProgressionStep
+	Max   int
+}
+
+func (s StepStrategy) NextTarget(currentCount int, tx Tx) int {
+	if currentCount >= s.Max {
+		return s.Max
+	}
+
+	step, ok := findStep(s.Steps, currentCount)
+	if !ok {
+		// Past the last tier, stop increasing
+		return currentCount
+	}
+
+	if step.Every > 1 {
+		daysAtLevel := getDaysAtCurrentLevel(tx)
+		if daysAtLevel < step.Every-1 {
+			// Not enough completed days at this level yet
+			setDaysAtCurrentLevel(tx, daysAtLevel+1)
+			return currentCount
+		}
+		setDaysAtCurrentLevel(tx, 0)
+	}
+
+	return capTarget(currentCount+step.Increment, s.Max)
+}
+
+// LinearStrategy adds the same number of reps after every completed day
+type LinearStrategy struct {
+	Step int
+	Max  int
+}
+
+func (s LinearStrategy) NextTarget(currentCount int, tx Tx) int {
+	return capTarget(currentCount+s.Step, s.Max)
+}
+
+// PercentageStrategy grows the target by a percentage of itself, at least by one rep
+type PercentageStrategy struct {
+	Percent int
+	Max     int
+}
+
+func (s PercentageStrategy) NextTarget(currentCount int, tx Tx) int {
+	increase := int(math.Ceil(float64(currentCount) * float64(s.Percent) / 100))
+	if increase < 1 {
+		increase = 1
+	}
+	return capTarget(currentCount+increase, s.Max)
+}
+
+// FixedStrategy keeps the same target every day
+type FixedStrategy struct {
+	Target int
+	Max    int
+}
+
+func (s FixedStrategy) NextTarget(currentCount int, tx Tx) int {
+	return capTarget(s.Target, s.Max)
+}
+
+// ProgressionConfig selects a strategy and its parameters. It is stored as JSON
+// under the "progression" key of the Config bucket.
+type ProgressionConfig struct {
+	Strategy string            `json:"strategy"`
+	Step     int               `json:"step,omitempty"`
+	Percent  int               `json:"percent,omitempty"`
+	Target   int               `json:"target,omitempty"`
+	Steps    []ProgressionStep `json:"steps,omitempty"`
+}
+
+// ProgressionStrategies lists the names accepted in ProgressionConfig.Strategy
+var ProgressionStrategies = []string{"ladder", "linear", "percentage", "fixed", "steps"}
+
+// Validate checks that the parameters required by the selected strategy are present and sane
+func (c ProgressionConfig) Validate(maxTarget int) error {
+	switch c.Strategy {
+	case "ladder":
+		return nil
+	case "linear":
+		if c.Step < 1 || c.Step > 100 {
+			return errors.New("step must be between 1 and 100")
+		}
+	case "percentage":
+		if c.Percent < 1 || c.Percent > 100 {
+			return errors.New("percent must be between 1 and 100")
+		}
+	case "fixed":
+		if c.Target < 1 || c.Target > maxTarget {
+			return fmt.Errorf("target must be between 1 and %d", maxTarget)
+		}
+	case "steps":
+		return validateSteps(c.Steps, maxTarget)
+	default:
+		return fmt.Errorf("unknown strategy %q, expected one of %v", c.Strategy, ProgressionStrategies)
+	}
+	return nil
+}
+
+// NewStrategy builds the strategy described by the config. The ladder takes its
+// tiers from the settings and every strategy is capped at the maximum target.
+func (c ProgressionConfig) NewStrategy(settings Settings) (ProgressionStrategy, error) {
+	if err := c.Validate(settings.MaxTarget); err != nil {
+		return nil, err
+	}
+
+	switch c.Strategy {
+	case "linear":
+		return LinearStrategy{Step: c.Step, Max: settings.MaxTarget}, nil
+	case "percentage":
+		return PercentageStrategy{Percent: c.Percent, Max: settings.MaxTarget}, nil
+	case "fixed":
+		return FixedStrategy{Target: c.Target, Max: settings.MaxTarget}, nil
+	case "steps":
+		return StepStrategy{Steps: c.Steps, Max: settings.MaxTarget}, nil
+	default:
+		return StepStrategy{Steps: settings.Tiers, Max: settings.MaxTarget}, nil
+	}
+}
+
+func validateSteps(steps []ProgressionStep, maxTarget int) error {
+	if len(steps) == 0 {
+		return errors.New("steps must not be empty")
+	}
+
+	previous := 0
+	for i, step := range steps {
+		if step.Below <= previous || step.Below > maxTarget {
+			return fmt.Errorf("step %d: below must be increasing and at most %d", i+1, maxTarget)
+		}
+		if step.Increment < 1 {
+			return fmt.Errorf("step %d: increment must be at least 1", i+1)
+		}
+		if step.Every < 1 {
+			return fmt.Errorf("step %d: every must be at least 1", i+1)
+		}
+		previous = step.Below
+	}
+	return nil
+}
+
+func findStep(steps []ProgressionStep, currentCount int) (ProgressionStep, bool) {
+	for _, step := range steps {
+		if currentCount < step.Below {
+			return step, true
+		}
+	}
+	return ProgressionStep{}, false
+}
+
+func capTarget(target, maxTarget int) int {
+	if target > maxTarget {
+		return maxTarget
+	}
+	return target
+}
+
+// getProgressionConfig reads the selected strategy, defaulting to the ladder
+func getProgressionConfig(tx *scope) (ProgressionConfig, error) {
+	config := ProgressionConfig{Strategy: "ladder"}
+
+	data, err := tx.Config("progression")
+	if err != nil || data == "" {
+		return config, err
+	}
+
+	err = json.Unmarshal([]byte(data), &config)
+	return config, err
+}
+
+// setProgressionConfig stores the selected strategy and restarts the days-at-level counter
+func setProgressionConfig(tx *scope, config ProgressionConfig) error {
+	jsonData, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	err = tx.PutConfig("progression", string(jsonData))
+	if err != nil {
+		return err
+	}
+	return setDaysAtCurrentLevel(tx, 0)
+}
+
+// calculateTarget calculates the target count based on progression rules (legacy)
+func calculateTarget(startCount, daysSince int) int {
+	target := startCount
+
+	for i := 0; i < daysSince; i++ {
+		step, ok := findStep(defaultLadder, target)
+		if !ok {
+			break
+		}
+		// Tiers with Every > 1 only increase on every Every-th day
+		if step.Every <= 1 || i%step.Every == 0 {
+			target += step.Increment
+		}
+		// When target reaches the maximum, stop increasing
+		if target >= defaultMaxTarget {
+			target = defaultMaxTarget
+			break
+		}
+	}
+
+	return target
+}
+
+// calculateNextTarget calculates the next target after a completed day using the selected strategy
+func calculateNextTarget(currentCount int, tx *scope) int {
+	settings := loadSettings(tx)
+
+	config, err := getProgressionConfig(tx)
+	if err != nil {
+		log.Printf("Error reading progression config, using ladder: %v", err)
+		config = ProgressionConfig{Strategy: "ladder"}
+	}
+
+	strategy, err := config.NewStrategy(settings)
+	if err != nil {
+		log.Printf("Invalid progression config, using ladder: %v", err)
+		strategy = StepStrategy{Steps: settings.Tiers, Max: settings.MaxTarget}
+	}
+
+	return strategy.NextTarget(currentCount, tx)
+}
+
+// calculateTodayTarget works out the target for a new day from the days before it.
+// Days without a record count as skipped, and once DeloadAfter days in a row have been
+// missed the target is reduced by DeloadPercent of the last completed day's target.
+// Rest days don't count as missed.
+func calculateTodayTarget(tx *scope, today string) (int, error) {
+	todayTime, err := time.Parse("2006-01-02", today)
+	if err != nil {
+		return 0, err
+	}
+	days, err := tx.Days("", todayTime.AddDate(0, 0, -1).Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+
+	r := newReplay(tx)
+	for _, dayData := range days {
+		if err := r.add(dayData); err != nil {
+			return 0, err
+		}
+	}
+	return r.target(today)
+}
+
+// replay works out the targets of a history going forward through it once, carrying
+// the previous record, the last completed day and the days missed since then along.
+// Records are added in date order, the target of a day is asked for before its
+// record is added.
+type replay struct {
+	tx       *scope
+	settings Settings
+	previous *DayData
+	lastDone *DayData
+	// missed counts the training days since lastDone up to next
+	missed int
+	next   time.Time
+}
+
+func newReplay(tx *scope) *replay {
+	return &replay{tx: tx, settings: loadSettings(tx)}
+}
+
+// skipTo counts the days without a record before date as missed, unless the
+// schedule makes them rest days
+func (r *replay) skipTo(date time.Time) {
+	if r.previous == nil {
+		r.next = date
+		return
+	}
+	for ; r.next.Before(date); r.next = r.next.AddDate(0, 0, 1) {
+		if !r.settings.isRestWeekday(r.next) {
+			r.missed++
+		}
+	}
+}
+
+// add moves the replay past a day's record
+func (r *replay) add(dayData DayData) error {
+	date, err := time.Parse("2006-01-02", dayData.Date)
+	if err != nil {
+		return err
+	}
+	r.skipTo(date)
+
+	if dayData.Done {
+		r.lastDone = &dayData
+		r.missed = 0
+	} else if !IsDayOff(dayData) {
+		r.missed++
+	}
+	r.previous = &dayData
+	r.next = date.AddDate(0, 0, 1)
+	return nil
+}
+
+// target works out the target of a new day on the given date. Like NextTarget it
+// moves the counter of tiers that increase every few days.
+func (r *replay) target(day string) (int, error) {
+	date, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return 0, err
+	}
+	r.skipTo(date)
+
+	if r.previous == nil {
+		// No earlier days, start over from the starting target
+		return r.settings.StartTarget, nil
+	}
+
+	// The most recent day sets the target, any gap after it counts as skipped
+	target := targetAfter(*r.previous, r.tx)
+	if r.settings.DeloadAfter > 0 && r.lastDone != nil && r.missed >= r.settings.DeloadAfter {
+		deloaded := r.lastDone.Count * (100 - r.settings.DeloadPercent) / 100
+		if deloaded < target {
+			target = deloaded
+		}
+	}
+
+	if target < r.settings.StartTarget {
+		return r.settings.StartTarget, nil
+	}
+	return target, nil
+}
+
+// targetAfter returns the target for the day following the given one,
+// kept within the configured starting and maximum targets
+func targetAfter(day DayData, tx *scope) int {
+	settings := loadSettings(tx)
+
+	var target int
+	if day.Done {
+		// Day was completed, apply progression
+		target = calculateNextTarget(day.Count, tx)
+	} else if IsDayOff(day) {
+		// A rest day keeps the target for the next training day
+		target = day.Count
+	} else {
+		target = calculatePartialTarget(day.Count, day.Reps, settings.StartTarget)
+	}
+
+	if target < settings.StartTarget {
+		return settings.StartTarget
+	}
+	return capTarget(target, settings.MaxTarget)
+}
+
+// calculatePartialTarget calculates the next target after a day that was not completed.
+// A day with no reps is treated as skipped and keeps the same target. A day that got
+// within 10% of the target is retried at the same level, while a bigger shortfall moves
+// the target halfway down towards what was actually done, but not below minTarget.
+func calculatePartialTarget(currentCount, reps, minTarget int) int {
+	if reps <= 0 || reps*10 >= currentCount*9 {
+		return currentCount
+	}
+
+	target := (currentCount + reps + 1) / 2
+	if target < minTarget {
+		target = minTarget
+	}
+	if target > currentCount {
+		return currentCount
+	}
+	return target
+}
+
+// getDaysAtCurrentLevel retrieves the counter for days at current level (for tiers that increase every few days)
+func getDaysAtCurrentLevel(tx Tx) int {
+	data, err := tx.Config("daysAtLevel")
+	if err != nil || data == "" {
+		return 0
+	}
+	days, _ := strconv.Atoi(data)
+	return days
+}
+
+// setDaysAtCurrentLevel sets the counter for days at current level
+func setDaysAtCurrentLevel(tx Tx, days int) error {
+	return tx.PutConfig("daysAtLevel", strconv.Itoa(days))
+}
+
+// Progression returns the user's progression strategy
+func (s *Service) Progression(username string) (ProgressionConfig, error) {
+	var config ProgressionConfig
+	err := s.view(username, func(tx *scope) error {
+		var err error
+		config, err = getProgressionConfig(tx)
+		return err
+	})
+	return config, err
+}
+
+// SaveProgression validates the strategy against the user's maximum target and selects it
+func (s *Service) SaveProgression(username string, config ProgressionConfig) error {
+	return s.update(username, func(tx *scope) error {
+		settings, err := getSettings(tx)
+		if err != nil {
+			return err
+		}
+		if err := config.Validate(settings.MaxTarget); err != nil {
+			return err
+		}
+		return setProgressionConfig(tx, config)
+	})
+}
@@ -0,0 +1,331 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestProgressionStrategies(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	tests := []struct {
+		name     string
+		strategy ProgressionStrategy
+		current  int
+		expected int
+	}{
+		{"Ladder under 50", StepStrategy{Steps: defaultLadder, Max: 200}, 20, 22},
+		{"Ladder under 100", StepStrategy{Steps: defaultLadder, Max: 200}, 60, 61},
+		{"Ladder at cap", StepStrategy{Steps: defaultLadder, Max: 200}, 200, 200},
+		{"Linear", LinearStrategy{Step: 5, Max: 200}, 20, 25},
+		{"Linear capped", LinearStrategy{Step: 5, Max: 200}, 198, 200},
+		{"Percentage", PercentageStrategy{Percent: 10, Max: 200}, 50, 55},
+		{"Percentage rounds up", PercentageStrategy{Percent: 10, Max: 200}, 12, 14},
+		{"Percentage at least one", PercentageStrategy{Percent: 1, Max: 200}, 10, 11},
+		{"Fixed", FixedStrategy{Target: 30, Max: 200}, 12, 30},
+		{"Steps past last tier", StepStrategy{Steps: []ProgressionStep{{Below: 20, Increment: 3, Every: 1}}, Max: 200}, 25, 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDB.Update(func(root *bolt.Tx) error {
+				tx := testScope(root)
+				got := tt.strategy.NextTarget(tt.current, tx)
+				if got != tt.expected {
+					t.Errorf("Expected target %d, got %d", tt.expected, got)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestStepStrategyEvery(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	strategy := StepStrategy{Steps: []ProgressionStep{{Below: 100, Increment: 2, Every: 3}}, Max: 200}
+
+	// Increases only on every third completed day
+	expected := []int{40, 40, 42}
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		for i, want := range expected {
+			got := strategy.NextTarget(40, tx)
+			if got != want {
+				t.Errorf("Day %d: expected %d, got %d", i+1, want, got)
+			}
+		}
+		return nil
+	})
+}
+
+func TestProgressionConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ProgressionConfig
+		wantErr bool
+	}{
+		{"Ladder", ProgressionConfig{Strategy: "ladder"}, false},
+		{"Linear", ProgressionConfig{Strategy: "linear", Step: 3}, false},
+		{"Linear without step", ProgressionConfig{Strategy: "linear"}, true},
+		{"Percentage", ProgressionConfig{Strategy: "percentage", Percent: 5}, false},
+		{"Percentage too high", ProgressionConfig{Strategy: "percentage", Percent: 500}, true},
+		{"Fixed", ProgressionConfig{Strategy: "fixed", Target: 50}, false},
+		{"Fixed above cap", ProgressionConfig{Strategy: "fixed", Target: 500}, true},
+		{"Steps", ProgressionConfig{Strategy: "steps", Steps: []ProgressionStep{{Below: 30, Increment: 3, Every: 1}, {Below: 80, Increment: 1, Every: 2}}}, false},
+		{"Steps empty", ProgressionConfig{Strategy: "steps"}, true},
+		{"Steps not increasing", ProgressionConfig{Strategy: "steps", Steps: []ProgressionStep{{Below: 80, Increment: 1, Every: 1}, {Below: 30, Increment: 1, Every: 1}}}, true},
+		{"Steps zero every", ProgressionConfig{Strategy: "steps", Steps: []ProgressionStep{{Below: 80, Increment: 1}}}, true},
+		{"Unknown", ProgressionConfig{Strategy: "random"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate(200)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCalculateNextTargetUsesConfig(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		// Default is the ladder
+		if got := calculateNextTarget(20, tx); got != 22 {
+			t.Errorf("Expected ladder target 22, got %d", got)
+		}
+
+		setProgressionConfig(tx, ProgressionConfig{Strategy: "linear", Step: 4})
+		if got := calculateNextTarget(20, tx); got != 24 {
+			t.Errorf("Expected linear target 24, got %d", got)
+		}
+
+		// A corrupt config falls back to the ladder
+		tx.PutConfig("progression", "{invalid json}")
+		if got := calculateNextTarget(20, tx); got != 22 {
+			t.Errorf("Expected fallback target 22, got %d", got)
+		}
+		return nil
+	})
+}
+
+func TestProgressiveLoad(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Set first day to 2024-01-01
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		return setFirstDay(tx, "2024-01-01")
+	})
+	if err != nil {
+		t.Fatalf("Failed to set first day: %v", err)
+	}
+
+	// Test different scenarios
+	tests := []struct {
+		name          string
+		targetDate    string
+		expectedCount int
+	}{
+		{"Day 1", "2024-01-01", 10},    // Start at 10
+		{"Day 2", "2024-01-02", 12},    // +2 (10+2)
+		{"Day 10", "2024-01-10", 28},   // +2 each day for 9 days (10+9*2)
+		{"Day 25", "2024-01-25", 54},   // Reached 50, now +1 per day
+		{"Day 65", "2024-03-05", 94},   // Almost at 100
+		{"Day 75", "2024-03-15", 102},  // After 100, +1 every 2 days
+		{"Day 269", "2024-09-26", 200}, // Reached maximum of 200
+		{"Day 365", "2024-12-31", 200}, // Stay at 200 permanently
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var firstDay string
+			testDB.View(func(root *bolt.Tx) error {
+				tx := testScope(root)
+				fd, err := getFirstDay(tx)
+				if err != nil {
+					return err
+				}
+				firstDay = fd
+				return nil
+			})
+
+			firstTime, _ := time.Parse("2006-01-02", firstDay)
+			targetTime, _ := time.Parse("2006-01-02", tt.targetDate)
+
+			daysSince := int(targetTime.Sub(firstTime).Hours() / 24)
+			expected := calculateTarget(10, daysSince)
+
+			if expected != tt.expectedCount {
+				t.Errorf("Expected count %d, got %d", tt.expectedCount, expected)
+			}
+		})
+	}
+}
+
+func TestCalculateTodayTargetDeload(t *testing.T) {
+	putDays := func(t *testing.T, testDB *bolt.DB, days ...DayData) {
+		t.Helper()
+		err := testDB.Update(func(root *bolt.Tx) error {
+			tx := testScope(root)
+			for _, day := range days {
+				if err := tx.PutDay(day); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to add days: %v", err)
+		}
+	}
+
+	targetFor := func(t *testing.T, testDB *bolt.DB, today string) int {
+		t.Helper()
+		var target int
+		err := testDB.Update(func(root *bolt.Tx) error {
+			tx := testScope(root)
+			var err error
+			target, err = calculateTodayTarget(tx, today)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Failed to calculate target: %v", err)
+		}
+		return target
+	}
+
+	t.Run("Short gap keeps progression", func(t *testing.T) {
+		testDB := setupTestDB(t)
+		defer cleanupTestDB(t, testDB)
+
+		putDays(t, testDB, DayData{Date: "2024-03-01", Count: 60, Reps: 60, Done: true})
+
+		// Two days without records, below the default deload threshold of 3
+		if got := targetFor(t, testDB, "2024-03-04"); got != 61 {
+			t.Errorf("Expected 61, got %d", got)
+		}
+	})
+
+	t.Run("Long gap deloads", func(t *testing.T) {
+		testDB := setupTestDB(t)
+		defer cleanupTestDB(t, testDB)
+
+		putDays(t, testDB, DayData{Date: "2024-03-01", Count: 60, Reps: 60, Done: true})
+
+		// A week away reduces the target by 10% instead of resetting it
+		if got := targetFor(t, testDB, "2024-03-09"); got != 54 {
+			t.Errorf("Expected 54, got %d", got)
+		}
+	})
+
+	t.Run("Deload does not compound", func(t *testing.T) {
+		testDB := setupTestDB(t)
+		defer cleanupTestDB(t, testDB)
+
+		putDays(t, testDB,
+			DayData{Date: "2024-03-01", Count: 60, Reps: 60, Done: true},
+			DayData{Date: "2024-03-05", Count: 54},
+			DayData{Date: "2024-03-06", Count: 54},
+		)
+
+		if got := targetFor(t, testDB, "2024-03-07"); got != 54 {
+			t.Errorf("Expected 54, got %d", got)
+		}
+	})
+
+	t.Run("Partial day during absence still lowers target", func(t *testing.T) {
+		testDB := setupTestDB(t)
+		defer cleanupTestDB(t, testDB)
+
+		putDays(t, testDB,
+			DayData{Date: "2024-03-01", Count: 60, Reps: 60, Done: true},
+			DayData{Date: "2024-03-05", Count: 54, Reps: 20},
+		)
+
+		if got := targetFor(t, testDB, "2024-03-06"); got != 37 {
+			t.Errorf("Expected 37, got %d", got)
+		}
+	})
+
+	t.Run("Disabled deload freezes target", func(t *testing.T) {
+		testDB := setupTestDB(t)
+		defer cleanupTestDB(t, testDB)
+
+		putDays(t, testDB, DayData{Date: "2024-03-01", Count: 60, Reps: 60, Done: true})
+		testDB.Update(func(root *bolt.Tx) error {
+			tx := testScope(root)
+			settings := DefaultSettings
+			settings.DeloadAfter = 0
+			return setSettings(tx, settings)
+		})
+
+		if got := targetFor(t, testDB, "2024-03-20"); got != 61 {
+			t.Errorf("Expected 61, got %d", got)
+		}
+	})
+
+	t.Run("No history starts over", func(t *testing.T) {
+		testDB := setupTestDB(t)
+		defer cleanupTestDB(t, testDB)
+
+		if got := targetFor(t, testDB, "2024-03-20"); got != 10 {
+			t.Errorf("Expected 10, got %d", got)
+		}
+	})
+}
+
+func TestCalculatePartialTarget(t *testing.T) {
+	tests := []struct {
+		name     string
+		count    int
+		reps     int
+		expected int
+	}{
+		{"Skipped day keeps target", 40, 0, 40},
+		{"Within 10% keeps target", 40, 36, 40},
+		{"Half done moves halfway down", 40, 20, 30},
+		{"Never drops below 10", 12, 1, 10},
+		{"Tiny target stays put", 10, 3, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calculatePartialTarget(tt.count, tt.reps, 10)
+			if got != tt.expected {
+				t.Errorf("Expected target %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTargetAfterPartialDay(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Completed day applies the regular progression
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		if got := targetAfter(DayData{Count: 20, Reps: 20, Done: true}, tx); got != 22 {
+			t.Errorf("Expected 22 after completed day, got %d", got)
+		}
+		// 90% day is retried, zero day is a skip
+		if got := targetAfter(DayData{Count: 20, Reps: 18}, tx); got != 20 {
+			t.Errorf("Expected 20 after 90%% day, got %d", got)
+		}
+		if got := targetAfter(DayData{Count: 20}, tx); got != 20 {
+			t.Errorf("Expected 20 after skipped day, got %d", got)
+		}
+		return nil
+	})
+}
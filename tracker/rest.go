@@ -0,0 +1,109 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Statuses of a day off. Rest days neither break the streak nor count as missed
+// in the progression, whether they were marked by hand or fall on a scheduled
+// rest weekday.
+const (
+	StatusRest = "rest"
+	StatusSick = "sick"
+)
+
+// ParseWeekday turns a weekday name such as "sunday" into a time.Weekday
+func ParseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+func validateRestDays(days []string) error {
+	seen := map[time.Weekday]bool{}
+	for _, name := range days {
+		day, ok := ParseWeekday(name)
+		if !ok {
+			return fmt.Errorf("invalid weekday %q", name)
+		}
+		if seen[day] {
+			return fmt.Errorf("%s is listed more than once", name)
+		}
+		seen[day] = true
+	}
+	if len(seen) == 7 {
+		return errors.New("at least one day of the week must be a training day")
+	}
+	return nil
+}
+
+// isRestWeekday reports whether the date falls on a weekday off in the weekly schedule
+func (s Settings) isRestWeekday(date time.Time) bool {
+	for _, name := range s.RestDays {
+		if day, ok := ParseWeekday(name); ok && day == date.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDayOff reports whether a day record is a rest or sick day
+func IsDayOff(dayData DayData) bool {
+	return dayData.Status == StatusRest || dayData.Status == StatusSick
+}
+
+// SetStatus marks a day as a rest or sick day, or turns it back into a training
+// day with an empty status. A past day without a record gets the target it
+// would have had. Past days must be within the backfill window, ErrOutsideBackfill
+// is returned for older ones and ErrFutureDate for days after today.
+func (s *Service) SetStatus(username, date, status string) (Day, error) {
+	if status != "" && status != StatusRest && status != StatusSick {
+		return Day{}, fmt.Errorf("status must be %s or %s", StatusRest, StatusSick)
+	}
+
+	var response Day
+	err := s.update(username, func(tx *scope) error {
+		today := userToday(tx)
+		if err := inBackfillWindow(loadSettings(tx), date, today); err != nil {
+			return err
+		}
+
+		dayData, found, err := loadDay(tx, date)
+		if err != nil {
+			return err
+		}
+		var before *DayData
+		if found {
+			previous := dayData
+			before = &previous
+		} else {
+			count, err := calculateTodayTarget(tx, date)
+			if err != nil {
+				return err
+			}
+			dayData = DayData{Date: date, Count: count}
+		}
+
+		dayData.Status = status
+		if err := tx.PutDay(dayData); err != nil {
+			return err
+		}
+		if date < today {
+			if err := afterPastChange(tx, today, "status", before, dayData); err != nil {
+				return err
+			}
+		} else if _, err := updateStreak(tx, today); err != nil {
+			return err
+		}
+
+		response, err = newDay(tx, dayData)
+		return err
+	})
+	return response, err
+}
@@ -0,0 +1,129 @@
+package tracker
+
+import (
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestValidateRestDays(t *testing.T) {
+	tests := []struct {
+		name  string
+		days  []string
+		valid bool
+	}{
+		{"None", nil, true},
+		{"Weekend", []string{"saturday", "Sunday"}, true},
+		{"Unknown day", []string{"caturday"}, false},
+		{"Duplicate", []string{"sunday", "sunday"}, false},
+		{"Whole week", []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRestDays(tt.days)
+			if (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestComputeStreakRestDays(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		settings := DefaultSettings
+		settings.RestDays = []string{"sunday"}
+		settings.FreezeEvery = 0
+		setSettings(tx, settings)
+
+		// 2024-03-03 and 2024-03-10 are Sundays
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 10, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-02", Count: 10, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-04", Count: 10, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-05", Count: 10, Status: StatusSick})
+		tx.PutDay(DayData{Date: "2024-03-06", Count: 10, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-07", Count: 10, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-08", Count: 10, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-09", Count: 10, Done: true})
+
+		// Scheduled and marked days off bridge the run without counting
+		streak, _ := computeStreak(tx, "2024-03-11")
+		if streak.Current != 7 || streak.Longest != 7 {
+			t.Errorf("Expected streak of 7 across rest days, got %+v", streak)
+		}
+
+		// A missed training day still breaks it
+		streak, _ = computeStreak(tx, "2024-03-12")
+		if streak.Current != 0 || streak.Longest != 7 {
+			t.Errorf("Expected broken streak after a missed Monday, got %+v", streak)
+		}
+
+		// A scheduled rest day that got a training record isn't a day off
+		tx.PutDay(DayData{Date: "2024-03-03", Count: 10})
+		streak, _ = computeStreak(tx, "2024-03-11")
+		if streak.Longest != 5 {
+			t.Errorf("Expected the Sunday record to break the run, got %+v", streak)
+		}
+		return nil
+	})
+}
+
+func TestCalculateTodayTargetRestDays(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		settings := DefaultSettings
+		settings.DeloadAfter = 3
+		settings.DeloadPercent = 10
+		setSettings(tx, settings)
+
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 100, Reps: 100, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-02", Count: 101, Status: StatusRest})
+		tx.PutDay(DayData{Date: "2024-03-03", Count: 101, Reps: 50, Status: StatusSick})
+		tx.PutDay(DayData{Date: "2024-03-04", Count: 101, Status: StatusRest})
+
+		// Days off keep the target and don't trigger the deload
+		target, err := calculateTodayTarget(tx, "2024-03-05")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if target != 101 {
+			t.Errorf("Expected target 101 after rest days, got %d", target)
+		}
+
+		// The same gap of training days does
+		for _, date := range []string{"2024-03-02", "2024-03-03", "2024-03-04"} {
+			tx.PutDay(DayData{Date: date, Count: 101})
+		}
+		target, _ = calculateTodayTarget(tx, "2024-03-05")
+		if target != 90 {
+			t.Errorf("Expected deloaded target 90 after missed days, got %d", target)
+		}
+		return nil
+	})
+}
+
+func TestLoadTodayScheduledRest(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		settings := DefaultSettings
+		settings.RestDays = []string{"sunday"}
+		setSettings(tx, settings)
+
+		sunday, _ := loadToday(tx, "2024-03-10")
+		monday, _ := loadToday(tx, "2024-03-11")
+		if sunday.Status != StatusRest || monday.Status != "" {
+			t.Errorf("Expected only Sunday to be a rest day, got %q and %q", sunday.Status, monday.Status)
+		}
+		return nil
+	})
+}
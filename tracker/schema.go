@@ -0,0 +1,163 @@
+package tracker
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Migration upgrades a user's data from the previous schema version to Version.
+// Apply returns a line for every change, so that a dry run can show them.
+type Migration struct {
+	Version int
+	Name    string
+	Apply   func(tx Tx) ([]string, error)
+}
+
+// Migrations are applied in order to data below their version. Records are JSON
+// without a version of their own, so a field added later reads as its zero value
+// in old records, and a migration is needed whenever that zero value is wrong.
+var Migrations = []Migration{
+	{1, "fill in the reps of days completed before reps were logged", migrateCompletedReps},
+	{2, "restore firstDay from the earliest day", migrateFirstDay},
+}
+
+// SchemaVersion is the version of the data this build reads and writes
+var SchemaVersion = Migrations[len(Migrations)-1].Version
+
+// MigrationResult reports the migrations applied to a user's data
+type MigrationResult struct {
+	From    int                `json:"from"`
+	To      int                `json:"to"`
+	Applied []AppliedMigration `json:"applied"`
+	DryRun  bool               `json:"dryRun"`
+}
+
+// AppliedMigration is a migration together with the changes it made
+type AppliedMigration struct {
+	Version int      `json:"version"`
+	Name    string   `json:"name"`
+	Changes []string `json:"changes"`
+}
+
+// getSchemaVersion returns the version of the user's data, 0 for data from before
+// versions were kept
+func getSchemaVersion(tx Tx) (int, error) {
+	value, err := tx.Config("schemaVersion")
+	if err != nil || value == "" {
+		return 0, err
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version %q", value)
+	}
+	return version, nil
+}
+
+func setSchemaVersion(tx Tx, version int) error {
+	return tx.PutConfig("schemaVersion", strconv.Itoa(version))
+}
+
+// migrate applies the migrations the user's data is missing. A new user without
+// any data yet has nothing to migrate and is put at the current version.
+func migrate(tx Tx) (MigrationResult, error) {
+	result := MigrationResult{Applied: []AppliedMigration{}}
+
+	from, err := getSchemaVersion(tx)
+	if err != nil {
+		return result, err
+	}
+	if from > SchemaVersion {
+		return result, fmt.Errorf("data is at schema version %d, newer than the %d of this build", from, SchemaVersion)
+	}
+	if from == 0 {
+		empty, err := isEmpty(tx)
+		if err != nil {
+			return result, err
+		}
+		if empty {
+			result.From, result.To = SchemaVersion, SchemaVersion
+			return result, setSchemaVersion(tx, SchemaVersion)
+		}
+	}
+	result.From, result.To = from, from
+
+	for _, migration := range Migrations {
+		if migration.Version <= from {
+			continue
+		}
+		changes, err := migration.Apply(tx)
+		if err != nil {
+			return result, fmt.Errorf("migration %d: %v", migration.Version, err)
+		}
+		if changes == nil {
+			changes = []string{}
+		}
+		result.Applied = append(result.Applied, AppliedMigration{migration.Version, migration.Name, changes})
+		result.To = migration.Version
+	}
+
+	if result.To == from {
+		return result, nil
+	}
+	return result, setSchemaVersion(tx, result.To)
+}
+
+// Migrate brings the user's data up to SchemaVersion. A dry run reports the
+// changes without saving them.
+func (s *Service) Migrate(username string, dryRun bool) (MigrationResult, error) {
+	var result MigrationResult
+	err := s.Store.Update(username, func(tx Tx) error {
+		var err error
+		result, err = migrate(tx)
+		if err == nil && dryRun {
+			return errDryRun
+		}
+		return err
+	})
+	if err == errDryRun {
+		err = nil
+	}
+	result.DryRun = dryRun
+	return result, err
+}
+
+// migrateCompletedReps sets the reps of completed days without any to the target.
+// Before reps were logged a day only had a target and done, and removing a set
+// from such a day would mark it as missed.
+func migrateCompletedReps(tx Tx) ([]string, error) {
+	var changes []string
+
+	days, err := tx.Days("", "")
+	if err != nil {
+		return nil, err
+	}
+	for _, day := range days {
+		if !day.Done || day.Reps != 0 {
+			continue
+		}
+		day.Reps = day.Count
+		if err := tx.PutDay(day); err != nil {
+			return nil, err
+		}
+		changes = append(changes, fmt.Sprintf("%s: reps 0 -> %d", day.Date, day.Reps))
+	}
+	return changes, nil
+}
+
+// migrateFirstDay sets a missing firstDay to the earliest day. Without it the next
+// new day is taken for the first one and starts over at the starting target.
+func migrateFirstDay(tx Tx) ([]string, error) {
+	firstDay, err := getFirstDay(tx)
+	if err != nil || firstDay != "" {
+		return nil, err
+	}
+
+	days, err := tx.Days("", "")
+	if err != nil || len(days) == 0 {
+		return nil, err
+	}
+	if err := setFirstDay(tx, days[0].Date); err != nil {
+		return nil, err
+	}
+	return []string{"firstDay: set to " + days[0].Date}, nil
+}
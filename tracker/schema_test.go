@@ -0,0 +1,115 @@
+package tracker
+
+import (
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestMigrate(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Data from before reps were logged and without firstDay or a schema version
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 5, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-02", Count: 6, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-03", Count: 7, Reps: 3})
+		tx.PutDay(DayData{Date: "2024-03-04", Count: 7, Reps: 7, Done: true})
+		return nil
+	})
+	svc := New(NewBoltStore(testDB))
+
+	// Test 1: A dry run reports the changes without saving them
+	result, err := svc.Migrate(testUser, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.DryRun || result.From != 0 || result.To != SchemaVersion || len(result.Applied) != len(Migrations) {
+		t.Errorf("Expected every migration from 0 to %d, got %+v", SchemaVersion, result)
+	}
+	if changes := result.Applied[0].Changes; len(changes) != 2 || changes[0] != "2024-03-01: reps 0 -> 5" {
+		t.Errorf("Expected the reps of two days to be filled in, got %v", changes)
+	}
+	if changes := result.Applied[1].Changes; len(changes) != 1 || changes[0] != "firstDay: set to 2024-03-01" {
+		t.Errorf("Expected firstDay to be restored, got %v", changes)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		if version, _ := getSchemaVersion(tx); version != 0 {
+			t.Errorf("Expected the dry run to keep version 0, got %d", version)
+		}
+		if day, _, _ := tx.Day("2024-03-01"); day.Reps != 0 {
+			t.Errorf("Expected the dry run to keep the reps, got %+v", day)
+		}
+		return nil
+	})
+
+	// Test 2: Migrating saves the changes and the version
+	if _, err := svc.Migrate(testUser, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		if version, _ := getSchemaVersion(tx); version != SchemaVersion {
+			t.Errorf("Expected version %d, got %d", SchemaVersion, version)
+		}
+		if day, _, _ := tx.Day("2024-03-02"); day.Reps != 6 {
+			t.Errorf("Expected 6 reps, got %+v", day)
+		}
+		if day, _, _ := tx.Day("2024-03-03"); day.Reps != 3 || day.Done {
+			t.Errorf("Expected the partial day to be kept, got %+v", day)
+		}
+		if firstDay, _ := getFirstDay(tx); firstDay != "2024-03-01" {
+			t.Errorf("Expected firstDay 2024-03-01, got %q", firstDay)
+		}
+		return nil
+	})
+
+	// Test 3: Nothing is left to do
+	result, _ = svc.Migrate(testUser, false)
+	if result.From != SchemaVersion || len(result.Applied) != 0 {
+		t.Errorf("Expected no migrations, got %+v", result)
+	}
+
+	// Test 4: Data from a newer build is refused
+	testDB.Update(func(root *bolt.Tx) error {
+		return setSchemaVersion(testScope(root), SchemaVersion+1)
+	})
+	if _, err := svc.Migrate(testUser, false); err == nil {
+		t.Errorf("Expected error for a newer schema version")
+	}
+}
+
+func TestNewDataIsAtSchemaVersion(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	svc := New(NewBoltStore(testDB))
+	if _, err := svc.Today(testUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result, err := svc.Migrate(testUser, true)
+	if err != nil || result.From != SchemaVersion {
+		t.Errorf("Expected the first day to be at version %d, got %+v (%v)", SchemaVersion, result, err)
+	}
+}
+
+func TestMigrateEmptyData(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// A new user has nothing to migrate, so the data is put at the current version
+	svc := New(NewBoltStore(testDB))
+	result, err := svc.Migrate(testUser, false)
+	if err != nil || result.From != SchemaVersion || result.To != SchemaVersion || len(result.Applied) != 0 {
+		t.Errorf("Expected nothing to migrate, got %+v (%v)", result, err)
+	}
+	testDB.View(func(root *bolt.Tx) error {
+		if version, _ := getSchemaVersion(testScope(root)); version != SchemaVersion {
+			t.Errorf("Expected version %d, got %d", SchemaVersion, version)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,232 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SetData is a single set of reps logged during a day
+type SetData struct {
+	ID   uint64 `json:"id"`
+	Reps int    `json:"reps"`
+	Time string `json:"time"`
+	Note string `json:"note,omitempty"`
+	// Final marks the set logged by completing the day, which undoing the completion removes
+	Final bool `json:"final,omitempty"`
+}
+
+// Day is a day record together with its sets
+type Day struct {
+	DayData
+	Sets      []SetData `json:"sets"`
+	Remaining int       `json:"remaining"`
+}
+
+const (
+	// MaxSetReps bounds a single logged set to catch typos
+	MaxSetReps = 1000
+	// MaxNoteLength bounds the optional note attached to a set
+	MaxNoteLength = 200
+)
+
+// recordSet stores a new set for the day and adds its reps to the day's total.
+// The day is marked as done, and the streak updated, once reps reach the target.
+func recordSet(tx *scope, dayData *DayData, reps int, note string) (SetData, error) {
+	return addSet(tx, dayData, SetData{Reps: reps, Note: note})
+}
+
+// addSet is recordSet for a set with its fields filled in, it assigns the ID and time
+func addSet(tx *scope, dayData *DayData, set SetData) (SetData, error) {
+	set.Time = tx.clock.Now().Format(time.RFC3339)
+	set, err := tx.AddSet(dayData.Date, set)
+	if err != nil {
+		return SetData{}, err
+	}
+
+	wasDone := dayData.Done
+	dayData.Reps += set.Reps
+	if dayData.Reps >= dayData.Count {
+		dayData.Done = true
+	}
+
+	err = tx.PutDay(*dayData)
+	if err != nil {
+		return SetData{}, err
+	}
+
+	// Reaching the target completes the day
+	if !wasDone && dayData.Done {
+		if _, err := updateStreak(tx, userToday(tx)); err != nil {
+			return SetData{}, err
+		}
+	}
+
+	return set, nil
+}
+
+// removeSet deletes a set and takes its reps off the day's total
+func removeSet(tx *scope, dayData *DayData, id uint64) (bool, error) {
+	set, found, err := tx.Set(dayData.Date, id)
+	if err != nil || !found {
+		return false, err
+	}
+
+	err = tx.DeleteSet(dayData.Date, id)
+	if err != nil {
+		return false, err
+	}
+
+	dayData.Reps -= set.Reps
+	if dayData.Reps < 0 {
+		dayData.Reps = 0
+	}
+	wasDone := dayData.Done
+	dayData.Done = dayData.Reps >= dayData.Count
+
+	err = tx.PutDay(*dayData)
+	if err != nil {
+		return false, err
+	}
+
+	// Falling below the target reopens the day, which can break the streak
+	if wasDone && !dayData.Done {
+		if _, err := updateStreak(tx, userToday(tx)); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// newDay attaches the sets and remaining reps to a day record
+func newDay(tx *scope, dayData DayData) (Day, error) {
+	sets, err := tx.Sets(dayData.Date)
+	if err != nil {
+		return Day{}, err
+	}
+
+	remaining := dayData.Count - dayData.Reps
+	if remaining < 0 || dayData.Done {
+		remaining = 0
+	}
+
+	return Day{
+		DayData:   dayData,
+		Sets:      sets,
+		Remaining: remaining,
+	}, nil
+}
+
+// loadDay returns the record for the given date. Today's record is created on
+// demand, past days must already exist.
+func loadDay(tx *scope, date string) (DayData, bool, error) {
+	today := userToday(tx)
+	if date == today {
+		dayData, err := loadToday(tx, today)
+		return dayData, err == nil, err
+	}
+	return tx.Day(date)
+}
+
+// Day returns a day with its sets, found is false for a past day without a record
+func (s *Service) Day(username, date string) (Day, bool, error) {
+	var response Day
+	found := true
+	fn := func(tx *scope) error {
+		var dayData DayData
+		var err error
+		dayData, found, err = loadDay(tx, date)
+		if err != nil || !found {
+			return err
+		}
+		response, err = newDay(tx, dayData)
+		return err
+	}
+
+	// Today's record may need to be created, so only a past day can be read in a view
+	var err error
+	if date != s.Date(username) {
+		err = s.view(username, fn)
+	} else {
+		err = s.update(username, fn)
+	}
+	return response, found, err
+}
+
+// AddSet logs a set of reps, with an optional note, on a day. found is false for
+// a past day without a record. Past days must be within the backfill window,
+// ErrOutsideBackfill is returned for older ones.
+func (s *Service) AddSet(username, date string, reps int, note string) (Day, bool, error) {
+	note = strings.TrimSpace(note)
+	if reps <= 0 || reps > MaxSetReps {
+		return Day{}, false, fmt.Errorf("reps must be between 1 and %d", MaxSetReps)
+	}
+	if len(note) > MaxNoteLength {
+		return Day{}, false, fmt.Errorf("note must be at most %d characters", MaxNoteLength)
+	}
+
+	var response Day
+	found := true
+	err := s.update(username, func(tx *scope) error {
+		var dayData DayData
+		var err error
+		dayData, found, err = loadDay(tx, date)
+		if err != nil || !found {
+			return err
+		}
+		today := userToday(tx)
+		if err := inBackfillWindow(loadSettings(tx), date, today); err != nil {
+			return err
+		}
+
+		before := dayData
+		if _, err := recordSet(tx, &dayData, reps, note); err != nil {
+			return err
+		}
+		if err := afterPastChange(tx, today, "set", &before, dayData); err != nil {
+			return err
+		}
+		response, err = newDay(tx, dayData)
+		return err
+	})
+	return response, found, err
+}
+
+// ErrSetNotFound is returned when deleting a set that doesn't exist
+var ErrSetNotFound = errors.New("set not found")
+
+// DeleteSet removes a set and takes its reps off the day. found is false for a
+// past day without a record, and ErrSetNotFound is returned for an unknown set.
+// Like AddSet it only changes past days within the backfill window.
+func (s *Service) DeleteSet(username, date string, id uint64) (Day, bool, error) {
+	var response Day
+	found := true
+	err := s.update(username, func(tx *scope) error {
+		var dayData DayData
+		var err error
+		dayData, found, err = loadDay(tx, date)
+		if err != nil || !found {
+			return err
+		}
+		today := userToday(tx)
+		if err := inBackfillWindow(loadSettings(tx), date, today); err != nil {
+			return err
+		}
+
+		before := dayData
+		removed, err := removeSet(tx, &dayData, id)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return ErrSetNotFound
+		}
+		if err := afterPastChange(tx, today, "delete-set", &before, dayData); err != nil {
+			return err
+		}
+		response, err = newDay(tx, dayData)
+		return err
+	})
+	return response, found, err
+}
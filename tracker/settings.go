@@ -0,0 +1,177 @@
+package tracker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Settings holds the user-tunable targets. Saved settings are stored as JSON under
+// the "settings" config key and take precedence over the service defaults.
+type Settings struct {
+	StartTarget int               `json:"startTarget"`
+	MaxTarget   int               `json:"maxTarget"`
+	Tiers       []ProgressionStep `json:"tiers"`
+	// DeloadAfter is the number of consecutive missed days after which the target
+	// is reduced by DeloadPercent, 0 disables the deload
+	DeloadAfter   int `json:"deloadAfter"`
+	DeloadPercent int `json:"deloadPercent"`
+	// RestDays are the weekdays off in the weekly schedule, e.g. ["sunday"]
+	RestDays []string `json:"restDays"`
+	// A streak freeze is earned every FreezeEvery completed days, 0 disables them,
+	// and at most FreezeCap can be held. With FreezeAuto a freeze is used up on any
+	// missed day, otherwise only on days picked with Service.Freeze.
+	FreezeEvery int  `json:"freezeEvery"`
+	FreezeCap   int  `json:"freezeCap"`
+	FreezeAuto  bool `json:"freezeAuto"`
+	// BackfillDays is how many days back past days can still be edited, 0 allows only today
+	BackfillDays int `json:"backfillDays"`
+	// Timezone is the IANA time zone days are counted in, e.g. "Asia/Tashkent",
+	// the server's own if empty. A day ends at DayStartHour rather than midnight.
+	Timezone     string `json:"timezone"`
+	DayStartHour int    `json:"dayStartHour"`
+}
+
+const (
+	// maxAllowedTarget bounds MaxTarget to keep the numbers human
+	maxAllowedTarget = 10000
+	// maxDeloadPercent keeps a deload from wiping out all progress
+	maxDeloadPercent = 90
+	// maxFreezeCap bounds how many streak freezes can be saved up
+	maxFreezeCap = 30
+)
+
+// DefaultSettings are used when neither the service nor the user set anything else
+var DefaultSettings = Settings{
+	StartTarget:   defaultStartTarget,
+	MaxTarget:     defaultMaxTarget,
+	Tiers:         defaultLadder,
+	DeloadAfter:   3,
+	DeloadPercent: 10,
+	RestDays:      []string{},
+	FreezeEvery:   7,
+	FreezeCap:     2,
+	FreezeAuto:    true,
+	BackfillDays:  7,
+}
+
+// Validate checks that the targets and tiers are consistent with each other
+func (s Settings) Validate() error {
+	if s.MaxTarget < 1 || s.MaxTarget > maxAllowedTarget {
+		return fmt.Errorf("maxTarget must be between 1 and %d", maxAllowedTarget)
+	}
+	if s.StartTarget < 1 || s.StartTarget > s.MaxTarget {
+		return errors.New("startTarget must be between 1 and maxTarget")
+	}
+	if err := validateSteps(s.Tiers, s.MaxTarget); err != nil {
+		return fmt.Errorf("tiers: %v", err)
+	}
+	if s.DeloadAfter < 0 || s.DeloadAfter > 365 {
+		return errors.New("deloadAfter must be between 0 and 365")
+	}
+	if s.DeloadAfter > 0 && (s.DeloadPercent < 1 || s.DeloadPercent > maxDeloadPercent) {
+		return fmt.Errorf("deloadPercent must be between 1 and %d", maxDeloadPercent)
+	}
+	if err := validateRestDays(s.RestDays); err != nil {
+		return fmt.Errorf("restDays: %v", err)
+	}
+	if s.FreezeEvery < 0 || s.FreezeEvery > 365 {
+		return errors.New("freezeEvery must be between 0 and 365")
+	}
+	if s.FreezeCap < 0 || s.FreezeCap > maxFreezeCap {
+		return fmt.Errorf("freezeCap must be between 0 and %d", maxFreezeCap)
+	}
+	if s.BackfillDays < 0 || s.BackfillDays > 365 {
+		return errors.New("backfillDays must be between 0 and 365")
+	}
+	if err := validateTimezone(s.Timezone); err != nil {
+		return fmt.Errorf("timezone: %v", err)
+	}
+	if s.DayStartHour < 0 || s.DayStartHour > MaxDayStartHour {
+		return fmt.Errorf("dayStartHour must be between 0 and %d", MaxDayStartHour)
+	}
+	return nil
+}
+
+// ParseTiers reads a comma-separated list of below:increment:every triples,
+// e.g. "50:2:1,100:1:1,200:1:2"
+func ParseTiers(value string) ([]ProgressionStep, error) {
+	var tiers []ProgressionStep
+	for _, part := range strings.Split(value, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid tier %q, expected below:increment:every", part)
+		}
+
+		var numbers [3]int
+		for i, field := range fields {
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tier %q: %v", part, err)
+			}
+			numbers[i] = n
+		}
+
+		tiers = append(tiers, ProgressionStep{Below: numbers[0], Increment: numbers[1], Every: numbers[2]})
+	}
+	return tiers, nil
+}
+
+// getSettings returns the saved settings, or the defaults if none were saved
+func getSettings(tx *scope) (Settings, error) {
+	settings := tx.defaults
+
+	data, err := tx.Config("settings")
+	if err != nil || data == "" {
+		return settings, err
+	}
+
+	// Decoding reuses the arrays of the slices, which belong to the defaults
+	settings.Tiers = append(settings.Tiers[:0:0], settings.Tiers...)
+	settings.RestDays = append(settings.RestDays[:0:0], settings.RestDays...)
+	err = json.Unmarshal([]byte(data), &settings)
+	return settings, err
+}
+
+// loadSettings is getSettings for the target calculation, which falls back to the
+// defaults rather than failing on a corrupt record
+func loadSettings(tx *scope) Settings {
+	settings, err := getSettings(tx)
+	if err != nil {
+		log.Printf("Error reading settings, using defaults: %v", err)
+		return tx.defaults
+	}
+	return settings
+}
+
+func setSettings(tx *scope, settings Settings) error {
+	jsonData, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return tx.PutConfig("settings", string(jsonData))
+}
+
+// Settings returns the user's settings
+func (s *Service) Settings(username string) (Settings, error) {
+	var settings Settings
+	err := s.view(username, func(tx *scope) error {
+		var err error
+		settings, err = getSettings(tx)
+		return err
+	})
+	return settings, err
+}
+
+// SaveSettings validates and stores the user's settings
+func (s *Service) SaveSettings(username string, settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	return s.update(username, func(tx *scope) error {
+		return setSettings(tx, settings)
+	})
+}
@@ -0,0 +1,66 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		wantErr  bool
+	}{
+		{"Defaults", DefaultSettings, false},
+		{"Higher start", Settings{StartTarget: 30, MaxTarget: 200, Tiers: defaultLadder}, false},
+		{"Start above max", Settings{StartTarget: 300, MaxTarget: 200, Tiers: defaultLadder}, true},
+		{"Zero max", Settings{StartTarget: 10, MaxTarget: 0, Tiers: defaultLadder}, true},
+		{"Tier above max", Settings{StartTarget: 10, MaxTarget: 150, Tiers: defaultLadder}, true},
+		{"No tiers", Settings{StartTarget: 10, MaxTarget: 200}, true},
+		{"Freeze cap too high", Settings{StartTarget: 10, MaxTarget: 200, Tiers: defaultLadder, FreezeCap: 31}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.settings.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTargetAfterRespectsSettings(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		setSettings(tx, Settings{
+			StartTarget: 25,
+			MaxTarget:   60,
+			Tiers:       []ProgressionStep{{Below: 60, Increment: 5, Every: 1}},
+		})
+
+		// Custom tiers and cap
+		if got := targetAfter(DayData{Date: yesterday, Count: 50, Reps: 50, Done: true}, tx); got != 55 {
+			t.Errorf("Expected 55, got %d", got)
+		}
+		if got := targetAfter(DayData{Date: yesterday, Count: 58, Reps: 58, Done: true}, tx); got != 60 {
+			t.Errorf("Expected target capped at 60, got %d", got)
+		}
+
+		// Targets below the starting target are raised to it
+		if got := targetAfter(DayData{Date: yesterday, Count: 12, Reps: 12, Done: true}, tx); got != 25 {
+			t.Errorf("Expected 25, got %d", got)
+		}
+		if got := targetAfter(DayData{Date: yesterday, Count: 40, Reps: 4}, tx); got != 25 {
+			t.Errorf("Expected partial day to stop at 25, got %d", got)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,593 @@
+package tracker
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteSchema creates the tables of a SQLiteStore. Every row belongs to a user and
+// is deleted together with them.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS users (
+	username TEXT PRIMARY KEY
+);
+CREATE TABLE IF NOT EXISTS days (
+	username TEXT NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+	date     TEXT NOT NULL,
+	count    INTEGER NOT NULL,
+	reps     INTEGER NOT NULL,
+	done     INTEGER NOT NULL,
+	status   TEXT NOT NULL,
+	PRIMARY KEY (username, date)
+);
+CREATE TABLE IF NOT EXISTS sets (
+	username TEXT NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+	date     TEXT NOT NULL,
+	id       INTEGER NOT NULL,
+	reps     INTEGER NOT NULL,
+	time     TEXT NOT NULL,
+	note     TEXT NOT NULL,
+	final    INTEGER NOT NULL,
+	PRIMARY KEY (username, date, id)
+);
+CREATE TABLE IF NOT EXISTS streaks (
+	username     TEXT PRIMARY KEY REFERENCES users (username) ON DELETE CASCADE,
+	current      INTEGER NOT NULL,
+	longest      INTEGER NOT NULL,
+	last_date    TEXT NOT NULL,
+	freezes      INTEGER NOT NULL,
+	freezes_used INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS freeze_days (
+	username TEXT NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+	date     TEXT NOT NULL,
+	PRIMARY KEY (username, date)
+);
+CREATE TABLE IF NOT EXISTS config (
+	username TEXT NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+	key      TEXT NOT NULL,
+	value    TEXT NOT NULL,
+	PRIMARY KEY (username, key)
+);
+CREATE TABLE IF NOT EXISTS audit (
+	username TEXT NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+	id       INTEGER NOT NULL,
+	time     TEXT NOT NULL,
+	date     TEXT NOT NULL,
+	action   TEXT NOT NULL,
+	before   TEXT,
+	after    TEXT,
+	PRIMARY KEY (username, id)
+);
+CREATE TABLE IF NOT EXISTS sequences (
+	username TEXT NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+	name     TEXT NOT NULL,
+	value    INTEGER NOT NULL,
+	PRIMARY KEY (username, name)
+);
+`
+
+// SQLiteStore keeps the tracker data in a SQLite database. It needs a build with
+// cgo, see SQLiteSupported.
+type SQLiteStore struct {
+	DB *sql.DB
+}
+
+// ErrSQLiteUnsupported is returned when opening a SQLite database in a build without cgo
+var ErrSQLiteUnsupported = errors.New("this binary was built without cgo and only supports the bolt driver, SQLite needs CGO_ENABLED=1")
+
+// OpenSQLite opens the SQLite database at path, creating it and its tables if
+// needed. ErrSQLiteUnsupported is returned in a build without cgo.
+func OpenSQLite(path string) (SQLiteStore, error) {
+	if !SQLiteSupported {
+		return SQLiteStore{}, ErrSQLiteUnsupported
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return SQLiteStore{}, err
+	}
+	// A single connection runs one transaction at a time, like BoltDB, so a read
+	// never has to wait for another connection to give up its lock
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return SQLiteStore{}, fmt.Errorf("create tables: %v", err)
+	}
+	return SQLiteStore{DB: db}, nil
+}
+
+func (s SQLiteStore) View(username string, fn func(tx Tx) error) error {
+	return s.run(username, false, fn)
+}
+
+func (s SQLiteStore) Update(username string, fn func(tx Tx) error) error {
+	return s.run(username, true, fn)
+}
+
+// run calls fn in a transaction on the user's rows, which is only committed for
+// an update
+func (s SQLiteStore) run(username string, write bool, fn func(tx Tx) error) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var found int
+	err = tx.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", username).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found == 0 {
+		return ErrUserNotFound
+	}
+
+	if err := fn(sqliteTx{tx: tx, user: username}); err != nil {
+		return err
+	}
+	if !write {
+		return nil
+	}
+	return tx.Commit()
+}
+
+func (s SQLiteStore) CreateUser(username string) error {
+	_, err := s.DB.Exec("INSERT OR IGNORE INTO users (username) VALUES (?)", username)
+	return err
+}
+
+func (s SQLiteStore) DeleteUser(username string) error {
+	_, err := s.DB.Exec("DELETE FROM users WHERE username = ?", username)
+	return err
+}
+
+func (s SQLiteStore) Users() ([]string, error) {
+	usernames := []string{}
+	rows, err := s.DB.Query("SELECT username FROM users ORDER BY username")
+	if err != nil {
+		return usernames, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return usernames, err
+		}
+		usernames = append(usernames, username)
+	}
+	return usernames, rows.Err()
+}
+
+// Backup writes the database through VACUUM INTO, which needs a file of its own
+func (s SQLiteStore) Backup(w io.Writer) error {
+	f, err := os.CreateTemp("", "pushups-*.sqlite")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if _, err := s.DB.Exec("VACUUM INTO ?", f.Name()); err != nil {
+		return err
+	}
+	f, err = os.Open(f.Name())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// sqliteTx is a transaction on the rows of one user
+type sqliteTx struct {
+	tx   *sql.Tx
+	user string
+}
+
+// nextID returns id, or the next value of the named sequence for 0. The sequence
+// is moved past a given id so that it isn't handed out again.
+func (t sqliteTx) nextID(name string, id uint64) (uint64, error) {
+	var seq uint64
+	err := t.tx.QueryRow("SELECT value FROM sequences WHERE username = ? AND name = ?", t.user, name).Scan(&seq)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	if id == 0 {
+		id = seq + 1
+	}
+	if id > seq {
+		_, err = t.tx.Exec("INSERT OR REPLACE INTO sequences (username, name, value) VALUES (?, ?, ?)", t.user, name, id)
+		return id, err
+	}
+	return id, nil
+}
+
+func (t sqliteTx) Day(date string) (DayData, bool, error) {
+	day := DayData{Date: date}
+	err := t.tx.QueryRow("SELECT count, reps, done, status FROM days WHERE username = ? AND date = ?", t.user, date).
+		Scan(&day.Count, &day.Reps, &day.Done, &day.Status)
+	if err == sql.ErrNoRows {
+		return DayData{}, false, nil
+	}
+	return day, err == nil, err
+}
+
+func (t sqliteTx) PutDay(day DayData) error {
+	_, err := t.tx.Exec("INSERT OR REPLACE INTO days (username, date, count, reps, done, status) VALUES (?, ?, ?, ?, ?, ?)",
+		t.user, day.Date, day.Count, day.Reps, day.Done, day.Status)
+	return err
+}
+
+func (t sqliteTx) DeleteDay(date string) error {
+	_, err := t.tx.Exec("DELETE FROM days WHERE username = ? AND date = ?", t.user, date)
+	return err
+}
+
+func (t sqliteTx) Days(from, to string) ([]DayData, error) {
+	days := []DayData{}
+
+	query := "SELECT date, count, reps, done, status FROM days WHERE username = ?"
+	args := []interface{}{t.user}
+	if from != "" {
+		query += " AND date >= ?"
+		args = append(args, from)
+	}
+	if to != "" {
+		query += " AND date <= ?"
+		args = append(args, to)
+	}
+	rows, err := t.tx.Query(query+" ORDER BY date", args...)
+	if err != nil {
+		return days, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day DayData
+		if err := rows.Scan(&day.Date, &day.Count, &day.Reps, &day.Done, &day.Status); err != nil {
+			return days, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+func (t sqliteTx) FirstDate() (string, error) {
+	var date sql.NullString
+	err := t.tx.QueryRow("SELECT MIN(date) FROM days WHERE username = ?", t.user).Scan(&date)
+	return date.String, err
+}
+
+func (t sqliteTx) Sets(date string) ([]SetData, error) {
+	sets := []SetData{}
+	rows, err := t.tx.Query("SELECT id, reps, time, note, final FROM sets WHERE username = ? AND date = ? ORDER BY id", t.user, date)
+	if err != nil {
+		return sets, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var set SetData
+		if err := rows.Scan(&set.ID, &set.Reps, &set.Time, &set.Note, &set.Final); err != nil {
+			return sets, err
+		}
+		sets = append(sets, set)
+	}
+	return sets, rows.Err()
+}
+
+func (t sqliteTx) Set(date string, id uint64) (SetData, bool, error) {
+	set := SetData{ID: id}
+	err := t.tx.QueryRow("SELECT reps, time, note, final FROM sets WHERE username = ? AND date = ? AND id = ?", t.user, date, id).
+		Scan(&set.Reps, &set.Time, &set.Note, &set.Final)
+	if err == sql.ErrNoRows {
+		return SetData{}, false, nil
+	}
+	return set, err == nil, err
+}
+
+func (t sqliteTx) AddSet(date string, set SetData) (SetData, error) {
+	var err error
+	set.ID, err = t.nextID("sets/"+date, set.ID)
+	if err != nil {
+		return set, err
+	}
+	_, err = t.tx.Exec("INSERT OR REPLACE INTO sets (username, date, id, reps, time, note, final) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		t.user, date, set.ID, set.Reps, set.Time, set.Note, set.Final)
+	return set, err
+}
+
+func (t sqliteTx) DeleteSet(date string, id uint64) error {
+	_, err := t.tx.Exec("DELETE FROM sets WHERE username = ? AND date = ? AND id = ?", t.user, date, id)
+	return err
+}
+
+// DeleteSets starts the IDs of the date over, like a new day
+func (t sqliteTx) DeleteSets(date string) error {
+	if _, err := t.tx.Exec("DELETE FROM sets WHERE username = ? AND date = ?", t.user, date); err != nil {
+		return err
+	}
+	_, err := t.tx.Exec("DELETE FROM sequences WHERE username = ? AND name = ?", t.user, "sets/"+date)
+	return err
+}
+
+func (t sqliteTx) Streak() (StreakData, error) {
+	var streak StreakData
+	err := t.tx.QueryRow("SELECT current, longest, last_date, freezes, freezes_used FROM streaks WHERE username = ?", t.user).
+		Scan(&streak.Current, &streak.Longest, &streak.LastDate, &streak.Freezes, &streak.FreezesUsed)
+	if err == sql.ErrNoRows {
+		return StreakData{}, nil
+	}
+	return streak, err
+}
+
+func (t sqliteTx) PutStreak(streak StreakData) error {
+	_, err := t.tx.Exec("INSERT OR REPLACE INTO streaks (username, current, longest, last_date, freezes, freezes_used) VALUES (?, ?, ?, ?, ?, ?)",
+		t.user, streak.Current, streak.Longest, streak.LastDate, streak.Freezes, streak.FreezesUsed)
+	return err
+}
+
+func (t sqliteTx) FreezeDays() ([]string, error) {
+	var days []string
+	rows, err := t.tx.Query("SELECT date FROM freeze_days WHERE username = ? ORDER BY date", t.user)
+	if err != nil {
+		return days, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return days, err
+		}
+		days = append(days, date)
+	}
+	return days, rows.Err()
+}
+
+func (t sqliteTx) PutFreezeDays(days []string) error {
+	if _, err := t.tx.Exec("DELETE FROM freeze_days WHERE username = ?", t.user); err != nil {
+		return err
+	}
+	for _, date := range days {
+		if _, err := t.tx.Exec("INSERT OR IGNORE INTO freeze_days (username, date) VALUES (?, ?)", t.user, date); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t sqliteTx) Config(key string) (string, error) {
+	var value string
+	err := t.tx.QueryRow("SELECT value FROM config WHERE username = ? AND key = ?", t.user, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func (t sqliteTx) PutConfig(key, value string) error {
+	_, err := t.tx.Exec("INSERT OR REPLACE INTO config (username, key, value) VALUES (?, ?, ?)", t.user, key, value)
+	return err
+}
+
+func (t sqliteTx) ConfigKeys() ([]string, error) {
+	keys := []string{}
+	rows, err := t.tx.Query("SELECT key FROM config WHERE username = ? ORDER BY key", t.user)
+	if err != nil {
+		return keys, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return keys, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// auditDay is a day of an audit entry as stored in the before and after columns,
+// JSON or NULL
+func auditDay(day *DayData) (interface{}, error) {
+	if day == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(day)
+	return string(data), err
+}
+
+// parseAuditDay reads a day stored by auditDay
+func parseAuditDay(data sql.NullString) (*DayData, error) {
+	if !data.Valid {
+		return nil, nil
+	}
+	var day DayData
+	if err := json.Unmarshal([]byte(data.String), &day); err != nil {
+		return nil, err
+	}
+	return &day, nil
+}
+
+func (t sqliteTx) AddAudit(entry AuditEntry) (AuditEntry, error) {
+	var err error
+	entry.ID, err = t.nextID("audit", entry.ID)
+	if err != nil {
+		return entry, err
+	}
+	before, err := auditDay(entry.Before)
+	if err != nil {
+		return entry, err
+	}
+	after, err := auditDay(entry.After)
+	if err != nil {
+		return entry, err
+	}
+	_, err = t.tx.Exec("INSERT OR REPLACE INTO audit (username, id, time, date, action, before, after) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		t.user, entry.ID, entry.Time, entry.Date, entry.Action, before, after)
+	return entry, err
+}
+
+func (t sqliteTx) Audit(date string) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+
+	query := "SELECT id, time, date, action, before, after FROM audit WHERE username = ?"
+	args := []interface{}{t.user}
+	if date != "" {
+		query += " AND date = ?"
+		args = append(args, date)
+	}
+	rows, err := t.tx.Query(query+" ORDER BY id", args...)
+	if err != nil {
+		return entries, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry AuditEntry
+		var before, after sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.Time, &entry.Date, &entry.Action, &before, &after); err != nil {
+			return entries, err
+		}
+		if entry.Before, err = parseAuditDay(before); err != nil {
+			return entries, fmt.Errorf("audit %d: %v", entry.ID, err)
+		}
+		if entry.After, err = parseAuditDay(after); err != nil {
+			return entries, fmt.Errorf("audit %d: %v", entry.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// queryStrings returns the first column of the rows of a query
+func (t sqliteTx) queryStrings(query string, args ...interface{}) ([]string, error) {
+	values := []string{}
+	rows, err := t.tx.Query(query, args...)
+	if err != nil {
+		return values, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return values, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// Damaged reads the rows one date or entry at a time. A set that can't be read is
+// listed on its own, so that the other sets of its date are kept.
+func (t sqliteTx) Damaged() ([]DamagedRecord, error) {
+	damaged := []DamagedRecord{}
+	add := func(kind, key string, err error) {
+		damaged = append(damaged, DamagedRecord{Kind: kind, Key: key, Err: err.Error()})
+	}
+
+	dates, err := t.queryStrings("SELECT date FROM days WHERE username = ? ORDER BY date", t.user)
+	if err != nil {
+		return damaged, err
+	}
+	for _, date := range dates {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			add("day", date, fmt.Errorf("key is not a date"))
+		} else if _, _, err := t.Day(date); err != nil {
+			add("day", date, err)
+		}
+	}
+
+	dates, err = t.queryStrings("SELECT DISTINCT date FROM sets WHERE username = ? ORDER BY date", t.user)
+	if err != nil {
+		return damaged, err
+	}
+	for _, date := range dates {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			add("set", date, fmt.Errorf("key is not a date"))
+			continue
+		}
+		if _, err := t.Sets(date); err == nil {
+			continue
+		}
+		ids, err := t.queryStrings("SELECT id FROM sets WHERE username = ? AND date = ? ORDER BY id", t.user, date)
+		if err != nil {
+			return damaged, err
+		}
+		for _, id := range ids {
+			var set SetData
+			err := t.tx.QueryRow("SELECT id, reps, time, note, final FROM sets WHERE username = ? AND date = ? AND id = ?", t.user, date, id).
+				Scan(&set.ID, &set.Reps, &set.Time, &set.Note, &set.Final)
+			if err != nil {
+				add("set", date+"/"+id, err)
+			}
+		}
+	}
+
+	if _, err := t.Streak(); err != nil {
+		add("streak", "current", err)
+	}
+	if _, err := t.FreezeDays(); err != nil {
+		add("streak", "freezes", err)
+	}
+
+	ids, err := t.queryStrings("SELECT id FROM audit WHERE username = ? ORDER BY id", t.user)
+	if err != nil {
+		return damaged, err
+	}
+	for _, id := range ids {
+		var entry AuditEntry
+		var before, after sql.NullString
+		err := t.tx.QueryRow("SELECT id, time, date, action, before, after FROM audit WHERE username = ? AND id = ?", t.user, id).
+			Scan(&entry.ID, &entry.Time, &entry.Date, &entry.Action, &before, &after)
+		if err == nil {
+			_, err = parseAuditDay(before)
+		}
+		if err == nil {
+			_, err = parseAuditDay(after)
+		}
+		if err != nil {
+			add("audit", id, err)
+		}
+	}
+	return damaged, nil
+}
+
+func (t sqliteTx) DeleteDamaged(record DamagedRecord) error {
+	var err error
+	switch record.Kind {
+	case "day":
+		_, err = t.tx.Exec("DELETE FROM days WHERE username = ? AND date = ?", t.user, record.Key)
+	case "set":
+		date, id, found := strings.Cut(record.Key, "/")
+		if !found {
+			return t.DeleteSets(date)
+		}
+		_, err = t.tx.Exec("DELETE FROM sets WHERE username = ? AND date = ? AND id = ?", t.user, date, id)
+	case "streak":
+		if record.Key == "freezes" {
+			_, err = t.tx.Exec("DELETE FROM freeze_days WHERE username = ?", t.user)
+		} else {
+			_, err = t.tx.Exec("DELETE FROM streaks WHERE username = ?", t.user)
+		}
+	case "audit":
+		_, err = t.tx.Exec("DELETE FROM audit WHERE username = ? AND id = ?", t.user, record.Key)
+	default:
+		err = fmt.Errorf("unknown record kind %q", record.Kind)
+	}
+	return err
+}
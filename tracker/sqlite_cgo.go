@@ -0,0 +1,7 @@
+//go:build cgo
+
+package tracker
+
+// SQLiteSupported reports whether SQLite databases can be opened, which needs a
+// build with cgo
+const SQLiteSupported = true
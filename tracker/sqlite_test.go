@@ -0,0 +1,313 @@
+package tracker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupTestSQLite opens a SQLite database with the test user, the test is skipped
+// in a build without cgo
+func setupTestSQLite(t *testing.T) SQLiteStore {
+	t.Helper()
+	if !SQLiteSupported {
+		t.Skip("SQLite needs cgo")
+	}
+	store, err := OpenSQLite("test.sqlite")
+	if err != nil {
+		t.Fatalf("Failed to create test DB: %v", err)
+	}
+	if err := store.CreateUser(testUser); err != nil {
+		t.Fatalf("Failed to setup test DB: %v", err)
+	}
+	return store
+}
+
+func cleanupTestSQLite(t *testing.T, store SQLiteStore) {
+	t.Helper()
+	store.DB.Close()
+	os.Remove("test.sqlite")
+}
+
+func TestSQLiteDays(t *testing.T) {
+	store := setupTestSQLite(t)
+	defer cleanupTestSQLite(t, store)
+
+	err := store.Update(testUser, func(tx Tx) error {
+		for _, date := range []string{"2024-03-03", "2024-03-01", "2024-03-02", "2024-03-05"} {
+			if err := tx.PutDay(DayData{Date: date, Count: 10}); err != nil {
+				return err
+			}
+		}
+		// Putting a day again replaces it
+		return tx.PutDay(DayData{Date: "2024-03-02", Count: 12, Reps: 12, Done: true, Status: StatusRest})
+	})
+	if err != nil {
+		t.Fatalf("Failed to store days: %v", err)
+	}
+
+	store.View(testUser, func(tx Tx) error {
+		day, found, _ := tx.Day("2024-03-02")
+		expected := DayData{Date: "2024-03-02", Count: 12, Reps: 12, Done: true, Status: StatusRest}
+		if !found || day != expected {
+			t.Errorf("Expected %+v, got %+v (found %v)", expected, day, found)
+		}
+		if _, found, _ := tx.Day("2024-03-04"); found {
+			t.Errorf("Expected no record for 2024-03-04")
+		}
+
+		tests := []struct {
+			from, to string
+			expected []string
+		}{
+			{"", "", []string{"2024-03-01", "2024-03-02", "2024-03-03", "2024-03-05"}},
+			{"2024-03-02", "2024-03-03", []string{"2024-03-02", "2024-03-03"}},
+			{"2024-03-04", "", []string{"2024-03-05"}},
+			{"", "2024-02-28", nil},
+		}
+		for _, tt := range tests {
+			days, err := tx.Days(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var dates []string
+			for _, day := range days {
+				dates = append(dates, day.Date)
+			}
+			if len(dates) != len(tt.expected) {
+				t.Errorf("Expected %v from %q to %q, got %v", tt.expected, tt.from, tt.to, dates)
+				continue
+			}
+			for i := range dates {
+				if dates[i] != tt.expected[i] {
+					t.Errorf("Expected %v from %q to %q, got %v", tt.expected, tt.from, tt.to, dates)
+					break
+				}
+			}
+		}
+
+		if first, _ := tx.FirstDate(); first != "2024-03-01" {
+			t.Errorf("Expected first date 2024-03-01, got %s", first)
+		}
+		return nil
+	})
+}
+
+func TestSQLiteSets(t *testing.T) {
+	store := setupTestSQLite(t)
+	defer cleanupTestSQLite(t, store)
+
+	store.Update(testUser, func(tx Tx) error {
+		first, _ := tx.AddSet("2024-03-01", SetData{Reps: 10, Note: "morning"})
+		second, _ := tx.AddSet("2024-03-01", SetData{Reps: 5, Final: true})
+		if first.ID != 1 || second.ID != 2 {
+			t.Errorf("Expected IDs 1 and 2, got %d and %d", first.ID, second.ID)
+		}
+		// Every date counts on its own
+		if other, _ := tx.AddSet("2024-03-02", SetData{Reps: 3}); other.ID != 1 {
+			t.Errorf("Expected ID 1 on another date, got %d", other.ID)
+		}
+
+		tx.DeleteSet("2024-03-01", first.ID)
+		if _, found, _ := tx.Set("2024-03-01", first.ID); found {
+			t.Errorf("Expected the deleted set to be gone")
+		}
+		if sets, _ := tx.Sets("2024-03-01"); len(sets) != 1 || sets[0] != second {
+			t.Errorf("Expected only the second set, got %+v", sets)
+		}
+
+		// IDs aren't reused after a deletion, and a given ID moves the sequence
+		if third, _ := tx.AddSet("2024-03-01", SetData{Reps: 1}); third.ID != 3 {
+			t.Errorf("Expected ID 3, got %d", third.ID)
+		}
+		tx.AddSet("2024-03-01", SetData{ID: 10, Reps: 1})
+		if next, _ := tx.AddSet("2024-03-01", SetData{Reps: 1}); next.ID != 11 {
+			t.Errorf("Expected ID 11 after a copied set, got %d", next.ID)
+		}
+
+		tx.DeleteSets("2024-03-01")
+		if sets, _ := tx.Sets("2024-03-01"); len(sets) != 0 {
+			t.Errorf("Expected no sets, got %+v", sets)
+		}
+		return nil
+	})
+}
+
+func TestSQLiteStreakConfigAndAudit(t *testing.T) {
+	store := setupTestSQLite(t)
+	defer cleanupTestSQLite(t, store)
+
+	streak := StreakData{Current: 5, Longest: 10, LastDate: "2024-01-05", Freezes: 1, FreezesUsed: 2}
+	before := DayData{Date: "2024-01-04", Count: 10}
+	err := store.Update(testUser, func(tx Tx) error {
+		if empty, _ := tx.Streak(); empty != (StreakData{}) {
+			t.Errorf("Expected an empty streak, got %+v", empty)
+		}
+		if err := tx.PutStreak(streak); err != nil {
+			return err
+		}
+		if err := tx.PutFreezeDays([]string{"2024-01-02", "2024-01-03"}); err != nil {
+			return err
+		}
+		if err := tx.PutConfig("firstDay", "2024-01-01"); err != nil {
+			return err
+		}
+		if _, err := tx.AddAudit(AuditEntry{Date: "2024-01-04", Action: "edit", Before: &before}); err != nil {
+			return err
+		}
+		_, err := tx.AddAudit(AuditEntry{Date: "2024-01-03", Action: "delete"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to store data: %v", err)
+	}
+
+	store.View(testUser, func(tx Tx) error {
+		if stored, _ := tx.Streak(); stored != streak {
+			t.Errorf("Expected %+v, got %+v", streak, stored)
+		}
+		if days, _ := tx.FreezeDays(); len(days) != 2 || days[1] != "2024-01-03" {
+			t.Errorf("Expected two freeze days, got %v", days)
+		}
+		if value, _ := tx.Config("firstDay"); value != "2024-01-01" {
+			t.Errorf("Expected firstDay 2024-01-01, got %q", value)
+		}
+		if value, _ := tx.Config("missing"); value != "" {
+			t.Errorf("Expected an empty value, got %q", value)
+		}
+		if keys, _ := tx.ConfigKeys(); len(keys) != 1 || keys[0] != "firstDay" {
+			t.Errorf("Expected the firstDay key, got %v", keys)
+		}
+
+		entries, _ := tx.Audit("2024-01-04")
+		if len(entries) != 1 || entries[0].ID != 1 || *entries[0].Before != before || entries[0].After != nil {
+			t.Errorf("Expected the edit entry, got %+v", entries)
+		}
+		if entries, _ := tx.Audit(""); len(entries) != 2 || entries[1].ID != 2 {
+			t.Errorf("Expected both entries, got %+v", entries)
+		}
+		return nil
+	})
+}
+
+func TestSQLiteUsers(t *testing.T) {
+	store := setupTestSQLite(t)
+	defer cleanupTestSQLite(t, store)
+
+	if err := store.View("nobody", func(tx Tx) error { return nil }); err != ErrUserNotFound {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+
+	store.CreateUser("alice")
+	store.Update("alice", func(tx Tx) error {
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 10})
+		tx.AddSet("2024-03-01", SetData{Reps: 10})
+		return nil
+	})
+	// Creating an existing user keeps their data
+	store.CreateUser("alice")
+	store.View("alice", func(tx Tx) error {
+		if _, found, _ := tx.Day("2024-03-01"); !found {
+			t.Errorf("Expected alice's day to be kept")
+		}
+		return nil
+	})
+
+	if users, _ := store.Users(); len(users) != 2 || users[0] != testUser || users[1] != "alice" {
+		t.Errorf("Expected admin and alice, got %v", users)
+	}
+
+	// A failed update is rolled back
+	store.Update("alice", func(tx Tx) error {
+		tx.PutDay(DayData{Date: "2024-03-02", Count: 10})
+		return ErrUserNotFound
+	})
+	store.View("alice", func(tx Tx) error {
+		if _, found, _ := tx.Day("2024-03-02"); found {
+			t.Errorf("Expected the failed update to be rolled back")
+		}
+		return nil
+	})
+
+	if err := store.DeleteUser("alice"); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	if users, _ := store.Users(); len(users) != 1 {
+		t.Errorf("Expected only admin to be left, got %v", users)
+	}
+	var sets int
+	store.DB.QueryRow("SELECT COUNT(*) FROM sets").Scan(&sets)
+	if sets != 0 {
+		t.Errorf("Expected alice's sets to be deleted with the user, got %d", sets)
+	}
+}
+
+func TestSQLiteBackup(t *testing.T) {
+	store := setupTestSQLite(t)
+	defer cleanupTestSQLite(t, store)
+
+	store.Update(testUser, func(tx Tx) error {
+		return tx.PutDay(DayData{Date: "2024-03-01", Count: 10})
+	})
+
+	file := filepath.Join(t.TempDir(), "backup.sqlite")
+	f, _ := os.Create(file)
+	if err := store.Backup(f); err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+	f.Close()
+
+	backup, err := OpenSQLite(file)
+	if err != nil {
+		t.Fatalf("Failed to open the backup: %v", err)
+	}
+	defer backup.DB.Close()
+	backup.View(testUser, func(tx Tx) error {
+		if _, found, _ := tx.Day("2024-03-01"); !found {
+			t.Errorf("Expected the day to be in the backup")
+		}
+		return nil
+	})
+}
+
+func TestSQLiteDamaged(t *testing.T) {
+	store := setupTestSQLite(t)
+	defer cleanupTestSQLite(t, store)
+
+	store.Update(testUser, func(tx Tx) error {
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 10})
+		tx.PutDay(DayData{Date: "March 2", Count: 10})
+		tx.AddSet("2024-03-01", SetData{Reps: 4, Time: "2024-03-01T08:00:00Z"})
+		tx.AddSet("2024-03-01", SetData{Reps: 6, Time: "2024-03-01T09:00:00Z"})
+		_, err := tx.AddAudit(AuditEntry{Date: "2024-03-01", Action: "edit"})
+		return err
+	})
+	store.DB.Exec("UPDATE audit SET after = '{' WHERE username = ?", testUser)
+	store.DB.Exec("UPDATE sets SET reps = 'six' WHERE username = ? AND id = 2", testUser)
+
+	var damaged []DamagedRecord
+	store.Update(testUser, func(tx Tx) error {
+		damaged, _ = tx.Damaged()
+		for _, record := range damaged {
+			if err := tx.DeleteDamaged(record); err != nil {
+				t.Errorf("Failed to delete %+v: %v", record, err)
+			}
+		}
+		return nil
+	})
+	if len(damaged) != 3 || damaged[0].Key != "March 2" || damaged[1].Key != "2024-03-01/2" || damaged[2].Kind != "audit" {
+		t.Fatalf("Expected the day, the set and the audit entry, got %+v", damaged)
+	}
+
+	store.View(testUser, func(tx Tx) error {
+		days, _ := tx.Days("", "")
+		entries, err := tx.Audit("")
+		if len(days) != 1 || err != nil || len(entries) != 0 {
+			t.Errorf("Expected the damaged records to be deleted, got %+v, %+v (%v)", days, entries, err)
+		}
+		if sets, err := tx.Sets("2024-03-01"); len(sets) != 1 || sets[0].Reps != 4 {
+			t.Errorf("Expected the other set to be kept, got %+v (%v)", sets, err)
+		}
+		return nil
+	})
+}
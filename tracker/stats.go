@@ -0,0 +1,175 @@
+package tracker
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Stats sums up a user's days from From to To. The tracked days and reps are
+// counted by isTracked and countedReps, like on the leaderboard.
+type Stats struct {
+	From           string  `json:"from"`
+	To             string  `json:"to"`
+	TotalReps      int     `json:"totalReps"`
+	CompletedDays  int     `json:"completedDays"`
+	TrackedDays    int     `json:"trackedDays"`
+	CompletionRate float64 `json:"completionRate"`
+	// AverageReps is the average of the completed days
+	AverageReps float64 `json:"averageReps"`
+	// BestWeek, Monday to Sunday, and BestMonth have the most reps, the earliest
+	// one of a tie. They are nil without any reps.
+	BestWeek  *PeriodStats `json:"bestWeek"`
+	BestMonth *PeriodStats `json:"bestMonth"`
+	// Weekdays are the completion rates of each day of the week, Monday first
+	Weekdays []WeekdayStats `json:"weekdays"`
+	// Targets are the target of the first day and of every day it changed on
+	Targets []TargetChange `json:"targets"`
+}
+
+// PeriodStats is the reps of a week or month, from Start to End
+type PeriodStats struct {
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	Reps          int    `json:"reps"`
+	CompletedDays int    `json:"completedDays"`
+}
+
+// WeekdayStats is the completion rate of one day of the week
+type WeekdayStats struct {
+	Weekday        string  `json:"weekday"`
+	CompletedDays  int     `json:"completedDays"`
+	TrackedDays    int     `json:"trackedDays"`
+	CompletionRate float64 `json:"completionRate"`
+}
+
+// TargetChange is the target from a date on
+type TargetChange struct {
+	Date   string `json:"date"`
+	Target int    `json:"target"`
+}
+
+// percentage returns part of total in percent with one decimal, 0 for no total
+func percentage(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 10
+}
+
+// addToPeriod adds a day's reps to the period starting at start in periods
+func addToPeriod(periods map[string]*PeriodStats, start, end time.Time, reps int, done bool) {
+	key := start.Format("2006-01-02")
+	period, ok := periods[key]
+	if !ok {
+		period = &PeriodStats{Start: key, End: end.Format("2006-01-02")}
+		periods[key] = period
+	}
+	period.Reps += reps
+	if done {
+		period.CompletedDays++
+	}
+}
+
+// bestPeriod returns the period with the most reps, the earliest of a tie
+func bestPeriod(periods map[string]*PeriodStats) *PeriodStats {
+	var best *PeriodStats
+	for _, period := range periods {
+		if period.Reps == 0 {
+			continue
+		}
+		if best == nil || period.Reps > best.Reps || (period.Reps == best.Reps && period.Start < best.Start) {
+			best = period
+		}
+	}
+	return best
+}
+
+// computeStats walks the dates from from, or the first record for "", to to
+func computeStats(tx *scope, from, to, today string) (Stats, error) {
+	stats := Stats{From: from, To: to, Weekdays: []WeekdayStats{}, Targets: []TargetChange{}}
+
+	records, err := tx.Days(from, to)
+	if err != nil {
+		return stats, err
+	}
+	if len(records) == 0 {
+		return stats, nil
+	}
+	days := map[string]DayData{}
+	for _, dayData := range records {
+		days[dayData.Date] = dayData
+	}
+	if stats.From == "" || stats.From < records[0].Date {
+		stats.From = records[0].Date
+	}
+
+	settings := loadSettings(tx)
+	weekdays := make([]WeekdayStats, 7)
+	weeks := map[string]*PeriodStats{}
+	months := map[string]*PeriodStats{}
+	completedReps := 0
+
+	start, _ := time.Parse("2006-01-02", stats.From)
+	end, _ := time.Parse("2006-01-02", to)
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		key := date.Format("2006-01-02")
+		dayData, found := days[key]
+
+		// Monday is the first day of the week
+		weekday := (int(date.Weekday()) + 6) % 7
+		if isTracked(settings, date, dayData, found, today) {
+			stats.TrackedDays++
+			weekdays[weekday].TrackedDays++
+		}
+		if !found {
+			continue
+		}
+
+		reps := countedReps(dayData)
+		stats.TotalReps += reps
+		if dayData.Done {
+			stats.CompletedDays++
+			weekdays[weekday].CompletedDays++
+			completedReps += reps
+		}
+
+		monday := date.AddDate(0, 0, -weekday)
+		addToPeriod(weeks, monday, monday.AddDate(0, 0, 6), reps, dayData.Done)
+		first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		addToPeriod(months, first, first.AddDate(0, 1, -1), reps, dayData.Done)
+
+		if len(stats.Targets) == 0 || stats.Targets[len(stats.Targets)-1].Target != dayData.Count {
+			stats.Targets = append(stats.Targets, TargetChange{Date: key, Target: dayData.Count})
+		}
+	}
+
+	stats.CompletionRate = percentage(stats.CompletedDays, stats.TrackedDays)
+	if stats.CompletedDays > 0 {
+		stats.AverageReps = math.Round(float64(completedReps)/float64(stats.CompletedDays)*10) / 10
+	}
+	stats.BestWeek = bestPeriod(weeks)
+	stats.BestMonth = bestPeriod(months)
+	for i := range weekdays {
+		weekdays[i].Weekday = strings.ToLower(time.Weekday((i + 1) % 7).String())
+		weekdays[i].CompletionRate = percentage(weekdays[i].CompletedDays, weekdays[i].TrackedDays)
+	}
+	stats.Weekdays = weekdays
+	return stats, nil
+}
+
+// Stats sums up the user's days from from to to, both included. An empty from
+// starts at the first record and an empty to, or one after today, ends today.
+func (s *Service) Stats(username, from, to string) (Stats, error) {
+	var stats Stats
+	err := s.view(username, func(tx *scope) error {
+		today := userToday(tx)
+		if to == "" || to > today {
+			to = today
+		}
+		var err error
+		stats, err = computeStats(tx, from, to, today)
+		return err
+	})
+	return stats, err
+}
@@ -0,0 +1,137 @@
+package tracker
+
+import (
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestComputeStats(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		// Monday 2024-02-26 to Monday 2024-03-04, today
+		tx.PutDay(DayData{Date: "2024-02-26", Count: 10, Reps: 12, Done: true})
+		tx.PutDay(DayData{Date: "2024-02-27", Count: 12, Done: true}) // Completed without logged reps
+		tx.PutDay(DayData{Date: "2024-02-28", Count: 14, Reps: 5})
+		tx.PutDay(DayData{Date: "2024-02-29", Count: 14, Status: "sick"})
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 14, Reps: 14, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-04", Count: 16, Reps: 3})
+
+		stats, err := computeStats(tx, "", "2024-03-04", "2024-03-04")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if stats.From != "2024-02-26" || stats.To != "2024-03-04" {
+			t.Errorf("Expected the range to start at the first record, got %s to %s", stats.From, stats.To)
+		}
+		if stats.TotalReps != 46 || stats.CompletedDays != 3 {
+			t.Errorf("Expected 46 reps on 3 completed days, got %d on %d", stats.TotalReps, stats.CompletedDays)
+		}
+		// The sick day and today in progress aren't tracked, the weekend without records is
+		if stats.TrackedDays != 6 || stats.CompletionRate != 50 {
+			t.Errorf("Expected 6 tracked days at 50%%, got %d at %v", stats.TrackedDays, stats.CompletionRate)
+		}
+		if stats.AverageReps != 12.7 {
+			t.Errorf("Expected 12.7 reps on average, got %v", stats.AverageReps)
+		}
+
+		if stats.BestWeek == nil || *stats.BestWeek != (PeriodStats{Start: "2024-02-26", End: "2024-03-03", Reps: 43, CompletedDays: 3}) {
+			t.Errorf("Unexpected best week %+v", stats.BestWeek)
+		}
+		if stats.BestMonth == nil || *stats.BestMonth != (PeriodStats{Start: "2024-02-01", End: "2024-02-29", Reps: 29, CompletedDays: 2}) {
+			t.Errorf("Unexpected best month %+v", stats.BestMonth)
+		}
+
+		if len(stats.Weekdays) != 7 || stats.Weekdays[0].Weekday != "monday" || stats.Weekdays[6].Weekday != "sunday" {
+			t.Fatalf("Expected the weekdays from Monday to Sunday, got %+v", stats.Weekdays)
+		}
+		if monday := stats.Weekdays[0]; monday.TrackedDays != 1 || monday.CompletionRate != 100 {
+			t.Errorf("Expected Monday at 100%%, got %+v", monday)
+		}
+		if thursday := stats.Weekdays[3]; thursday.TrackedDays != 0 || thursday.CompletionRate != 0 {
+			t.Errorf("Expected no tracked Thursday, got %+v", thursday)
+		}
+
+		expected := []TargetChange{{"2024-02-26", 10}, {"2024-02-27", 12}, {"2024-02-28", 14}, {"2024-03-04", 16}}
+		if len(stats.Targets) != len(expected) {
+			t.Fatalf("Expected targets %v, got %v", expected, stats.Targets)
+		}
+		for i, change := range expected {
+			if stats.Targets[i] != change {
+				t.Errorf("Expected target %v, got %v", change, stats.Targets[i])
+			}
+		}
+
+		// A range without records
+		stats, err = computeStats(tx, "2024-01-01", "2024-01-31", "2024-03-04")
+		if err != nil || stats.TrackedDays != 0 || stats.BestWeek != nil || len(stats.Weekdays) != 0 {
+			t.Errorf("Expected empty stats, got %+v (%v)", stats, err)
+		}
+		return nil
+	})
+}
+
+func TestStatsRestWeekdays(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		settings := DefaultSettings
+		settings.RestDays = []string{"saturday", "sunday"}
+		setSettings(tx, settings)
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 10, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-03", Count: 10, Reps: 10, Done: true}) // Done on a rest day anyway
+
+		stats, _ := computeStats(tx, "2024-02-01", "2024-03-05", "2024-03-06")
+		// Friday, Sunday, Monday and Tuesday, but not Saturday
+		if stats.From != "2024-03-01" || stats.TrackedDays != 4 || stats.CompletionRate != 50 {
+			t.Errorf("Expected 4 tracked days at 50%% from 2024-03-01, got %d at %v from %s",
+				stats.TrackedDays, stats.CompletionRate, stats.From)
+		}
+		return nil
+	})
+}
+
+func TestStatsMatchSummary(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		settings := DefaultSettings
+		settings.RestDays = []string{"sunday"}
+		setSettings(tx, settings)
+		setFirstDay(tx, "2024-03-01")
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 10, Done: true}) // Completed without logged reps
+		tx.PutDay(DayData{Date: "2024-03-02", Count: 10, Reps: 4})
+		tx.PutDay(DayData{Date: "2024-03-05", Count: 10, Status: StatusSick})
+		tx.PutDay(DayData{Date: "2024-03-06", Count: 10, Reps: 12, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-10", Count: 11, Reps: 11, Done: true}) // Done on a rest day
+		tx.PutDay(DayData{Date: "2024-03-12", Count: 11, Reps: 3})              // Today, in progress
+
+		summary, err := summarize(tx, "2024-03-01", "2024-03-12")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		stats, err := computeStats(tx, "2024-03-01", "2024-03-12", "2024-03-12")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// The leaderboard and the statistics count the same days and reps
+		if stats.TotalReps != summary.TotalReps || stats.CompletedDays != summary.CompletedDays ||
+			stats.TrackedDays != summary.TrackedDays || stats.CompletionRate != summary.CompletionRate {
+			t.Errorf("Expected the stats to match the summary %+v, got %+v", summary, stats)
+		}
+		// 1-11 March without Sunday the 3rd and the sick day, plus Sunday the 10th
+		if stats.TrackedDays != 9 || stats.TotalReps != 40 {
+			t.Errorf("Expected 9 tracked days and 40 reps, got %d and %d", stats.TrackedDays, stats.TotalReps)
+		}
+		return nil
+	})
+}
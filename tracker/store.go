@@ -0,0 +1,85 @@
+package tracker
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrUserNotFound is returned by a Store for a user that has no data
+var ErrUserNotFound = errors.New("user not found")
+
+// Store keeps the data of every user. Each call runs fn in a transaction on one
+// user's data, which is rolled back if fn returns an error.
+type Store interface {
+	View(username string, fn func(tx Tx) error) error
+	Update(username string, fn func(tx Tx) error) error
+
+	// CreateUser sets up the storage of a new user, keeping any existing data
+	CreateUser(username string) error
+	// DeleteUser removes all of a user's data
+	DeleteUser(username string) error
+	// Users lists the users that have data, in name order
+	Users() ([]string, error)
+	// Backup writes a consistent copy of the whole database, which can be opened
+	// like the original
+	Backup(w io.Writer) error
+}
+
+// Tx reads and writes one user's days, sets, streak, config and audit trail
+type Tx interface {
+	// Day returns the record of a date, found is false if there is none
+	Day(date string) (day DayData, found bool, err error)
+	PutDay(day DayData) error
+	DeleteDay(date string) error
+	// Days returns the records from from to to, both included, in date order.
+	// An empty bound leaves that end open.
+	Days(from, to string) ([]DayData, error)
+	// FirstDate returns the key of the earliest record, empty if there are none
+	FirstDate() (string, error)
+
+	// Sets returns the sets of a date in the order they were added
+	Sets(date string) ([]SetData, error)
+	Set(date string, id uint64) (set SetData, found bool, err error)
+	// AddSet stores a set under the next ID of its date and returns it with the ID.
+	// A set that already has an ID, copied from another store, keeps it.
+	AddSet(date string, set SetData) (SetData, error)
+	DeleteSet(date string, id uint64) error
+	DeleteSets(date string) error
+
+	// Streak returns the stored streak, an empty one if none was stored yet
+	Streak() (StreakData, error)
+	PutStreak(streak StreakData) error
+	// FreezeDays returns the missed days picked to be covered by a streak freeze
+	FreezeDays() ([]string, error)
+	PutFreezeDays(days []string) error
+
+	// Config returns a config value, empty if it was never set
+	Config(key string) (string, error)
+	PutConfig(key, value string) error
+	// ConfigKeys lists the config values that are set, in key order
+	ConfigKeys() ([]string, error)
+
+	// AddAudit appends an entry to the audit trail and returns it with its ID, which
+	// is kept like for AddSet
+	AddAudit(entry AuditEntry) (AuditEntry, error)
+	// Audit returns the entries of a date, or of every date for "", in the order
+	// they were added
+	Audit(date string) ([]AuditEntry, error)
+
+	// Damaged lists the records that can't be read back, such as day keys that
+	// aren't dates or values that don't decode
+	Damaged() ([]DamagedRecord, error)
+	// DeleteDamaged removes a record listed by Damaged
+	DeleteDamaged(record DamagedRecord) error
+}
+
+// DamagedRecord is a stored record that can't be read. Kind is day, set, streak
+// or audit, and Key the date, "date/id" of a set, "current" or "freezes" of the
+// streak, or ID of an audit entry. An ID that isn't a number is given in hex
+// with a 0x prefix. A set key without an ID stands for all sets of the date,
+// when they aren't stored as sets at all.
+type DamagedRecord struct {
+	Kind string `json:"kind"`
+	Key  string `json:"key"`
+	Err  string `json:"error"`
+}
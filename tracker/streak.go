@@ -0,0 +1,167 @@
+package tracker
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// computeStreak derives the streak from the days, see streakHistory
+func computeStreak(tx *scope, today string) (StreakData, error) {
+	streak, _, err := streakHistory(tx, today)
+	return streak, err
+}
+
+// streakHistory walks the history day by day up to today. Current is the run of
+// completed days that is still going, with today counting once it is completed,
+// and LastDate is the last completed day. Rest days neither break nor extend a run,
+// and a missed day is covered by a streak freeze if one is available. It also
+// returns the days that were covered by a freeze.
+func streakHistory(tx *scope, today string) (StreakData, []string, error) {
+	var streak StreakData
+	var frozen []string
+
+	todayTime, err := time.Parse("2006-01-02", today)
+	if err != nil {
+		return streak, nil, err
+	}
+
+	records, err := tx.Days("", today)
+	if err != nil {
+		return streak, nil, err
+	}
+	if len(records) == 0 {
+		return streak, nil, nil
+	}
+	days := map[string]DayData{}
+	for _, dayData := range records {
+		days[dayData.Date] = dayData
+	}
+	first, _ := time.Parse("2006-01-02", records[0].Date)
+
+	picked, err := getFreezeDays(tx)
+	if err != nil {
+		return streak, nil, err
+	}
+
+	settings := loadSettings(tx)
+	run, completed := 0, 0
+	for date := first; !date.After(todayTime); date = date.AddDate(0, 0, 1) {
+		key := date.Format("2006-01-02")
+		dayData, found := days[key]
+
+		switch {
+		case dayData.Done:
+			run++
+			if run > streak.Longest {
+				streak.Longest = run
+			}
+			streak.LastDate = key
+
+			completed++
+			if settings.FreezeEvery > 0 && completed%settings.FreezeEvery == 0 && streak.Freezes < settings.FreezeCap {
+				streak.Freezes++
+			}
+		case key == today:
+			// Today is still open
+		case found && IsDayOff(dayData), !found && settings.isRestWeekday(date):
+		case run > 0 && streak.Freezes > 0 && (settings.FreezeAuto || picked[key]):
+			streak.Freezes--
+			streak.FreezesUsed++
+			frozen = append(frozen, key)
+		default:
+			run = 0
+		}
+	}
+
+	streak.Current = run
+	return streak, frozen, nil
+}
+
+// ErrNoFreeze is returned when a streak freeze can't be used on the requested day
+var ErrNoFreeze = errors.New("no streak freeze can cover this day: it wasn't missed during a streak, or no freeze is available")
+
+// getFreezeDays returns the missed days picked to be covered by a streak freeze
+func getFreezeDays(tx Tx) (map[string]bool, error) {
+	days := map[string]bool{}
+	list, err := tx.FreezeDays()
+	if err != nil {
+		return days, err
+	}
+	for _, date := range list {
+		days[date] = true
+	}
+	return days, nil
+}
+
+func putFreezeDays(tx Tx, days map[string]bool) error {
+	list := make([]string, 0, len(days))
+	for date := range days {
+		list = append(list, date)
+	}
+	sort.Strings(list)
+	return tx.PutFreezeDays(list)
+}
+
+// updateStreak recomputes the streak after days changed and stores it
+func updateStreak(tx *scope, today string) (StreakData, error) {
+	streak, err := computeStreak(tx, today)
+	if err != nil {
+		return streak, err
+	}
+	return streak, tx.PutStreak(streak)
+}
+
+// Streak derives the user's streak from the history, so it is never stale after missed days
+func (s *Service) Streak(username string) (StreakData, error) {
+	var streak StreakData
+	err := s.view(username, func(tx *scope) error {
+		var err error
+		streak, err = computeStreak(tx, userToday(tx))
+		return err
+	})
+	return streak, err
+}
+
+// RebuildStreak recomputes the user's stored streak from their history and
+// returns it together with the one stored before
+func (s *Service) RebuildStreak(username string) (before, after StreakData, err error) {
+	err = s.update(username, func(tx *scope) error {
+		before, _ = tx.Streak()
+		after, err = updateStreak(tx, userToday(tx))
+		return err
+	})
+	return before, after, err
+}
+
+// Freeze uses a streak freeze on a missed day before today. ErrNoFreeze is
+// returned if no freeze can cover it. With FreezeAuto on, freezes are used up
+// without asking.
+func (s *Service) Freeze(username, date string) (StreakData, error) {
+	var streak StreakData
+	err := s.update(username, func(tx *scope) error {
+		picked, err := getFreezeDays(tx)
+		if err != nil {
+			return err
+		}
+		picked[date] = true
+		if err := putFreezeDays(tx, picked); err != nil {
+			return err
+		}
+
+		var frozen []string
+		streak, frozen, err = streakHistory(tx, userToday(tx))
+		if err != nil {
+			return err
+		}
+		covered := false
+		for _, day := range frozen {
+			covered = covered || day == date
+		}
+		if !covered {
+			return ErrNoFreeze
+		}
+		return tx.PutStreak(streak)
+	})
+	return streak, err
+}
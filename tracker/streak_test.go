@@ -0,0 +1,314 @@
+package tracker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestComputeStreak(t *testing.T) {
+	tests := []struct {
+		name     string
+		days     []DayData
+		expected StreakData
+	}{
+		{"No days", nil, StreakData{}},
+		{
+			"Completed today",
+			[]DayData{
+				{Date: "2024-03-09", Done: true},
+				{Date: "2024-03-10", Done: true},
+			},
+			StreakData{Current: 2, Longest: 2, LastDate: "2024-03-10"},
+		},
+		{
+			"Today still open",
+			[]DayData{
+				{Date: "2024-03-08", Done: true},
+				{Date: "2024-03-09", Done: true},
+				{Date: "2024-03-10"},
+			},
+			StreakData{Current: 2, Longest: 2, LastDate: "2024-03-09"},
+		},
+		{
+			"Missed yesterday",
+			[]DayData{
+				{Date: "2024-03-07", Done: true},
+				{Date: "2024-03-08", Done: true},
+				{Date: "2024-03-09"},
+			},
+			StreakData{Current: 0, Longest: 2, LastDate: "2024-03-08"},
+		},
+		{
+			"Gap in the history",
+			[]DayData{
+				{Date: "2024-03-01", Done: true},
+				{Date: "2024-03-02", Done: true},
+				{Date: "2024-03-03", Done: true},
+				{Date: "2024-03-09", Done: true},
+			},
+			StreakData{Current: 1, Longest: 3, LastDate: "2024-03-09"},
+		},
+		{
+			"Across months",
+			[]DayData{
+				{Date: "2024-02-28", Done: true},
+				{Date: "2024-02-29", Done: true},
+				{Date: "2024-03-01", Done: true},
+			},
+			StreakData{Current: 0, Longest: 3, LastDate: "2024-03-01"},
+		},
+		{
+			"Future days are ignored",
+			[]DayData{
+				{Date: "2024-03-10", Done: true},
+				{Date: "2024-03-11", Done: true},
+			},
+			StreakData{Current: 1, Longest: 1, LastDate: "2024-03-10"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDB := setupTestDB(t)
+			defer cleanupTestDB(t, testDB)
+
+			testDB.Update(func(root *bolt.Tx) error {
+				tx := testScope(root)
+				for _, day := range tt.days {
+					day.Count = 10
+					tx.PutDay(day)
+				}
+
+				streak, err := computeStreak(tx, "2024-03-10")
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if streak != tt.expected {
+					t.Errorf("Expected %+v, got %+v", tt.expected, streak)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestStreakFreezes(t *testing.T) {
+	tests := []struct {
+		name     string
+		every    int
+		auto     bool
+		picked   []string
+		expected StreakData
+	}{
+		{"Used automatically", 2, true, nil, StreakData{Current: 6, Longest: 6, LastDate: "2024-03-07", Freezes: 1, FreezesUsed: 1}},
+		{"Not picked", 2, false, nil, StreakData{Current: 2, Longest: 4, LastDate: "2024-03-07", Freezes: 1}},
+		{"Picked", 2, false, []string{"2024-03-05"}, StreakData{Current: 6, Longest: 6, LastDate: "2024-03-07", Freezes: 1, FreezesUsed: 1}},
+		{"Disabled", 0, true, nil, StreakData{Current: 2, Longest: 4, LastDate: "2024-03-07"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDB := setupTestDB(t)
+			defer cleanupTestDB(t, testDB)
+
+			testDB.Update(func(root *bolt.Tx) error {
+				tx := testScope(root)
+				settings := DefaultSettings
+				settings.FreezeEvery = tt.every
+				settings.FreezeCap = 1
+				settings.FreezeAuto = tt.auto
+				setSettings(tx, settings)
+
+				// 2024-03-05 is missed, with a freeze earned by then
+				for _, date := range []string{"2024-03-01", "2024-03-02", "2024-03-03", "2024-03-04", "2024-03-06", "2024-03-07"} {
+					tx.PutDay(DayData{Date: date, Count: 10, Done: true})
+				}
+				picked := map[string]bool{}
+				for _, date := range tt.picked {
+					picked[date] = true
+				}
+				putFreezeDays(tx, picked)
+
+				streak, err := computeStreak(tx, "2024-03-08")
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if streak != tt.expected {
+					t.Errorf("Expected %+v, got %+v", tt.expected, streak)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestUpdateStreak(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	today := time.Now().Format("2006-01-02")
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+
+	// Test case 1: First day (no yesterday data)
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		tx.PutDay(DayData{Date: today, Count: 10, Done: true})
+		updateStreak(tx, today)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to update streak: %v", err)
+	}
+
+	var streak StreakData
+	testDB.View(func(root *bolt.Tx) error {
+		b := testBucket(root, "Streak")
+		data := b.Get([]byte("current"))
+		if data != nil {
+			return json.Unmarshal(data, &streak)
+		}
+		return nil
+	})
+
+	if streak.Current != 1 {
+		t.Errorf("Expected current streak to be 1 for first day, got %d", streak.Current)
+	}
+	if streak.Longest != 1 {
+		t.Errorf("Expected longest streak to be 1 for first day, got %d", streak.Longest)
+	}
+	if streak.LastDate != today {
+		t.Errorf("Expected last date to be %s, got %s", today, streak.LastDate)
+	}
+
+	// Test case 2: Yesterday was completed
+	yesterdayData := DayData{
+		Date:  yesterday,
+		Count: 10,
+		Done:  true,
+	}
+	yesterdayJSON, _ := json.Marshal(yesterdayData)
+
+	err = testDB.Update(func(root *bolt.Tx) error {
+		// Add yesterday's data
+		b := testBucket(root, "Days")
+		err := b.Put([]byte(yesterday), yesterdayJSON)
+		if err != nil {
+			return err
+		}
+
+		// Set initial streak to 1 (yesterday's streak)
+		streak := StreakData{
+			Current:  1,
+			Longest:  1,
+			LastDate: yesterday,
+		}
+		streakJSON, _ := json.Marshal(streak)
+		streakB := testBucket(root, "Streak")
+		err = streakB.Put([]byte("current"), streakJSON)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to add yesterday data and initial streak: %v", err)
+	}
+
+	// Update streak for today
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		updateStreak(tx, today)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to update streak: %v", err)
+	}
+
+	testDB.View(func(root *bolt.Tx) error {
+		b := testBucket(root, "Streak")
+		data := b.Get([]byte("current"))
+		if data != nil {
+			return json.Unmarshal(data, &streak)
+		}
+		return nil
+	})
+
+	if streak.Current != 2 {
+		t.Errorf("Expected current streak to be 2 when yesterday was completed, got %d", streak.Current)
+	}
+
+	// Test case 3: Yesterday was not completed
+	yesterdayNotDone := DayData{
+		Date:  yesterday,
+		Count: 10,
+		Done:  false,
+	}
+	yesterdayNotDoneJSON, _ := json.Marshal(yesterdayNotDone)
+
+	err = testDB.Update(func(root *bolt.Tx) error {
+		// Update yesterday's data to not done
+		b := testBucket(root, "Days")
+		err := b.Put([]byte(yesterday), yesterdayNotDoneJSON)
+		if err != nil {
+			return err
+		}
+
+		// Set initial streak to 1 (yesterday's streak)
+		streak := StreakData{
+			Current:  1,
+			Longest:  1,
+			LastDate: yesterday,
+		}
+		streakJSON, _ := json.Marshal(streak)
+		streakB := testBucket(root, "Streak")
+		err = streakB.Put([]byte("current"), streakJSON)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to update yesterday data and set initial streak: %v", err)
+	}
+
+	// Update streak for today
+	err = testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		updateStreak(tx, today)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to update streak again: %v", err)
+	}
+
+	testDB.View(func(root *bolt.Tx) error {
+		b := testBucket(root, "Streak")
+		data := b.Get([]byte("current"))
+		if data != nil {
+			return json.Unmarshal(data, &streak)
+		}
+		return nil
+	})
+
+	if streak.Current != 1 {
+		t.Errorf("Expected current streak to be 1 when yesterday was not completed, got %d", streak.Current)
+	}
+}
+
+func TestRemoveSetUpdatesStreak(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		dayData := DayData{Date: "2024-03-10", Count: 10}
+		set, _ := recordSet(tx, &dayData, 10, "")
+		if streak, _ := tx.Streak(); streak.Longest != 1 {
+			t.Errorf("Expected streak after completing the day, got %+v", streak)
+		}
+
+		// Deleting the set reopens the day
+		removeSet(tx, &dayData, set.ID)
+		if streak, _ := tx.Streak(); streak.Longest != 0 {
+			t.Errorf("Expected no streak after reopening the day, got %+v", streak)
+		}
+		return nil
+	})
+}
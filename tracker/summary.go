@@ -0,0 +1,100 @@
+package tracker
+
+import "time"
+
+// Summary is a user's standing over a window of days, as ranked on the leaderboard
+type Summary struct {
+	CurrentStreak int `json:"currentStreak"`
+	LongestStreak int `json:"longestStreak"`
+	TotalReps     int `json:"totalReps"`
+	CompletedDays int `json:"completedDays"`
+	TrackedDays   int `json:"trackedDays"`
+	// CompletionRate is the percentage of tracked days that were completed
+	CompletionRate float64 `json:"completionRate"`
+}
+
+// isTracked reports whether a date counts towards the completion rate, dayData
+// being its record if found. Today only counts once it is completed, so the rate
+// doesn't drop while the day is in progress, and days off, marked or from the
+// rest days, only count once they are completed.
+func isTracked(settings Settings, date time.Time, dayData DayData, found bool, today string) bool {
+	if dayData.Done {
+		return true
+	}
+	dayOff := (found && IsDayOff(dayData)) || (!found && settings.isRestWeekday(date))
+	return !dayOff && date.Format("2006-01-02") != today
+}
+
+// countedReps returns the reps a day counts with. Days completed before reps were
+// logged only have the target.
+func countedReps(dayData DayData) int {
+	if dayData.Done && dayData.Reps < dayData.Count {
+		return dayData.Count
+	}
+	return dayData.Reps
+}
+
+// summarize sums up the days from from to today, the tracked days as isTracked
+// counts them
+func summarize(tx *scope, from, today string) (Summary, error) {
+	var summary Summary
+
+	streak, err := computeStreak(tx, today)
+	if err != nil {
+		return summary, err
+	}
+	summary.CurrentStreak = streak.Current
+	summary.LongestStreak = streak.Longest
+
+	firstDay, err := getFirstDay(tx)
+	if err != nil {
+		return summary, err
+	}
+
+	records, err := tx.Days(from, today)
+	if err != nil {
+		return summary, err
+	}
+	days := map[string]DayData{}
+	for _, dayData := range records {
+		days[dayData.Date] = dayData
+		if firstDay == "" || dayData.Date < firstDay {
+			firstDay = dayData.Date
+		}
+
+		summary.TotalReps += countedReps(dayData)
+		if dayData.Done {
+			summary.CompletedDays++
+		}
+	}
+
+	if firstDay != "" {
+		start := from
+		if firstDay > start {
+			start = firstDay
+		}
+		settings := loadSettings(tx)
+		startTime, _ := time.Parse("2006-01-02", start)
+		todayTime, _ := time.Parse("2006-01-02", today)
+		for date := startTime; !date.After(todayTime); date = date.AddDate(0, 0, 1) {
+			dayData, found := days[date.Format("2006-01-02")]
+			if isTracked(settings, date, dayData, found, today) {
+				summary.TrackedDays++
+			}
+		}
+	}
+
+	summary.CompletionRate = percentage(summary.CompletedDays, summary.TrackedDays)
+	return summary, nil
+}
+
+// Summary sums up the user's days from from to to, both given as dates
+func (s *Service) Summary(username, from, to string) (Summary, error) {
+	var summary Summary
+	err := s.view(username, func(tx *scope) error {
+		var err error
+		summary, err = summarize(tx, from, to)
+		return err
+	})
+	return summary, err
+}
@@ -0,0 +1,76 @@
+package tracker
+
+import (
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestSummarize(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		setFirstDay(tx, "2024-03-07")
+		tx.PutDay(DayData{Date: "2024-03-01", Count: 99, Reps: 99, Done: true}) // Before the window
+		tx.PutDay(DayData{Date: "2024-03-07", Count: 10, Done: true})           // Completed without logged reps
+		tx.PutDay(DayData{Date: "2024-03-08", Count: 12, Reps: 6})
+		tx.PutDay(DayData{Date: "2024-03-10", Count: 12, Reps: 14, Done: true})
+		tx.PutDay(DayData{Date: "2024-02-20", Count: 5, Done: true}) // An older, longer streak
+		tx.PutDay(DayData{Date: "2024-02-21", Count: 6, Done: true})
+		tx.PutDay(DayData{Date: "2024-02-22", Count: 7, Done: true})
+
+		summary, err := summarize(tx, "2024-03-04", "2024-03-10")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if summary.TotalReps != 30 {
+			t.Errorf("Expected 30 reps, got %d", summary.TotalReps)
+		}
+		if summary.CompletedDays != 2 {
+			t.Errorf("Expected 2 completed days, got %d", summary.CompletedDays)
+		}
+		// Tracking started on 2024-03-07, so 4 days including today
+		if summary.TrackedDays != 4 || summary.CompletionRate != 50 {
+			t.Errorf("Expected 4 tracked days at 50%%, got %d at %v", summary.TrackedDays, summary.CompletionRate)
+		}
+		if summary.CurrentStreak != 1 || summary.LongestStreak != 3 {
+			t.Errorf("Expected streaks 1 and 3, got %d and %d", summary.CurrentStreak, summary.LongestStreak)
+		}
+		return nil
+	})
+}
+
+func TestSummarizeDaysOff(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		settings := DefaultSettings
+		settings.RestDays = []string{"sunday"}
+		setSettings(tx, settings)
+		setFirstDay(tx, "2024-03-04")
+		tx.PutDay(DayData{Date: "2024-03-04", Count: 10, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-05", Count: 10, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-06", Count: 10, Status: StatusSick})
+		tx.PutDay(DayData{Date: "2024-03-07", Count: 10, Done: true})
+		tx.PutDay(DayData{Date: "2024-03-08", Count: 10, Status: StatusRest})
+		tx.PutDay(DayData{Date: "2024-03-09", Count: 10, Done: true})
+
+		// The sick and rest days and Sunday aren't missed, today is still open
+		summary, _ := summarize(tx, "2024-03-01", "2024-03-11")
+		if summary.TrackedDays != 4 || summary.CompletionRate != 100 {
+			t.Errorf("Expected 4 tracked days at 100%%, got %d at %v", summary.TrackedDays, summary.CompletionRate)
+		}
+
+		// It agrees with the statistics
+		stats, _ := computeStats(tx, "2024-03-01", "2024-03-11", "2024-03-11")
+		if stats.TrackedDays != summary.TrackedDays || stats.CompletionRate != summary.CompletionRate {
+			t.Errorf("Expected the statistics to match, got %d at %v", stats.TrackedDays, stats.CompletionRate)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+package tracker
+
+import (
+	"fmt"
+	"time"
+
+	// Embedded so that time zones also work on hosts without a zoneinfo database
+	_ "time/tzdata"
+)
+
+// MaxDayStartHour is the latest hour a day can roll over at
+const MaxDayStartHour = 23
+
+func validateTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("unknown time zone %q", name)
+	}
+	return nil
+}
+
+// Location returns the time zone days are counted in, the server's own if none is set
+func (s Settings) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// DateAt returns the date of the day the given moment belongs to. Before DayStartHour
+// it still belongs to the previous date, so a late workout counts for the day before.
+func (s Settings) DateAt(t time.Time) string {
+	return t.In(s.Location()).Add(-time.Duration(s.DayStartHour) * time.Hour).Format("2006-01-02")
+}
+
+// userToday returns the current date for the user owning the transaction
+func userToday(tx *scope) string {
+	return loadSettings(tx).DateAt(tx.clock.Now())
+}
+
+// Date returns the current date for the user, falling back to the defaults if
+// the user can't be read
+func (s *Service) Date(username string) string {
+	today := s.Defaults.DateAt(s.Clock.Now())
+	s.view(username, func(tx *scope) error {
+		today = userToday(tx)
+		return nil
+	})
+	return today
+}
@@ -0,0 +1,46 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		dayStart int
+		moment   string
+		expected string
+	}{
+		{"UTC", "UTC", 0, "2024-03-10T20:00:00Z", "2024-03-10"},
+		{"Ahead of UTC", "Asia/Tashkent", 0, "2024-03-10T20:00:00Z", "2024-03-11"},
+		{"Behind UTC", "America/New_York", 0, "2024-03-10T02:00:00Z", "2024-03-09"},
+		{"Before the rollover hour", "Asia/Tashkent", 3, "2024-03-10T21:30:00Z", "2024-03-10"},
+		{"After the rollover hour", "Asia/Tashkent", 3, "2024-03-10T22:30:00Z", "2024-03-11"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moment, _ := time.Parse(time.RFC3339, tt.moment)
+			settings := Settings{Timezone: tt.timezone, DayStartHour: tt.dayStart}
+			if got := settings.DateAt(moment); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSettingsValidateTimezone(t *testing.T) {
+	settings := DefaultSettings
+	settings.Timezone = "Mars/Olympus_Mons"
+	if err := settings.Validate(); err == nil {
+		t.Errorf("Expected error for an unknown time zone")
+	}
+
+	settings.Timezone = "Europe/Berlin"
+	settings.DayStartHour = 24
+	if err := settings.Validate(); err == nil {
+		t.Errorf("Expected error for a rollover hour of 24")
+	}
+}
@@ -0,0 +1,245 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+func getFirstDay(tx Tx) (string, error) {
+	return tx.Config("firstDay")
+}
+
+func setFirstDay(tx Tx, firstDay string) error {
+	return tx.PutConfig("firstDay", firstDay)
+}
+
+// loadToday returns the record for today, creating it with a freshly calculated target if needed
+func loadToday(tx *scope, today string) (DayData, error) {
+	dayData, found, err := tx.Day(today)
+	if found || err != nil {
+		return dayData, err
+	}
+
+	// Today's data doesn't exist, create it
+	firstDay, err := getFirstDay(tx)
+	if err != nil {
+		return dayData, err
+	}
+	if firstDay == "" {
+		// Database is empty, this is initialization day. The new data is already
+		// in the current schema.
+		err = setFirstDay(tx, today)
+		if err != nil {
+			return dayData, err
+		}
+		err = setSchemaVersion(tx, SchemaVersion)
+		if err != nil {
+			return dayData, err
+		}
+	}
+
+	dayData, err = newToday(tx, today, firstDay == "")
+	if err != nil {
+		return dayData, err
+	}
+	err = tx.PutDay(dayData)
+	return dayData, err
+}
+
+// newToday returns the record today is created with, without storing it. The
+// first day starts at the starting target.
+func newToday(tx *scope, today string, first bool) (DayData, error) {
+	targetCount := loadSettings(tx).StartTarget
+	if !first {
+		// Calculate target based on the previous days
+		var err error
+		targetCount, err = calculateTodayTarget(tx, today)
+		if err != nil {
+			return DayData{}, err
+		}
+	}
+
+	dayData := DayData{
+		Date:  today,
+		Count: targetCount,
+		Done:  false,
+	}
+
+	// Days off in the weekly schedule start out as rest days, they can still be trained
+	if todayTime, err := time.Parse("2006-01-02", today); err == nil && loadSettings(tx).isRestWeekday(todayTime) {
+		dayData.Status = StatusRest
+	}
+	return dayData, nil
+}
+
+// Today returns the user's current day, creating its record with a freshly
+// calculated target if needed
+func (s *Service) Today(username string) (Day, error) {
+	var response Day
+	err := s.update(username, func(tx *scope) error {
+		dayData, err := loadToday(tx, userToday(tx))
+		if err != nil {
+			return err
+		}
+		response, err = newDay(tx, dayData)
+		return err
+	})
+	return response, err
+}
+
+// Peek returns today like Today, but without creating its record, so that it
+// works on a database opened read-only. Without a record today gets the target
+// it would be created with.
+func (s *Service) Peek(username string) (Day, error) {
+	var response Day
+	err := s.view(username, func(tx *scope) error {
+		today := userToday(tx)
+		dayData, found, err := tx.Day(today)
+		if err != nil {
+			return err
+		}
+		if !found {
+			firstDay, err := getFirstDay(tx)
+			if err != nil {
+				return err
+			}
+			if dayData, err = newToday(tx, today, firstDay == ""); err != nil {
+				return err
+			}
+		}
+		response, err = newDay(tx, dayData)
+		return err
+	})
+	return response, err
+}
+
+// Complete marks today as done. The reps still missing are logged as a final set.
+func (s *Service) Complete(username string) (Day, error) {
+	var response Day
+	err := s.update(username, func(tx *scope) error {
+		today := userToday(tx)
+		dayData, err := loadToday(tx, today)
+		if err != nil {
+			return err
+		}
+
+		if remaining := dayData.Count - dayData.Reps; !dayData.Done && remaining > 0 {
+			// Log the remaining reps as a final set, which completes the day
+			_, err := addSet(tx, &dayData, SetData{Reps: remaining, Final: true})
+			if err != nil {
+				return err
+			}
+		} else if !dayData.Done {
+			dayData.Done = true
+
+			err := tx.PutDay(dayData)
+			if err != nil {
+				return err
+			}
+
+			if _, err := updateStreak(tx, today); err != nil {
+				return err
+			}
+		}
+
+		response, err = newDay(tx, dayData)
+		return err
+	})
+	return response, err
+}
+
+// ErrCompletedBySets is returned when undoing a day whose logged sets reach the
+// target on their own, deleting a set reopens it instead
+var ErrCompletedBySets = errors.New("the logged sets reach the target, delete a set to reopen the day")
+
+// undoComplete reopens a completed day. The final set logged by completing it is
+// removed, and the streak recomputed, which also takes back a freeze earned with it.
+// A day whose other sets reach the target stays done, since Done follows the reps.
+func undoComplete(tx *scope, today string) (DayData, error) {
+	dayData, err := loadToday(tx, today)
+	if err != nil || !dayData.Done {
+		return dayData, err
+	}
+	before := dayData
+
+	sets, err := tx.Sets(today)
+	if err != nil {
+		return dayData, err
+	}
+	var final *SetData
+	if len(sets) > 0 && sets[len(sets)-1].Final {
+		final = &sets[len(sets)-1]
+	}
+	reps := dayData.Reps
+	if final != nil {
+		reps -= final.Reps
+	}
+	if reps >= dayData.Count {
+		return dayData, ErrCompletedBySets
+	}
+	if final != nil {
+		if _, err := removeSet(tx, &dayData, final.ID); err != nil {
+			return dayData, err
+		}
+	}
+
+	dayData.Done = false
+	if err := tx.PutDay(dayData); err != nil {
+		return dayData, err
+	}
+	if err := addAudit(tx, AuditEntry{Date: today, Action: "undo", Before: &before, After: &dayData}); err != nil {
+		return dayData, err
+	}
+	_, err = updateStreak(tx, today)
+	return dayData, err
+}
+
+// Undo reopens today after it was completed by mistake
+func (s *Service) Undo(username string) (Day, error) {
+	var response Day
+	err := s.update(username, func(tx *scope) error {
+		dayData, err := undoComplete(tx, userToday(tx))
+		if err != nil {
+			return err
+		}
+		response, err = newDay(tx, dayData)
+		return err
+	})
+	return response, err
+}
+
+// SetTarget replaces today's target, e.g. for a lighter day, and the days after
+// it follow on from the new one. The day is completed if its reps already reach
+// the target. The change is kept in the audit trail.
+func (s *Service) SetTarget(username string, count int) (Day, error) {
+	var response Day
+	err := s.update(username, func(tx *scope) error {
+		settings := loadSettings(tx)
+		if count < 1 || count > settings.MaxTarget {
+			return fmt.Errorf("target must be between 1 and %d", settings.MaxTarget)
+		}
+
+		today := userToday(tx)
+		dayData, err := loadToday(tx, today)
+		if err != nil {
+			return err
+		}
+		before := dayData
+		dayData.Count = count
+		dayData.Done = dayData.Done || dayData.Reps >= dayData.Count
+		if err := tx.PutDay(dayData); err != nil {
+			return err
+		}
+		if err := addAudit(tx, AuditEntry{Date: today, Action: "target", Before: &before, After: &dayData}); err != nil {
+			return err
+		}
+		if _, err := updateStreak(tx, today); err != nil {
+			return err
+		}
+
+		response, err = newDay(tx, dayData)
+		return err
+	})
+	return response, err
+}
@@ -0,0 +1,124 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestSetAndGetFirstDay(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Test setting first day
+	err := testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		return setFirstDay(tx, "2024-01-01")
+	})
+	if err != nil {
+		t.Errorf("Failed to set first day: %v", err)
+	}
+
+	// Test getting first day
+	var firstDay string
+	err = testDB.View(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		fd, err := getFirstDay(tx)
+		if err != nil {
+			return err
+		}
+		firstDay = fd
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Failed to get first day: %v", err)
+	}
+
+	if firstDay != "2024-01-01" {
+		t.Errorf("Expected first day '2024-01-01', got '%s'", firstDay)
+	}
+}
+
+func TestSetTarget(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	clock := &OffsetClock{}
+	clock.Set(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc := New(NewBoltStore(testDB))
+	svc.Clock = clock
+	svc.Defaults.Timezone = "UTC"
+
+	if _, _, err := svc.AddSet(testUser, "2024-03-01", 8, ""); err != nil {
+		t.Fatalf("Failed to log a set: %v", err)
+	}
+
+	// Test 1: Lowering the target below the reps completes the day
+	day, err := svc.SetTarget(testUser, 8)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if day.Count != 8 || !day.Done || day.Remaining != 0 {
+		t.Errorf("Expected a completed day with target 8, got %+v", day)
+	}
+	if streak, _ := svc.Streak(testUser); streak.Current != 1 {
+		t.Errorf("Expected a streak of 1, got %+v", streak)
+	}
+	if entries, _ := svc.Audit(testUser, "2024-03-01"); len(entries) != 1 || entries[0].Action != "target" || entries[0].Before.Count != 10 {
+		t.Errorf("Expected the change to be audited, got %+v", entries)
+	}
+
+	// Test 2: The next day follows on from the new target, and fsck keeps it
+	clock.Shift(24 * time.Hour)
+	if day, _ := svc.Today(testUser); day.Count != 10 {
+		t.Errorf("Expected target 10 after completing 8, got %+v", day)
+	}
+	if result, _ := svc.Fsck(testUser, false); len(result.Problems) != 0 {
+		t.Errorf("Expected the target set by hand to be kept, got %+v", result.Problems)
+	}
+
+	// Test 3: Targets out of range
+	for _, count := range []int{0, DefaultSettings.MaxTarget + 1} {
+		if _, err := svc.SetTarget(testUser, count); err == nil {
+			t.Errorf("Expected error for target %d", count)
+		}
+	}
+}
+
+func TestPeek(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(root *bolt.Tx) error {
+		tx := testScope(root)
+		setFirstDay(tx, "2024-03-08")
+		tx.PutDay(DayData{Date: "2024-03-08", Count: 10, Reps: 10, Done: true})
+		return tx.PutDay(DayData{Date: "2024-03-09", Count: 12, Reps: 12, Done: true})
+	})
+
+	clock := &OffsetClock{}
+	clock.Set(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC))
+	svc := New(NewBoltStore(testDB))
+	svc.Clock = clock
+	svc.Defaults.Timezone = "UTC"
+
+	day, err := svc.Peek(testUser)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if day.Date != "2024-03-10" || day.Count != 14 || day.Remaining != 14 {
+		t.Errorf("Expected today's target of 14, got %+v", day)
+	}
+	if _, found, _ := svc.Day(testUser, "2024-03-09"); !found {
+		t.Errorf("Expected the earlier days to be kept")
+	}
+	if days, _ := svc.History(testUser, "2024-03-10", ""); len(days) != 0 {
+		t.Errorf("Expected today's record not to be created, got %+v", days)
+	}
+
+	// Today creates the record with the same target
+	if created, _ := svc.Today(testUser); created.Count != day.Count {
+		t.Errorf("Expected the target %d, got %+v", day.Count, created)
+	}
+}
@@ -0,0 +1,67 @@
+// Package tracker implements the push-up tracker: daily targets and their
+// progression, sets, streaks and freezes, rest days and the history of past days.
+// The HTTP server and the command-line tools are built on its Service, which
+// works on any Store.
+package tracker
+
+// DayData is the record of a day
+type DayData struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+	Reps  int    `json:"reps"`
+	Done  bool   `json:"done"`
+	// Status is empty for a training day, or StatusRest or StatusSick for a day off
+	Status string `json:"status,omitempty"`
+}
+
+// StreakData is the current and longest run of completed days
+type StreakData struct {
+	Current  int    `json:"current"`
+	Longest  int    `json:"longest"`
+	LastDate string `json:"lastDate"`
+	// Freezes is the number of streak freezes available, FreezesUsed how many
+	// missed days were covered by one so far
+	Freezes     int `json:"freezes"`
+	FreezesUsed int `json:"freezesUsed"`
+}
+
+// Service is the tracker for every user of a Store. Its methods take the user
+// to work on and run in a single transaction each.
+type Service struct {
+	Store Store
+	// Defaults are the settings of users who haven't saved their own
+	Defaults Settings
+	// Clock tells the time all dates are worked out from
+	Clock Clock
+}
+
+// New returns a service on the store with the default settings and the real time
+func New(store Store) *Service {
+	return &Service{Store: store, Defaults: DefaultSettings, Clock: SystemClock{}}
+}
+
+// scope is a transaction on one user's data, together with the service's
+// defaults and clock
+type scope struct {
+	Tx
+	defaults Settings
+	clock    Clock
+}
+
+func (s *Service) scope(tx Tx) *scope {
+	return &scope{Tx: tx, defaults: s.Defaults, clock: s.Clock}
+}
+
+// view runs fn in a read-only transaction on the user's data
+func (s *Service) view(username string, fn func(tx *scope) error) error {
+	return s.Store.View(username, func(tx Tx) error {
+		return fn(s.scope(tx))
+	})
+}
+
+// update runs fn in a read-write transaction on the user's data
+func (s *Service) update(username string, fn func(tx *scope) error) error {
+	return s.Store.Update(username, func(tx Tx) error {
+		return fn(s.scope(tx))
+	})
+}
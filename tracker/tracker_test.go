@@ -0,0 +1,60 @@
+package tracker
+
+import (
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+// testUser is the account the tests run as
+const testUser = "admin"
+
+// setupTestDB opens a database with the test user's buckets
+func setupTestDB(t *testing.T) *bolt.DB {
+	t.Helper()
+	file := "test.db"
+	db, err := bolt.Open(file, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to create test DB: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		return CreateBoltUser(tx, testUser)
+	})
+	if err != nil {
+		t.Fatalf("Failed to setup test DB: %v", err)
+	}
+
+	return db
+}
+
+func cleanupTestDB(t *testing.T, db *bolt.DB) {
+	t.Helper()
+	db.Close()
+	os.Remove("test.db")
+}
+
+// testScope returns the test user's data with the default settings
+func testScope(root *bolt.Tx) *scope {
+	tx, _ := BoltTx(root, testUser)
+	return &scope{Tx: tx, defaults: DefaultSettings, clock: SystemClock{}}
+}
+
+// testBucket returns one of the test user's buckets, for checking what is stored
+func testBucket(root *bolt.Tx, name string) *bolt.Bucket {
+	return root.Bucket([]byte("UserData")).Bucket([]byte(testUser)).Bucket([]byte(name))
+}
+
+func TestServiceUnknownUser(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	svc := New(NewBoltStore(testDB))
+	if _, err := svc.Today("nobody"); err != ErrUserNotFound {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+	if _, err := svc.Today(testUser); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
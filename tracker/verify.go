@@ -0,0 +1,37 @@
+package tracker
+
+import "fmt"
+
+// Verify reads all of a user's data and fails on the first record that doesn't
+// decode, or on data from a newer build, e.g. to check a backup before restoring it
+func (s *Service) Verify(username string) error {
+	return s.Store.View(username, func(tx Tx) error {
+		version, err := getSchemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		if version > SchemaVersion {
+			return fmt.Errorf("data is at schema version %d, newer than the %d of this build", version, SchemaVersion)
+		}
+
+		days, err := tx.Days("", "")
+		if err != nil {
+			return err
+		}
+		for _, day := range days {
+			if _, err := tx.Sets(day.Date); err != nil {
+				return fmt.Errorf("sets of %s: %v", day.Date, err)
+			}
+		}
+		if _, err := tx.Streak(); err != nil {
+			return fmt.Errorf("streak: %v", err)
+		}
+		if _, err := tx.FreezeDays(); err != nil {
+			return fmt.Errorf("freeze days: %v", err)
+		}
+		if _, err := tx.Audit(""); err != nil {
+			return fmt.Errorf("audit: %v", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// User is an account of the tracker. The record is stored as JSON in the Users
+// bucket, while the user's Days, Streak, Config and Sets buckets are nested under
+// their name in the UserData bucket.
+type User struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"passwordHash,omitempty"`
+	Admin        bool   `json:"admin"`
+	Created      string `json:"created"`
+	// LeaderboardOptOut hides the user from the team leaderboard
+	LeaderboardOptOut bool `json:"leaderboardOptOut"`
+}
+
+// maxPasswordLength is the most bcrypt will hash
+const maxPasswordLength = 72
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,32}$`)
+
+var (
+	errUserExists   = errors.New("user already exists")
+	errUserNotFound = tracker.ErrUserNotFound
+)
+
+type contextKey string
+
+// userContextKey holds the authenticated username in the request context
+const userContextKey contextKey = "user"
+
+func validateCredentials(username, password string) error {
+	if !usernamePattern.MatchString(username) {
+		return errors.New("username must be 1 to 32 letters, digits, '.', '_' or '-'")
+	}
+	if password == "" || len(password) > maxPasswordLength {
+		return fmt.Errorf("password must be between 1 and %d bytes", maxPasswordLength)
+	}
+	return nil
+}
+
+// getUser returns the account with the given name
+func getUser(tx *bolt.Tx, username string) (User, bool, error) {
+	var user User
+	data := tx.Bucket([]byte("Users")).Get([]byte(username))
+	if data == nil {
+		return user, false, nil
+	}
+	err := json.Unmarshal(data, &user)
+	return user, err == nil, err
+}
+
+func putUser(tx *bolt.Tx, user User) error {
+	jsonData, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket([]byte("Users")).Put([]byte(user.Username), jsonData)
+}
+
+// listUsers returns every account in username order
+func listUsers(tx *bolt.Tx) ([]User, error) {
+	users := []User{}
+	err := tx.Bucket([]byte("Users")).ForEach(func(k, v []byte) error {
+		var user User
+		if err := json.Unmarshal(v, &user); err != nil {
+			return fmt.Errorf("user %s: %v", k, err)
+		}
+		users = append(users, user)
+		return nil
+	})
+	return users, err
+}
+
+// createUser stores a new account with a hashed password. Its tracker data is
+// created by addUser.
+func createUser(tx *bolt.Tx, username, password string, admin bool) (User, error) {
+	if err := validateCredentials(username, password); err != nil {
+		return User{}, err
+	}
+	if _, exists, err := getUser(tx, username); err != nil || exists {
+		if err == nil {
+			err = errUserExists
+		}
+		return User{}, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, err
+	}
+
+	user := User{
+		Username:     username,
+		PasswordHash: string(hash),
+		Admin:        admin,
+		Created:      time.Now().Format(time.RFC3339),
+	}
+	if err := putUser(tx, user); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+// addUser creates an account and its tracker data, at the current schema version
+func addUser(username, password string, admin bool) (User, error) {
+	var user User
+	err := db.Update(func(tx *bolt.Tx) error {
+		var err error
+		user, err = createUser(tx, username, password, admin)
+		return err
+	})
+	if err != nil {
+		return user, err
+	}
+	if err := trackerStore().CreateUser(username); err != nil {
+		return user, err
+	}
+	_, err = service().Migrate(username, false)
+	return user, err
+}
+
+// setPassword replaces the password of an existing account
+func setPassword(tx *bolt.Tx, username, password string) error {
+	if err := validateCredentials(username, password); err != nil {
+		return err
+	}
+	user, found, err := getUser(tx, username)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errUserNotFound
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hash)
+	if err := putUser(tx, user); err != nil {
+		return err
+	}
+	// Sessions started with the old password no longer apply
+	return deleteUserSessions(tx, username)
+}
+
+// deleteUser removes an account with its sessions and tokens. Its tracker data is
+// removed by removeUser.
+func deleteUser(tx *bolt.Tx, username string) error {
+	if _, found, err := getUser(tx, username); err != nil || !found {
+		if err == nil {
+			err = errUserNotFound
+		}
+		return err
+	}
+	if err := tx.Bucket([]byte("Users")).Delete([]byte(username)); err != nil {
+		return err
+	}
+	if err := deleteUserSessions(tx, username); err != nil {
+		return err
+	}
+	_, err := deleteTokens(tx, func(t APIToken) bool { return t.Username == username })
+	return err
+}
+
+// removeUser deletes an account together with all of its data
+func removeUser(username string) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		return deleteUser(tx, username)
+	})
+	if err != nil {
+		return err
+	}
+	return trackerStore().DeleteUser(username)
+}
+
+// authenticate checks a username and password against the stored hash
+func authenticate(tx *bolt.Tx, username, password string) (User, bool) {
+	user, found, err := getUser(tx, username)
+	if err != nil || !found {
+		return User{}, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return User{}, false
+	}
+	return user, true
+}
+
+// bootstrapUsers creates the first account from USERNAME and PASSWORD when there
+// are no users yet. Data from the single-user layout, kept in top-level buckets,
+// is moved into that account in the same database.
+func bootstrapUsers(tx *bolt.Tx, username, password string) error {
+	if k, _ := tx.Bucket([]byte("Users")).Cursor().First(); k != nil {
+		return nil
+	}
+
+	if _, err := createUser(tx, username, password, true); err != nil {
+		return fmt.Errorf("create user %s: %v", username, err)
+	}
+
+	for _, name := range tracker.BoltBuckets {
+		legacy := tx.Bucket([]byte(name))
+		if legacy == nil {
+			continue
+		}
+		if err := tracker.CreateBoltUser(tx, username); err != nil {
+			return err
+		}
+		root := tx.Bucket([]byte("UserData")).Bucket([]byte(username))
+		if err := copyBucket(root.Bucket([]byte(name)), legacy); err != nil {
+			return fmt.Errorf("move bucket %s: %v", name, err)
+		}
+		if err := tx.DeleteBucket([]byte(name)); err != nil {
+			return err
+		}
+		log.Printf("Moved existing %s data to user %s", name, username)
+	}
+	return nil
+}
+
+// copyBucket copies every key and nested bucket of src into dst
+func copyBucket(dst, src *bolt.Bucket) error {
+	if err := dst.SetSequence(src.Sequence()); err != nil {
+		return err
+	}
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+		nested, err := dst.CreateBucketIfNotExists(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(nested, src.Bucket(k))
+	})
+}
+
+// withUser returns the request with the authenticated username attached
+func withUser(r *http.Request, username string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userContextKey, username))
+}
+
+// requestUser returns the username attached by the auth middleware
+func requestUser(r *http.Request) string {
+	username, _ := r.Context().Value(userContextKey).(string)
+	return username
+}
+
+// requireAdmin only lets administrators through
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var user User
+		err := db.View(func(tx *bolt.Tx) error {
+			var err error
+			user, _, err = getUser(tx, requestUser(r))
+			return err
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !user.Admin {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleUsers routes /api/admin/users and /api/admin/users/{name}
+func handleUsers(w http.ResponseWriter, r *http.Request) {
+	username := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/users"), "/")
+
+	if username == "" {
+		switch r.Method {
+		case "GET":
+			var users []User
+			err := db.View(func(tx *bolt.Tx) error {
+				var err error
+				users, err = listUsers(tx)
+				return err
+			})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for i := range users {
+				users[i].PasswordHash = ""
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(users)
+		case "POST":
+			handleCreateUser(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	switch r.Method {
+	case "PUT":
+		handleUpdateUser(w, r, username)
+	case "DELETE":
+		if username == requestUser(r) {
+			http.Error(w, "Cannot delete your own account", http.StatusBadRequest)
+			return
+		}
+		err := removeUser(username)
+		if err == errUserNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Admin    bool   `json:"admin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateCredentials(req.Username, req.Password); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := addUser(req.Username, req.Password, req.Admin)
+	if err == errUserExists {
+		http.Error(w, "User already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user.PasswordHash = ""
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+// handleUpdateUser changes the password or the admin flag of an account
+func handleUpdateUser(w http.ResponseWriter, r *http.Request, username string) {
+	var req struct {
+		Password string `json:"password"`
+		Admin    *bool  `json:"admin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Password != "" {
+		if err := validateCredentials(username, req.Password); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Admin != nil && !*req.Admin && username == requestUser(r) {
+		http.Error(w, "Cannot remove your own admin rights", http.StatusBadRequest)
+		return
+	}
+
+	var user User
+	err := db.Update(func(tx *bolt.Tx) error {
+		if req.Password != "" {
+			if err := setPassword(tx, username, req.Password); err != nil {
+				return err
+			}
+		}
+
+		var found bool
+		var err error
+		user, found, err = getUser(tx, username)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errUserNotFound
+		}
+		if req.Admin != nil {
+			user.Admin = *req.Admin
+			return putUser(tx, user)
+		}
+		return nil
+	})
+	if err == errUserNotFound {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user.PasswordHash = ""
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
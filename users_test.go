@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/rhamdeew/push_up_tracker/tracker"
+)
+
+func TestCreateUserAndAuthenticate(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	testDB.Update(func(tx *bolt.Tx) error {
+		user, err := createUser(tx, "alice", "secret", false)
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		if user.PasswordHash == "" || user.PasswordHash == "secret" {
+			t.Errorf("Expected password to be hashed, got %q", user.PasswordHash)
+		}
+
+		if _, err := createUser(tx, "alice", "other", false); err != errUserExists {
+			t.Errorf("Expected errUserExists for duplicate user, got %v", err)
+		}
+		if _, err := createUser(tx, "bad name", "secret", false); err == nil {
+			t.Errorf("Expected error for invalid username")
+		}
+		if _, err := createUser(tx, "bob", "", false); err == nil {
+			t.Errorf("Expected error for empty password")
+		}
+
+		if _, ok := authenticate(tx, "alice", "secret"); !ok {
+			t.Errorf("Expected valid credentials to authenticate")
+		}
+		if _, ok := authenticate(tx, "alice", "wrong"); ok {
+			t.Errorf("Expected wrong password to be rejected")
+		}
+		if _, ok := authenticate(tx, "nobody", "secret"); ok {
+			t.Errorf("Expected unknown user to be rejected")
+		}
+
+		if err := setPassword(tx, "alice", "changed"); err != nil {
+			t.Fatalf("Failed to set password: %v", err)
+		}
+		if _, ok := authenticate(tx, "alice", "changed"); !ok {
+			t.Errorf("Expected new password to authenticate")
+		}
+
+		if err := deleteUser(tx, "alice"); err != nil {
+			t.Fatalf("Failed to delete user: %v", err)
+		}
+		if _, found, _ := getUser(tx, "alice"); found {
+			t.Errorf("Expected the account to be removed")
+		}
+		return nil
+	})
+}
+
+func TestBootstrapUsersMovesLegacyData(t *testing.T) {
+	file := "test_bootstrap.db"
+	testDB, err := bolt.Open(file, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to create test DB: %v", err)
+	}
+	defer func() {
+		testDB.Close()
+		os.Remove(file)
+	}()
+
+	// Single-user layout with data in the top-level buckets
+	day, _ := json.Marshal(tracker.DayData{Date: "2024-01-01", Count: 20, Reps: 5})
+	set, _ := json.Marshal(tracker.SetData{ID: 1, Reps: 5, Note: "legacy"})
+	err = testDB.Update(func(tx *bolt.Tx) error {
+		for _, name := range append(tracker.BoltBuckets, "Users", "UserData") {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		if err := tx.Bucket([]byte("Config")).Put([]byte("firstDay"), []byte("2024-01-01")); err != nil {
+			return err
+		}
+		if err := tx.Bucket([]byte("Days")).Put([]byte("2024-01-01"), day); err != nil {
+			return err
+		}
+		sets, err := tx.Bucket([]byte("Sets")).CreateBucket([]byte("2024-01-01"))
+		if err != nil {
+			return err
+		}
+		sets.NextSequence()
+		return sets.Put([]byte{0, 0, 0, 0, 0, 0, 0, 1}, set)
+	})
+	if err != nil {
+		t.Fatalf("Failed to add legacy data: %v", err)
+	}
+
+	err = testDB.Update(func(tx *bolt.Tx) error {
+		return bootstrapUsers(tx, "owner", "pass")
+	})
+	if err != nil {
+		t.Fatalf("Failed to bootstrap users: %v", err)
+	}
+
+	testDB.View(func(tx *bolt.Tx) error {
+		user, found, _ := getUser(tx, "owner")
+		if !found || !user.Admin {
+			t.Errorf("Expected owner to be created as admin, got %+v", user)
+		}
+		for _, name := range tracker.BoltBuckets {
+			if tx.Bucket([]byte(name)) != nil {
+				t.Errorf("Expected top-level bucket %s to be removed", name)
+			}
+		}
+
+		data, err := tracker.BoltTx(tx, "owner")
+		if err != nil {
+			t.Fatalf("Failed to get user data: %v", err)
+		}
+		b := tx.Bucket([]byte("UserData")).Bucket([]byte("owner"))
+		if firstDay, _ := data.Config("firstDay"); firstDay != "2024-01-01" {
+			t.Errorf("Expected firstDay to be moved, got %q", firstDay)
+		}
+		if b.Bucket([]byte("Days")).Get([]byte("2024-01-01")) == nil {
+			t.Errorf("Expected day record to be moved")
+		}
+		sets, _ := data.Sets("2024-01-01")
+		if len(sets) != 1 || sets[0].Note != "legacy" {
+			t.Errorf("Expected the logged set to be moved, got %+v", sets)
+		}
+		if seq := b.Bucket([]byte("Sets")).Bucket([]byte("2024-01-01")).Sequence(); seq != 1 {
+			t.Errorf("Expected set sequence 1 to be kept, got %d", seq)
+		}
+		return nil
+	})
+
+	// Bootstrapping again leaves existing users alone
+	err = testDB.Update(func(tx *bolt.Tx) error {
+		return bootstrapUsers(tx, "other", "pass")
+	})
+	if err != nil {
+		t.Fatalf("Failed to bootstrap users again: %v", err)
+	}
+	testDB.View(func(tx *bolt.Tx) error {
+		if _, found, _ := getUser(tx, "other"); found {
+			t.Errorf("Expected no user to be created when users exist")
+		}
+		return nil
+	})
+}
+
+func TestHandlersAreScopedToUser(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	if _, err := addUser("alice", "secret", false); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	handler := basicAuth(handleTodayReps)
+
+	req := httptest.NewRequest("POST", "/api/today/reps", strings.NewReader(`{"reps": 7}`))
+	req.SetBasicAuth("alice", "secret")
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The reps were logged for alice only
+	today := time.Now().Format("2006-01-02")
+	testDB.View(func(tx *bolt.Tx) error {
+		alice, _ := tracker.BoltTx(tx, "alice")
+		sets, _ := alice.Sets(today)
+		if len(sets) != 1 || sets[0].Reps != 7 {
+			t.Errorf("Expected alice to have one set of 7 reps, got %+v", sets)
+		}
+
+		sets, _ = testTx(tx).Sets(today)
+		if len(sets) != 0 {
+			t.Errorf("Expected the other user to have no sets, got %+v", sets)
+		}
+		return nil
+	})
+}
+
+func TestHandleUsers(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer cleanupTestDB(t, testDB)
+
+	// Save original db
+	origDB := db
+	db = testDB
+	defer func() { db = origDB }()
+
+	handler := requireAdmin(handleUsers)
+
+	// Test 1: Create a user
+	req := httptest.NewRequest("POST", "/api/admin/users", strings.NewReader(`{"username": "alice", "password": "secret"}`))
+	w := httptest.NewRecorder()
+
+	handler(w, asTestUser(req))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "passwordHash") {
+		t.Errorf("Expected password hash to be left out of the response")
+	}
+
+	// Every user gets their own buckets
+	testDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("UserData")).Bucket([]byte("alice"))
+		if b == nil {
+			t.Fatalf("Expected the user bucket to be created")
+		}
+		for _, name := range tracker.BoltBuckets {
+			if b.Bucket([]byte(name)) == nil {
+				t.Errorf("Expected bucket %s to be created", name)
+			}
+		}
+		return nil
+	})
+
+	// Test 2: Duplicate and invalid users
+	req = httptest.NewRequest("POST", "/api/admin/users", strings.NewReader(`{"username": "alice", "password": "secret"}`))
+	w = httptest.NewRecorder()
+
+	handler(w, asTestUser(req))
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for duplicate user, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/admin/users", strings.NewReader(`{"username": "a/b", "password": "secret"}`))
+	w = httptest.NewRecorder()
+
+	handler(w, asTestUser(req))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid username, got %d", w.Code)
+	}
+
+	// Test 3: List users
+	req = httptest.NewRequest("GET", "/api/admin/users", nil)
+	w = httptest.NewRecorder()
+
+	handler(w, asTestUser(req))
+
+	var users []User
+	json.Unmarshal(w.Body.Bytes(), &users)
+	if len(users) != 2 || users[0].Username != testUser || users[1].Username != "alice" {
+		t.Errorf("Expected admin and alice, got %+v", users)
+	}
+
+	// Test 4: Non-admins are forbidden
+	req = httptest.NewRequest("GET", "/api/admin/users", nil)
+	w = httptest.NewRecorder()
+
+	handler(w, withUser(req, "alice"))
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for non-admin, got %d", w.Code)
+	}
+
+	// Test 5: Change a password
+	req = httptest.NewRequest("PUT", "/api/admin/users/alice", strings.NewReader(`{"password": "changed"}`))
+	w = httptest.NewRecorder()
+
+	handler(w, asTestUser(req))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	testDB.View(func(tx *bolt.Tx) error {
+		if _, ok := authenticate(tx, "alice", "changed"); !ok {
+			t.Errorf("Expected changed password to authenticate")
+		}
+		return nil
+	})
+
+	// Test 6: Delete a user, but not yourself
+	req = httptest.NewRequest("DELETE", "/api/admin/users/"+testUser, nil)
+	w = httptest.NewRecorder()
+
+	handler(w, asTestUser(req))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 when deleting yourself, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("DELETE", "/api/admin/users/alice", nil)
+	w = httptest.NewRecorder()
+
+	handler(w, asTestUser(req))
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	testDB.View(func(tx *bolt.Tx) error {
+		if _, err := tracker.BoltTx(tx, "alice"); err != errUserNotFound {
+			t.Errorf("Expected user data to be removed, got %v", err)
+		}
+		return nil
+	})
+
+	req = httptest.NewRequest("DELETE", "/api/admin/users/alice", nil)
+	w = httptest.NewRecorder()
+
+	handler(w, asTestUser(req))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for deleted user, got %d", w.Code)
+	}
+}